@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"strings"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// appVersion returns the version of the running binary, from the build flags
+// or the module version, "dev" for a plain source build
+func appVersion() string {
+	if Version != "dev" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return Version
+}
+
+// appCommit returns the git commit the binary was built from, from the build
+// flags or the VCS details embedded by the Go toolchain
+func appCommit() string {
+	if Commit != "dev" {
+		return Commit
+	}
+	if revision := buildSetting("vcs.revision"); revision != "" {
+		return revision
+	}
+	return Commit
+}
+
+// appDate returns when the binary was built, from the build flags or the
+// commit time embedded by the Go toolchain
+func appDate() string {
+	if Date != "dev" {
+		return Date
+	}
+	if date := buildSetting("vcs.time"); date != "" {
+		return date
+	}
+	return Date
+}
+
+// buildSetting returns a setting embedded by the Go toolchain, empty if it
+// isn't set
+func buildSetting(key string) string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, setting := range info.Settings {
+		if setting.Key == key {
+			return setting.Value
+		}
+	}
+	return ""
+}
+
+// printVersion prints the build information of the binary. It reads nothing
+// else, so it works without a GPU or config.
+func printVersion() {
+	fmt.Printf("nvidiactl %s\ncommit: %s\nbuilt: %s\ngo: %s\n", appVersion(), appCommit(), appDate(), runtime.Version())
+}
+
+// printBanner logs a short block with the version, the GPU and the key
+// settings, for someone running nvidiactl by hand. The startup summary has
+// the details.
+func (a *AppState) printBanner() {
+	name, err := a.gpuDevice.Name()
+	if err != nil {
+		name = "unknown GPU"
+	}
+
+	mode := "control"
+	switch {
+	case a.cfg.IsMonitorMode() && a.gpuDevice.IsReadOnly():
+		mode = "monitor (read-only)"
+	case a.cfg.IsMonitorMode():
+		mode = "monitor"
+	case a.cfg.IsPerformanceMode():
+		mode = "performance"
+	}
+
+	fanSpeedLimits := a.gpuDevice.GetFanSpeedLimits()
+	powerLimits := a.gpuDevice.GetPowerLimits()
+
+	metricsBackends := "off"
+	if a.cfg.IsMetricsEnabled() {
+		names := make([]string, 0, len(a.cfg.GetMetricsBackends()))
+		for _, backend := range a.cfg.GetMetricsBackends() {
+			names = append(names, string(backend))
+		}
+		metricsBackends = strings.Join(names, ", ")
+	}
+
+	target := fmt.Sprintf("%d°C", a.cfg.GetTemperature())
+	if unit := a.cfg.GetTemperatureUnit(); unit != config.TemperatureUnitCelsius {
+		target += fmt.Sprintf(" (%d°%s)", unit.FromCelsius(a.cfg.GetTemperature()), unit)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "nvidiactl %s on %s\n", appVersion(), name)
+	fmt.Fprintf(&b, "  mode         %s, every %s\n", mode, a.cfg.GetIntervalDuration())
+	fmt.Fprintf(&b, "  target       %s, cooling with %s\n", target, a.cfg.GetCoolingPriority())
+	fmt.Fprintf(&b, "  fans         %s, %d-%d%% (max %d%%)\n", a.cfg.GetFanStrategy(),
+		fanSpeedLimits.Min, fanSpeedLimits.Max, a.cfg.GetFanSpeed())
+	fmt.Fprintf(&b, "  power limit  %d-%d W (default %d W)\n", powerLimits.Min, powerLimits.Max, powerLimits.Default)
+	fmt.Fprintf(&b, "  metrics      %s", metricsBackends)
+
+	logger.Info().Msg(b.String())
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestBuildInfoFallback(t *testing.T) {
+	tests := []struct {
+		name    string
+		ldflags string
+		want    string
+	}{
+		// Test binaries carry neither a module version nor VCS details
+		{name: "without ldflags", ldflags: "dev", want: "dev"},
+		{name: "with ldflags", ldflags: "v1.2.3", want: "v1.2.3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, commit, date := Version, Commit, Date
+			defer func() { Version, Commit, Date = version, commit, date }()
+			Version, Commit, Date = tt.ldflags, tt.ldflags, tt.ldflags
+
+			if got := appVersion(); got != tt.want {
+				t.Errorf("appVersion() = %q, want %q", got, tt.want)
+			}
+			if got := appCommit(); got != tt.want {
+				t.Errorf("appCommit() = %q, want %q", got, tt.want)
+			}
+			if got := appDate(); got != tt.want {
+				t.Errorf("appDate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
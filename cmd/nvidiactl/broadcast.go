@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"codeberg.org/mutker/nvidiactl/internal/status"
+)
+
+// subscriberBuffer is how many snapshots a subscriber may fall behind before
+// the oldest are dropped
+const subscriberBuffer = 4
+
+// stateSnapshot is the state of a tick together with the daemon state it was
+// taken in, so subscribers don't need to read AppState concurrently
+type stateSnapshot struct {
+	Timestamp       time.Time
+	State           GPUState
+	AutoFanControl  bool
+	PerformanceMode bool
+	MonitorMode     bool
+	PowerUnreliable bool
+	Ticks           uint64
+	// TemperatureUnit is the unit temperatures are reported in
+	TemperatureUnit config.TemperatureUnit
+}
+
+// broadcaster pushes each snapshot to all subscribers. Publishing never
+// blocks: a subscriber that falls behind loses its oldest snapshots, so it
+// always ends up with the latest one.
+type broadcaster struct {
+	mu          sync.Mutex
+	subscribers map[chan stateSnapshot]uint64
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subscribers: make(map[chan stateSnapshot]uint64)}
+}
+
+// Subscribe registers a subscriber. The returned function unregisters it and
+// closes the channel; it is safe to call more than once.
+func (b *broadcaster) Subscribe() (<-chan stateSnapshot, func()) {
+	ch := make(chan stateSnapshot, subscriberBuffer)
+
+	b.mu.Lock()
+	b.subscribers[ch] = 0
+	b.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+
+			if dropped := b.subscribers[ch]; dropped > 0 {
+				logger.Debug().Uint64("dropped", dropped).Msg("Slow state subscriber dropped snapshots")
+			}
+			delete(b.subscribers, ch)
+			close(ch)
+		})
+	}
+}
+
+// Publish delivers a snapshot to every subscriber without blocking
+func (b *broadcaster) Publish(snapshot stateSnapshot) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		for sent := false; !sent; {
+			select {
+			case ch <- snapshot:
+				sent = true
+			default:
+				// Make room by dropping the oldest snapshot. The subscriber
+				// may have drained the channel in the meantime, so retry.
+				select {
+				case <-ch:
+					b.subscribers[ch]++
+				default:
+				}
+			}
+		}
+	}
+}
+
+// snapshot captures the state of a tick for the subscribers
+func (a *AppState) snapshot(state GPUState) stateSnapshot {
+	return stateSnapshot{
+		Timestamp:       a.clock(),
+		State:           state,
+		AutoFanControl:  a.autoFanControl,
+		PerformanceMode: a.cfg.IsPerformanceMode() && !a.performanceSuspended,
+		MonitorMode:     a.cfg.IsMonitorMode(),
+		PowerUnreliable: a.powerUnreliable,
+		Ticks:           a.ticks,
+		TemperatureUnit: a.cfg.GetTemperatureUnit(),
+	}
+}
+
+// serveStatus publishes every snapshot, and the recent decisions if
+// decision_history is set, on the status socket until ctx is canceled
+func (a *AppState) serveStatus(ctx context.Context) {
+	snapshots, unsubscribe := a.states.Subscribe()
+	defer unsubscribe()
+
+	var decisions *decisionRing
+	if size := a.cfg.GetDecisionHistory(); size > 0 {
+		decisions = newDecisionRing(size)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case snapshot := <-snapshots:
+			a.statusServer.Publish(a.statusOf(snapshot))
+			if decisions != nil {
+				decisions.Add(decisionOf(snapshot))
+				a.statusServer.PublishDecisions(decisions.List())
+			}
+		}
+	}
+}
+
+// statusOf converts a snapshot to what the status socket serves
+func (a *AppState) statusOf(snapshot stateSnapshot) status.Status {
+	state := snapshot.State
+
+	targetFanSpeed := state.TargetFanSpeed
+	if snapshot.AutoFanControl {
+		targetFanSpeed = 0
+	}
+
+	var policies []string
+	for _, policy := range state.FanPolicies {
+		policies = append(policies, policy.String())
+	}
+
+	var gpus *status.GPUsStatus
+	if state.GPUs != nil {
+		gpus = &status.GPUsStatus{
+			Count:   state.GPUs.Count,
+			Min:     int(state.GPUs.Min),
+			Max:     int(state.GPUs.Max),
+			Mean:    state.GPUs.Mean,
+			Hottest: state.GPUs.Hottest,
+			Spread:  int(state.GPUs.Spread),
+		}
+	}
+
+	var utilization *status.UtilizationStatus
+	if state.Utilization >= 0 {
+		utilization = &status.UtilizationStatus{
+			Current: state.Utilization,
+			Average: state.AverageUtilization,
+		}
+	}
+
+	unit := snapshot.TemperatureUnit
+
+	return status.Status{
+		Timestamp: snapshot.Timestamp,
+		Temperature: status.TemperatureStatus{
+			Current: unit.FromCelsius(state.CurrentTemperature),
+			Average: unit.FromCelsius(state.AverageTemperature),
+			Unit:    string(unit),
+		},
+		FanSpeed: status.FanStatus{
+			Current:  state.CurrentFanSpeed,
+			Target:   targetFanSpeed,
+			Policies: policies,
+		},
+		PowerLimit: status.PowerStatus{
+			Current:     state.CurrentPowerLimit,
+			Target:      state.TargetPowerLimit,
+			Average:     state.AveragePowerLimit,
+			Draw:        max(state.PowerDraw, 0),
+			Board:       max(state.BoardPower, 0),
+			Enforced:    max(state.EnforcedPowerLimit, 0),
+			Constrained: state.PowerConstrained,
+		},
+		State: status.StateStatus{
+			AutoFanControl:         snapshot.AutoFanControl,
+			PerformanceMode:        snapshot.PerformanceMode,
+			MonitorMode:            snapshot.MonitorMode,
+			PowerControlUnreliable: snapshot.PowerUnreliable,
+		},
+		Daemon: status.DaemonStatus{
+			StartedAt:     a.startedAt,
+			UptimeSeconds: int64(snapshot.Timestamp.Sub(a.startedAt).Seconds()),
+			Ticks:         snapshot.Ticks,
+			Version:       appVersion(),
+			Commit:        appCommit(),
+		},
+		GPUs:        gpus,
+		Utilization: utilization,
+	}
+}
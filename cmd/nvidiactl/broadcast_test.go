@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+)
+
+func TestStatusOfUsesTheSnapshotUnit(t *testing.T) {
+	cfg := newTestConfig(t, "temperature_unit = \"C\"\n")
+	a := newTestAppState(t, cfg, newFakeGPU())
+
+	status := a.statusOf(stateSnapshot{
+		State:           GPUState{CurrentTemperature: 100, AverageTemperature: 0},
+		TemperatureUnit: config.TemperatureUnitFahrenheit,
+	})
+
+	if status.Temperature.Unit != "F" {
+		t.Errorf("status unit = %q, want the snapshot's F", status.Temperature.Unit)
+	}
+	if status.Temperature.Current != 212 || status.Temperature.Average != 32 {
+		t.Errorf("status temperatures = %d/%d, want 212/32", status.Temperature.Current, status.Temperature.Average)
+	}
+}
+
+func TestTickCountInStatus(t *testing.T) {
+	a := newTestAppState(t, newTestConfig(t, ""), newFakeGPU())
+	snapshots, unsubscribe := a.states.Subscribe()
+	defer unsubscribe()
+
+	var last stateSnapshot
+	for want := uint64(1); want <= 3; want++ {
+		if err := a.tick(context.Background()); err != nil {
+			t.Fatalf("tick() unexpected error: %v", err)
+		}
+		last = <-snapshots
+		if last.Ticks != want {
+			t.Errorf("snapshot of tick %d counts %d ticks", want, last.Ticks)
+		}
+	}
+
+	if got := a.statusOf(last).Daemon.Ticks; got != 3 {
+		t.Errorf("status ticks = %d, want 3", got)
+	}
+}
+
+// drain returns the ticks of the snapshots buffered for a subscriber
+func drain(snapshots <-chan stateSnapshot) []uint64 {
+	var ticks []uint64
+	for {
+		select {
+		case snapshot := <-snapshots:
+			ticks = append(ticks, snapshot.Ticks)
+		default:
+			return ticks
+		}
+	}
+}
+
+func TestBroadcasterDropsOldest(t *testing.T) {
+	b := newBroadcaster()
+	slow, unsubscribeSlow := b.Subscribe()
+	defer unsubscribeSlow()
+
+	published := subscriberBuffer + 2
+	for tick := 1; tick <= published; tick++ {
+		b.Publish(stateSnapshot{Ticks: uint64(tick)})
+	}
+
+	got := drain(slow)
+	if len(got) != subscriberBuffer {
+		t.Fatalf("slow subscriber got %v, want the last %d snapshots", got, subscriberBuffer)
+	}
+	for i, tick := range got {
+		if want := uint64(published - subscriberBuffer + 1 + i); tick != want {
+			t.Errorf("slow subscriber got %v, want the last %d snapshots in order", got, subscriberBuffer)
+			break
+		}
+	}
+	for _, dropped := range b.subscribers {
+		if dropped != 2 {
+			t.Errorf("dropped snapshots = %d, want 2", dropped)
+		}
+	}
+}
+
+func TestBroadcasterPublishesToEverySubscriber(t *testing.T) {
+	b := newBroadcaster()
+	first, unsubscribeFirst := b.Subscribe()
+	defer unsubscribeFirst()
+	second, unsubscribeSecond := b.Subscribe()
+
+	b.Publish(stateSnapshot{Ticks: 1})
+	unsubscribeSecond()
+	unsubscribeSecond()
+	b.Publish(stateSnapshot{Ticks: 2})
+
+	if got := drain(first); len(got) != 2 {
+		t.Errorf("first subscriber got %v, want both snapshots", got)
+	}
+	if got := <-second; got.Ticks != 1 {
+		t.Errorf("second subscriber got tick %d, want 1", got.Ticks)
+	}
+	if _, ok := <-second; ok {
+		t.Error("unsubscribing didn't close the channel")
+	}
+}
+
+func TestBroadcasterNeverBlocks(t *testing.T) {
+	b := newBroadcaster()
+	snapshots, unsubscribe := b.Subscribe()
+	defer unsubscribe()
+
+	const published = 1000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for tick := 1; tick <= published; tick++ {
+			b.Publish(stateSnapshot{Ticks: uint64(tick)})
+		}
+	}()
+
+	// A subscriber reading concurrently always ends up with the latest
+	var last uint64
+	for last != published {
+		select {
+		case snapshot := <-snapshots:
+			if snapshot.Ticks <= last {
+				t.Fatalf("got tick %d after %d", snapshot.Ticks, last)
+			}
+			last = snapshot.Ticks
+		case <-time.After(time.Second):
+			t.Fatalf("latest snapshot never arrived, last tick %d", last)
+		}
+	}
+	<-done
+}
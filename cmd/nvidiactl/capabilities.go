@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"codeberg.org/mutker/nvidiactl/internal/gpu"
+	"github.com/spf13/pflag"
+)
+
+// runCapabilities probes the GPU and prints what nvidiactl can read and
+// control on it as JSON. It returns the process exit code.
+func runCapabilities(args []string) int {
+	flags := pflag.NewFlagSet("capabilities", pflag.ContinueOnError)
+	compact := flags.Bool("compact", false, "print the JSON on a single line")
+	device := flags.String("device", "", "index or UUID of the GPU to probe (default: the first GPU)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	caps, err := gpu.ProbeCapabilities(*device)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl capabilities: %v\n", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	if !*compact {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(caps); err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl capabilities: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
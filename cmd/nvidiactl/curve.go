@@ -0,0 +1,25 @@
+package main
+
+import "codeberg.org/mutker/nvidiactl/internal/config"
+
+// interpolateCurve returns the value of the curve at the given temperature,
+// linearly interpolated between points. Below the first and above the last
+// point the curve is flat. The points must be sorted by temperature.
+func interpolateCurve(points []config.CurvePoint, temperature int) float64 {
+	if temperature <= points[0].Temperature {
+		return float64(points[0].Value)
+	}
+
+	for i := 1; i < len(points); i++ {
+		lower, upper := points[i-1], points[i]
+		if temperature > upper.Temperature {
+			continue
+		}
+
+		position := float64(temperature-lower.Temperature) / float64(upper.Temperature-lower.Temperature)
+
+		return float64(lower.Value) + position*float64(upper.Value-lower.Value)
+	}
+
+	return float64(points[len(points)-1].Value)
+}
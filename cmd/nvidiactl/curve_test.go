@@ -0,0 +1,45 @@
+package main
+
+import (
+	"testing"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+)
+
+func TestInterpolateCurve(t *testing.T) {
+	curve := []config.CurvePoint{
+		{Temperature: 60, Value: 320},
+		{Temperature: 70, Value: 300},
+		{Temperature: 80, Value: 250},
+	}
+
+	tests := []struct {
+		name        string
+		points      []config.CurvePoint
+		temperature int
+		want        float64
+	}{
+		{name: "below the first point", points: curve, temperature: 40, want: 320},
+		{name: "at the first point", points: curve, temperature: 60, want: 320},
+		{name: "between points", points: curve, temperature: 65, want: 310},
+		{name: "at a middle point", points: curve, temperature: 70, want: 300},
+		{name: "fraction between points", points: curve, temperature: 73, want: 285},
+		{name: "at the last point", points: curve, temperature: 80, want: 250},
+		{name: "above the last point", points: curve, temperature: 95, want: 250},
+		{name: "single point", points: curve[:1], temperature: 75, want: 320},
+		{
+			name:        "rising curve",
+			points:      []config.CurvePoint{{Temperature: 50, Value: 30}, {Temperature: 80, Value: 90}},
+			temperature: 61,
+			want:        52,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interpolateCurve(tt.points, tt.temperature); got != tt.want {
+				t.Errorf("interpolateCurve(%v, %d) = %v, want %v", tt.points, tt.temperature, got, tt.want)
+			}
+		})
+	}
+}
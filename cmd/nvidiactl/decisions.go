@@ -0,0 +1,74 @@
+package main
+
+import "time"
+
+// decisionRecord is a control decision as served at /decisions
+type decisionRecord struct {
+	Timestamp          time.Time      `json:"timestamp"`
+	Temperature        int            `json:"temperature"`
+	AverageTemperature int            `json:"average_temperature"`
+	FanSpeed           int            `json:"fan_speed"`
+	TargetFanSpeed     int            `json:"target_fan_speed"`
+	PowerLimit         int            `json:"power_limit"`
+	TargetPowerLimit   int            `json:"target_power_limit"`
+	AutoFanControl     bool           `json:"auto_fan_control"`
+	Reason             decisionReason `json:"reason"`
+}
+
+// decisionRing keeps the most recent decisions, overwriting the oldest once
+// full. It is only used from the status goroutine.
+type decisionRing struct {
+	records []decisionRecord
+	next    int
+	full    bool
+}
+
+func newDecisionRing(size int) *decisionRing {
+	return &decisionRing{records: make([]decisionRecord, size)}
+}
+
+// Add records a decision, dropping the oldest if the ring is full
+func (r *decisionRing) Add(record decisionRecord) {
+	if len(r.records) == 0 {
+		return
+	}
+
+	r.records[r.next] = record
+	r.next = (r.next + 1) % len(r.records)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// List returns a copy of the recorded decisions, oldest first
+func (r *decisionRing) List() []decisionRecord {
+	if !r.full {
+		return append([]decisionRecord{}, r.records[:r.next]...)
+	}
+
+	list := make([]decisionRecord, 0, len(r.records))
+	list = append(list, r.records[r.next:]...)
+	return append(list, r.records[:r.next]...)
+}
+
+// decisionOf converts a snapshot to a decision record
+func decisionOf(snapshot stateSnapshot) decisionRecord {
+	state := snapshot.State
+
+	targetFanSpeed := state.TargetFanSpeed
+	if snapshot.AutoFanControl {
+		targetFanSpeed = 0
+	}
+
+	return decisionRecord{
+		Timestamp:          snapshot.Timestamp,
+		Temperature:        state.CurrentTemperature,
+		AverageTemperature: state.AverageTemperature,
+		FanSpeed:           state.CurrentFanSpeed,
+		TargetFanSpeed:     targetFanSpeed,
+		PowerLimit:         state.CurrentPowerLimit,
+		TargetPowerLimit:   state.TargetPowerLimit,
+		AutoFanControl:     snapshot.AutoFanControl,
+		Reason:             state.Reason,
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecisionRing(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		added int
+		want  []int
+	}{
+		{name: "empty", size: 3, added: 0, want: []int{}},
+		{name: "partly filled", size: 3, added: 2, want: []int{1, 2}},
+		{name: "exactly full", size: 3, added: 3, want: []int{1, 2, 3}},
+		{name: "wrapped once", size: 3, added: 4, want: []int{2, 3, 4}},
+		{name: "wrapped several times", size: 3, added: 8, want: []int{6, 7, 8}},
+		{name: "single slot", size: 1, added: 5, want: []int{5}},
+		{name: "disabled", size: 0, added: 5, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newDecisionRing(tt.size)
+			for temperature := 1; temperature <= tt.added; temperature++ {
+				r.Add(decisionRecord{Temperature: temperature})
+			}
+
+			got := []int{}
+			for _, record := range r.List() {
+				got = append(got, record.Temperature)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("List() after adding %d to a ring of %d = %v, want %v", tt.added, tt.size, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecisionRingListIsACopy(t *testing.T) {
+	r := newDecisionRing(2)
+	r.Add(decisionRecord{Temperature: 1})
+
+	list := r.List()
+	list[0].Temperature = 99
+	if got := r.List()[0].Temperature; got != 1 {
+		t.Errorf("changing the listed decisions changed the ring to %d", got)
+	}
+}
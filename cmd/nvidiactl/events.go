@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/gpu"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// minEventTickGap limits how often events trigger an extra tick, so a
+// card flapping between clocks can't drive the loop faster than the driver
+// sustains
+const minEventTickGap = time.Second
+
+// watchEvents starts listening for NVML events if enabled. The returned
+// channel is nil, and never ready, if events are disabled or unsupported.
+func (a *AppState) watchEvents(ctx context.Context) <-chan gpu.Event {
+	if !a.cfg.IsNVMLEventsEnabled() {
+		return nil
+	}
+
+	events, err := a.gpuDevice.WatchEvents(ctx)
+	if err != nil {
+		var domainErr errors.Error
+		if errors.As(err, &domainErr) && domainErr.Code() == gpu.ErrEventsUnsupported {
+			logger.Info().Msg("NVML events not supported by this GPU, polling only")
+		} else {
+			logger.Warn().Err(err).Msg("Failed to watch NVML events, polling only")
+		}
+		return nil
+	}
+
+	logger.Info().Msg("Watching NVML events")
+
+	return events
+}
+
+// handleEvent adjusts control in response to an NVML event, so throttling
+// and critical errors are handled before the next regular tick
+func (a *AppState) handleEvent(event gpu.Event) error {
+	log := logger.Debug()
+	if event.Type == gpu.EventCriticalError {
+		log = logger.Warn()
+	}
+	log.Str("event", string(event.Type)).Uint64("data", event.Data).Msg("NVML event received")
+
+	now := a.clock()
+	if now.Sub(a.lastEventTick) < minEventTickGap {
+		return nil
+	}
+	a.lastEventTick = now
+
+	return a.eventTick()
+}
+
+// eventTick adjusts control outside the regular cadence. Unlike a tick it
+// isn't counted towards the heartbeat, leaves resume and jitter detection
+// alone and records no metrics, so it can't collide with the regular
+// samples. The next tick records the state.
+func (a *AppState) eventTick() error {
+	if a.degraded {
+		return nil
+	}
+	defer a.publishHealth()
+
+	state, ok, err := a.adjust()
+	if !ok {
+		return err
+	}
+
+	a.states.Publish(a.snapshot(state))
+
+	return nil
+}
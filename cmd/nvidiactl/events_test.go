@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/gpu"
+)
+
+func TestHandleEventAdjustsWithoutCountingOrRecording(t *testing.T) {
+	cfg := newTestConfig(t, "metrics = true\nnvml_events = true\n")
+	device := newFakeGPU()
+	device.temperature = 85
+	collector := &fakeMetrics{}
+
+	a := newTestAppState(t, cfg, device)
+	a.metrics = collector
+
+	if err := a.tick(context.Background()); err != nil {
+		t.Fatalf("tick() unexpected error: %v", err)
+	}
+	ticks, lastTick, samples := a.ticks, a.lastTick, len(collector.snapshots)
+
+	device.temperature = 90
+	if err := a.handleEvent(gpu.Event{Type: gpu.EventClockChange}); err != nil {
+		t.Fatalf("handleEvent() unexpected error: %v", err)
+	}
+
+	if a.ticks != ticks {
+		t.Errorf("ticks after an event = %d, want %d", a.ticks, ticks)
+	}
+	if !a.lastTick.Equal(lastTick) {
+		t.Errorf("last tick moved from %v to %v on an event", lastTick, a.lastTick)
+	}
+	if len(collector.snapshots) != samples {
+		t.Errorf("event recorded %d metrics samples, want none", len(collector.snapshots)-samples)
+	}
+	if a.lastState == nil || a.lastState.CurrentTemperature != 90 {
+		t.Errorf("event didn't adjust control to the new reading, last state %+v", a.lastState)
+	}
+}
+
+func TestHandleEventRateLimited(t *testing.T) {
+	cfg := newTestConfig(t, "nvml_events = true\n")
+	device := newFakeGPU()
+
+	now := time.Now()
+	a := newTestAppState(t, cfg, device)
+	a.clock = func() time.Time { return now }
+
+	if err := a.handleEvent(gpu.Event{Type: gpu.EventClockChange}); err != nil {
+		t.Fatalf("handleEvent() unexpected error: %v", err)
+	}
+	first := a.lastState
+
+	now = now.Add(minEventTickGap / 2)
+	device.temperature = 70
+	if err := a.handleEvent(gpu.Event{Type: gpu.EventClockChange}); err != nil {
+		t.Fatalf("handleEvent() unexpected error: %v", err)
+	}
+	if a.lastState != first {
+		t.Error("an event within minEventTickGap of the last one adjusted control")
+	}
+}
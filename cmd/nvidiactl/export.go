@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/metrics"
+	"github.com/spf13/pflag"
+)
+
+var exportColumns = []string{
+	"timestamp",
+	"fan_speed_current", "fan_speed_target",
+	"temp_current", "temp_average",
+	"power_current", "power_target", "power_average",
+	"auto_fan_control", "performance_mode",
+}
+
+// runExport writes the samples of a metrics database as CSV with a header.
+// The database is opened read-only and no GPU is needed. It returns the
+// process exit code.
+func runExport(args []string) int {
+	flags := pflag.NewFlagSet("export", pflag.ContinueOnError)
+	dbPath := flags.String("db", "/var/lib/nvidiactl/metrics.db", "metrics database to export")
+	output := flags.StringP("output", "o", "", "file to write the CSV to (default: stdout)")
+	fromFlag := flags.String("from", "", "only export samples from this time on, in RFC 3339 (default: the first sample)")
+	toFlag := flags.String("to", "", "only export samples up to this time, in RFC 3339 (default: now)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	var from, to time.Time
+	for _, bound := range []struct {
+		name  string
+		value string
+		time  *time.Time
+	}{{"from", *fromFlag, &from}, {"to", *toFlag, &to}} {
+		if bound.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, bound.value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "nvidiactl export: invalid --%s: %v\n", bound.name, err)
+			return 2
+		}
+		*bound.time = t
+	}
+
+	history, err := metrics.ReadHistory(*dbPath, from, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl export: %v\n", err)
+		return 1
+	}
+
+	if *output == "" {
+		err = writeExportCSV(os.Stdout, history)
+	} else {
+		err = writeExportFile(*output, history)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl export: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// writeExportFile writes the CSV to a file, replacing it
+func writeExportFile(path string, history []metrics.MetricsSnapshot) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := writeExportCSV(file, history); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// writeExportCSV writes the samples in the columns of the metrics table
+func writeExportCSV(w io.Writer, history []metrics.MetricsSnapshot) error {
+	out := csv.NewWriter(w)
+	if err := out.Write(exportColumns); err != nil {
+		return err
+	}
+
+	for _, sample := range history {
+		if err := out.Write([]string{
+			sample.Timestamp.Format(time.RFC3339),
+			strconv.Itoa(sample.FanSpeed.Current),
+			strconv.Itoa(sample.FanSpeed.Target),
+			strconv.Itoa(sample.Temperature.Current),
+			strconv.Itoa(sample.Temperature.Average),
+			strconv.Itoa(sample.PowerLimit.Current),
+			strconv.Itoa(sample.PowerLimit.Target),
+			strconv.Itoa(sample.PowerLimit.Average),
+			strconv.FormatBool(sample.SystemState.AutoFanControl),
+			strconv.FormatBool(sample.SystemState.PerformanceMode),
+		}); err != nil {
+			return err
+		}
+	}
+
+	out.Flush()
+	return out.Error()
+}
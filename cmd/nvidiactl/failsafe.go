@@ -0,0 +1,137 @@
+package main
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// handleControlFailure counts a failed control iteration. Without a failsafe
+// threshold the error is returned and ends the main loop, as before.
+// Otherwise the failsafe engages once the threshold is reached.
+func (a *AppState) handleControlFailure(err error) error {
+	threshold := a.cfg.GetFailsafeThreshold()
+	if threshold <= 0 {
+		return err
+	}
+
+	a.controlFailures++
+	a.failsafeRecovery = 0
+
+	logger.Warn().
+		Err(err).
+		Int("consecutive_failures", a.controlFailures).
+		Int("failsafe_threshold", threshold).
+		Msg("GPU control failed")
+
+	if !a.failsafe && a.controlFailures >= threshold {
+		a.engageFailsafe(err)
+	}
+
+	return nil
+}
+
+// failTick handles a failed control iteration and counts it as skipped
+func (a *AppState) failTick(err error, reason string) error {
+	if err := a.handleControlFailure(err); err != nil {
+		return err
+	}
+
+	return a.skipTick(reason)
+}
+
+// skipTick counts a tick that ended without applying control. Once
+// max_consecutive_skips is reached it escalates to the failsafe when that is
+// enabled, and otherwise ends the main loop so a supervisor can restart the
+// daemon.
+func (a *AppState) skipTick(reason string) error {
+	errFactory := errors.New()
+
+	limit := a.cfg.GetMaxConsecutiveSkips()
+	if limit <= 0 || a.failsafe || a.cfg.IsMonitorMode() {
+		return nil
+	}
+
+	a.skippedTicks++
+	logger.Debug().
+		Str("reason", reason).
+		Int("consecutive_skips", a.skippedTicks).
+		Int("max_consecutive_skips", limit).
+		Msg("Tick skipped")
+
+	if a.skippedTicks < limit {
+		return nil
+	}
+
+	err := errFactory.WithData(errors.ErrTooManySkips, struct {
+		Skipped int
+		Reason  string
+	}{
+		Skipped: a.skippedTicks,
+		Reason:  reason,
+	})
+	a.skippedTicks = 0
+
+	if a.cfg.GetFailsafeThreshold() > 0 {
+		a.engageFailsafe(err)
+		return nil
+	}
+
+	return err
+}
+
+// engageFailsafe hands the card back to its driver defaults and suspends
+// control until the failsafe is reset.
+func (a *AppState) engageFailsafe(cause error) {
+	errFactory := errors.New()
+
+	a.failsafe = true
+	a.failsafeRecovery = 0
+
+	logger.ErrorWithCode(errFactory.Wrap(errors.ErrFailsafeEngaged, cause)).
+		Int("consecutive_failures", a.controlFailures).
+		Str("recovery", string(a.cfg.GetFailsafeRecovery())).
+		Msg("FAILSAFE ENGAGED: GPU control keeps failing, restoring automatic fans and default power limit")
+
+	if a.cfg.IsMonitorMode() {
+		return
+	}
+
+	if err := a.gpuDevice.EnableAutoFanControl(); err != nil {
+		logger.ErrorWithCode(errFactory.Wrap(errors.ErrEnableAutoFan, err)).Send()
+	} else {
+		a.autoFanControl = true
+	}
+
+	powerLimits := a.gpuDevice.GetPowerLimits()
+	if err := a.gpuDevice.SetPowerLimit(min(powerLimits.Default, powerLimits.Max)); err != nil {
+		logger.ErrorWithCode(errFactory.Wrap(errors.ErrResetPowerLimit, err)).Send()
+	}
+}
+
+// updateFailsafeRecovery counts successful reads while the failsafe is
+// engaged and, with automatic recovery, resumes control once as many
+// consecutive reads succeeded as failures were needed to engage it.
+func (a *AppState) updateFailsafeRecovery() {
+	if a.cfg.GetFailsafeRecovery() != config.FailsafeRecoveryAuto {
+		return
+	}
+
+	a.failsafeRecovery++
+	if a.failsafeRecovery >= a.cfg.GetFailsafeThreshold() {
+		a.resetFailsafe("reads recovered")
+	}
+}
+
+// resetFailsafe resumes normal control
+func (a *AppState) resetFailsafe(reason string) {
+	if !a.failsafe {
+		return
+	}
+
+	a.failsafe = false
+	a.controlFailures = 0
+	a.failsafeRecovery = 0
+
+	logger.Warn().Str("reason", reason).Msg("Failsafe reset, resuming GPU control")
+}
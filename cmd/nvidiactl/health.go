@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/status"
+	"github.com/spf13/pflag"
+)
+
+const (
+	// healthStaleTicks is how many intervals may pass without a successful
+	// tick before the daemon is reported as failed
+	healthStaleTicks = 3
+
+	defaultHealthTimeout = 5 * time.Second
+)
+
+// publishHealth publishes the health of each subsystem on the status socket
+func (a *AppState) publishHealth() {
+	if a.statusServer == nil {
+		return
+	}
+
+	ok := status.SubsystemHealth{Status: status.HealthOK}
+	health := status.Health{
+		Subsystems: map[string]status.SubsystemHealth{
+			status.SubsystemNVML:    ok,
+			status.SubsystemFan:     ok,
+			status.SubsystemPower:   ok,
+			status.SubsystemMetrics: ok,
+		},
+		LastTick:   a.lastRead,
+		MaxTickAge: healthStaleTicks * a.cfg.GetIntervalDuration(),
+	}
+
+	if a.readErr != nil {
+		health.Subsystems[status.SubsystemNVML] = status.SubsystemHealth{
+			Status: status.HealthFailed,
+			Detail: a.readErr.Error(),
+		}
+	}
+
+	fan, power := ok, ok
+	switch {
+	case a.cfg.IsMonitorMode():
+		fan = status.SubsystemHealth{Status: status.HealthOK, Detail: "monitor mode"}
+		power = fan
+	case a.failsafe:
+		fan = status.SubsystemHealth{Status: status.HealthDegraded, Detail: "failsafe engaged"}
+		power = fan
+	case a.controlFailures > 0:
+		detail := fmt.Sprintf("%d consecutive control failures", a.controlFailures)
+		fan = status.SubsystemHealth{Status: status.HealthDegraded, Detail: detail}
+		power = fan
+	}
+	if fan.Status == status.HealthOK && !a.cfg.IsMonitorMode() {
+		if !a.gpuDevice.IsFanControllable() {
+			fan = status.SubsystemHealth{Status: status.HealthDegraded, Detail: "fan control unavailable"}
+		} else if a.fanPolicyReverted {
+			fan = status.SubsystemHealth{Status: status.HealthDegraded, Detail: "fans returned to automatic control"}
+		}
+	}
+	if power.Status == status.HealthOK && a.powerUnreliable {
+		power = status.SubsystemHealth{Status: status.HealthDegraded, Detail: "power limit writes ignored"}
+	}
+	health.Subsystems[status.SubsystemFan] = fan
+	health.Subsystems[status.SubsystemPower] = power
+
+	switch {
+	case !a.cfg.IsMetricsEnabled():
+		health.Subsystems[status.SubsystemMetrics] = status.SubsystemHealth{Status: status.HealthOK, Detail: "disabled"}
+	case a.metricsDisabled:
+		health.Subsystems[status.SubsystemMetrics] = status.SubsystemHealth{
+			Status: status.HealthDegraded,
+			Detail: "disabled after repeated failures",
+		}
+	case a.metricsFailures > 0:
+		health.Subsystems[status.SubsystemMetrics] = status.SubsystemHealth{
+			Status: status.HealthDegraded,
+			Detail: fmt.Sprintf("%d consecutive failures", a.metricsFailures),
+		}
+	}
+
+	a.statusServer.PublishHealth(health)
+}
+
+// runHealth queries the health of a running daemon and prints it as JSON.
+// It exits with 0 when the daemon is ok or degraded and 1 when it failed or
+// can't be reached, for use as a health probe.
+func runHealth(args []string) int {
+	flags := pflag.NewFlagSet("health", pflag.ContinueOnError)
+	socketPath := flags.String("status-socket", status.DefaultSocketPath, "path to the daemon status socket")
+	timeout := flags.Duration("timeout", defaultHealthTimeout, "how long to wait for the daemon")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	health, err := status.NewClient(*socketPath).Health(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl health: daemon not reachable: %v\n", err)
+		return 1
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(health); err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl health: %v\n", err)
+		return 1
+	}
+
+	if health.Status == status.HealthFailed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/gpu"
+	"github.com/spf13/pflag"
+)
+
+// runListGPUs prints the index, name and UUID of every GPU, the values the
+// device setting accepts. It returns the process exit code.
+func runListGPUs(args []string) int {
+	flags := pflag.NewFlagSet("list-gpus", pflag.ContinueOnError)
+	asJSON := flags.Bool("json", false, "print the GPUs as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	devices, err := gpu.ListDevices()
+	if err != nil {
+		var domainErr errors.Error
+		if errors.As(err, &domainErr) && domainErr.Code() == gpu.ErrInitFailed {
+			fmt.Fprintf(os.Stderr, "nvidiactl list-gpus: can't initialize NVML, is the NVIDIA driver installed and loaded? (%v)\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "nvidiactl list-gpus: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(devices); err != nil {
+			fmt.Fprintf(os.Stderr, "nvidiactl list-gpus: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if len(devices) == 0 {
+		fmt.Println("No GPUs found")
+		return 0
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "INDEX\tNAME\tUUID")
+	for _, device := range devices {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", device.Index, device.Name, device.UUID)
+	}
+	if err := w.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl list-gpus: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
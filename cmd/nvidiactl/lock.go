@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// acquireInstanceLock takes an exclusive lock on the lock file, so only one
+// instance at a time writes to the card. Read-only instances don't take it
+// and can run next to a controlling one. The lock is released when the
+// process exits; the returned file must stay open until then. A lock file
+// that can't be opened, e.g. when run by hand without root, only disables
+// the guard.
+func acquireInstanceLock(path string, readOnly bool) (*os.File, error) {
+	errFactory := errors.New()
+
+	if path == "" || readOnly {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		logger.Warn().Err(err).Str("path", path).Msg("Failed to create lock file directory, not guarding against a second instance")
+		return nil, nil
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		logger.Warn().Err(err).Str("path", path).Msg("Failed to open lock file, not guarding against a second instance")
+		return nil, nil
+	}
+
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		holder, _ := os.ReadFile(path)
+		file.Close()
+		if err == syscall.EWOULDBLOCK {
+			return nil, errFactory.WithData(errors.ErrAlreadyRunning, struct {
+				Path string
+				PID  string
+			}{
+				Path: path,
+				PID:  string(holder),
+			})
+		}
+		return nil, errFactory.Wrap(errors.ErrInitFailed, err)
+	}
+
+	// The PID is informational, the lock itself is what counts
+	if err := file.Truncate(0); err == nil {
+		_, _ = file.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+
+	logger.Debug().Str("path", path).Msg("Instance lock acquired")
+
+	return file, nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+func TestInstanceLock(t *testing.T) {
+	tests := []struct {
+		name                     string
+		firstReadOnly            bool
+		secondReadOnly           bool
+		wantSecondAlreadyRunning bool
+	}{
+		{name: "two controllers", wantSecondAlreadyRunning: true},
+		{name: "monitor next to a controller", secondReadOnly: true},
+		{name: "controller next to a monitor", firstReadOnly: true},
+		{name: "two monitors", firstReadOnly: true, secondReadOnly: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "nvidiactl.lock")
+
+			first, err := acquireInstanceLock(path, tt.firstReadOnly)
+			if err != nil {
+				t.Fatalf("first acquireInstanceLock() unexpected error: %v", err)
+			}
+			if first != nil {
+				defer first.Close()
+			}
+			if (first == nil) != tt.firstReadOnly {
+				t.Errorf("first instance took the lock = %v, want %v", first != nil, !tt.firstReadOnly)
+			}
+
+			second, err := acquireInstanceLock(path, tt.secondReadOnly)
+			if second != nil {
+				defer second.Close()
+			}
+
+			var domainErr errors.Error
+			alreadyRunning := errors.As(err, &domainErr) && domainErr.Code() == errors.ErrAlreadyRunning
+			if alreadyRunning != tt.wantSecondAlreadyRunning {
+				t.Errorf("second acquireInstanceLock() error = %v, want already running %v", err, tt.wantSecondAlreadyRunning)
+			}
+		})
+	}
+}
@@ -5,50 +5,208 @@ import (
 	"math"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
 	"syscall"
 	"time"
 
 	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/control"
 	"codeberg.org/mutker/nvidiactl/internal/errors"
 	"codeberg.org/mutker/nvidiactl/internal/gpu"
 	"codeberg.org/mutker/nvidiactl/internal/logger"
 	metrics "codeberg.org/mutker/nvidiactl/internal/metrics"
+	"codeberg.org/mutker/nvidiactl/internal/process"
+	"codeberg.org/mutker/nvidiactl/internal/status"
+	"codeberg.org/mutker/nvidiactl/internal/systemd"
 )
 
 const (
-	minTemperature       = 50
+	minTemperature       = config.MinTemperature
 	powerLimitWindowSize = 5
 	maxPowerLimitChange  = 10
 	wattsPerDegree       = 5
 	powerLimitHysteresis = 5
-	performancePowFactor = 1.5
-	normalPowFactor      = 2.0
 	cleanupTimeout       = 5 * time.Second
+	readWarningInterval  = time.Minute
 	operationTimeout     = 2 * time.Second
+	heartbeatInterval    = time.Hour
+
+	// jitterWarningInterval limits how often late ticks are warned about
+	jitterWarningInterval = time.Minute
+
+	// powerReadbackTolerance is the difference in watts between a written and
+	// read back power limit still accepted, covering driver rounding
+	powerReadbackTolerance = 2
+)
+
+// Build information, set at build time with
+// -ldflags "-X main.Version=... -X main.Commit=... -X main.Date=...".
+// Left at "dev", the module version and VCS details embedded by the Go
+// toolchain are reported instead when available.
+var (
+	Version = "dev"
+	Commit  = "dev"
+	Date    = "dev"
 )
 
 type GPUState struct {
 	CurrentTemperature int
+	RawTemperature     int
 	AverageTemperature int
 	CurrentFanSpeed    int
 	TargetFanSpeed     int
 	CurrentPowerLimit  int
 	TargetPowerLimit   int
 	AveragePowerLimit  int
+	FanSpeedUnreadable bool
+	PerformanceState   int
+	BoardPower         int
+	FanPolicies        []gpu.FanControlPolicy
+	Reason             decisionReason
+	// GPUs summarizes the core temperatures of all GPUs, nil unless GPU
+	// coordination is enabled
+	GPUs *gpu.TemperatureAggregate
+	// Utilization is the GPU utilization in percent and AverageUtilization
+	// its mean over utilization_window, both -1 when unknown
+	Utilization        int
+	AverageUtilization int
+	// FastPath is set when the temperature jumped by at least
+	// fast_path_threshold since the last tick
+	FastPath bool
+	// PreThrottle is set when the temperature is within pre_throttle_margin
+	// of the slowdown threshold
+	PreThrottle bool
+	// EnforcedPowerLimit is the power limit the card enforces, -1 when
+	// unknown. PowerConstrained is set when it is below the limit set.
+	EnforcedPowerLimit int
+	PowerConstrained   bool
+	// FanOverrun is set while the fans are held up by fan_overrun
+	FanOverrun bool
+	// MemoryUsed and MemoryTotal are the frame buffer memory in MiB, both
+	// -1 when unknown
+	MemoryUsed  int
+	MemoryTotal int
+	// PowerDraw is the current power draw of the GPU in watts, -1 when unknown
+	PowerDraw int
 }
 
 type AppState struct {
-	cfg            config.Provider
-	autoFanControl bool
-	gpuDevice      gpu.Controller
-	metrics        metrics.MetricsCollector
+	cfg              config.Provider
+	autoFanControl   bool
+	gpuDevice        gpu.Controller
+	metrics          metrics.MetricsCollector
+	statusServer     status.Server
+	states           *broadcaster
+	policy           control.Policy
+	controlFailures  int
+	failsafe         bool
+	failsafeRecovery int
+	skippedTicks     int
+	fanStrategy      control.FanStrategy
+	clock            func() time.Time
+	lastTick         time.Time
+	metricsFailures  int
+	startedAt        time.Time
+	ticks            uint64
+	lastHeartbeat    time.Time
+	watcher          config.Watcher
+	loader           config.Loader
+	lastEventTick    time.Time
+	scope            controlScope
+	lastState        *GPUState
+	powerTarget      int
+	reloads          chan config.Provider
+	// mu serializes the fan and power loops with split control
+	mu sync.Mutex
+	// boardPowerUnsupported stops querying board power on cards without it
+	boardPowerUnsupported bool
+	// performanceSuspended is set while performance_max_temperature is exceeded
+	performanceSuspended bool
+	// fanPolicyUnsupported stops querying fan policies on cards without them
+	fanPolicyUnsupported bool
+	// fanPolicyReverted is set while a fan is found under automatic control
+	// that nvidiactl should be controlling
+	fanPolicyReverted bool
+	// overTargetStreak counts consecutive power updates above the target
+	// temperature
+	overTargetStreak int
+	// powerUnreliable is set when the GPU ignored a power limit write
+	powerUnreliable bool
+	// lastLimitsRefresh is when the hardware limits were last re-read
+	lastLimitsRefresh time.Time
+	// lastRead is when the GPU state was last read successfully, readErr
+	// the error of the latest read
+	lastRead time.Time
+	readErr  error
+	// metricsDisabled is set when metrics were switched off after
+	// metrics_max_failures
+	metricsDisabled bool
+	// preThrottle is set while pre-throttle protection is engaged
+	preThrottle bool
+	// instanceLock holds the lock file open for the lifetime of the
+	// process, nil if no lock was taken
+	instanceLock *os.File
+	// lateTicks counts the ticks beyond jitter_tolerance since the last
+	// warning about them
+	lateTicks         int
+	lastJitterWarning time.Time
+	// enforcedLimitUnsupported stops querying the enforced power limit on
+	// cards without it
+	enforcedLimitUnsupported bool
+	// powerConstrained is set while the card enforces a lower power limit
+	// than the one set
+	powerConstrained bool
+	// lastHigh is when the temperature was last at the target, and
+	// overrunFanSpeed the highest fan speed reached there, held for
+	// fan_overrun afterwards
+	lastHigh        time.Time
+	overrunFanSpeed int
+	// powerUsageUnsupported stops querying the power draw on cards without it
+	powerUsageUnsupported bool
+	// degraded is set while the GPU couldn't be initialized, each tick
+	// retries instead of controlling
+	degraded bool
+	// memoryTemperatureWarned is set once the memory sensor fallback was logged
+	memoryTemperatureWarned bool
+	// fanStopped is set while fan_stop holds the fans at 0%
+	fanStopped bool
+	// profile is the time-of-day profile selected for the tick, profileActive
+	// is unset while the base settings apply
+	profile       config.Profile
+	profileActive bool
+	// fanControlSkipped is set while fan adjustments are skipped for lack of
+	// a usable fan speed range, so the switch is logged once
+	fanControlSkipped bool
 }
 
 func main() {
 	errFactory := errors.New()
 
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "version", "--version":
+			printVersion()
+			os.Exit(0)
+		case "watch":
+			os.Exit(runWatch(os.Args[2:]))
+		case "capabilities":
+			os.Exit(runCapabilities(os.Args[2:]))
+		case "list-gpus", "--list-gpus":
+			os.Exit(runListGPUs(os.Args[2:]))
+		case "replay":
+			os.Exit(runReplay(os.Args[2:]))
+		case "health":
+			os.Exit(runHealth(os.Args[2:]))
+		case "report":
+			os.Exit(runReport(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		}
+	}
+
 	// Initialize with default log level first
-	logger.Init(string(config.LogLevelInfo), logger.IsService())
+	logger.Init(string(config.LogLevelInfo), logger.FormatConsole, logger.IsService())
 
 	logger.Debug().
 		Str("config_env", os.Getenv("NVIDIACTL_CONFIG")).
@@ -67,55 +225,91 @@ func main() {
 	}
 
 	// Re-initialize logger with config settings
-	if a.cfg.GetLogLevel() != string(config.DefaultLogLevel) {
-		logger.Init(a.cfg.GetLogLevel(), logger.IsService())
+	if a.cfg.GetLogLevel() != string(config.DefaultLogLevel) || a.cfg.GetLogFormat() != config.LogFormatConsole {
+		logger.Init(a.cfg.GetLogLevel(), string(a.cfg.GetLogFormat()), logger.IsService())
 	}
 
 	logger.Info().
+		Str("version", appVersion()).
+		Str("commit", appCommit()).
+		Str("build_date", appDate()).
 		Str("log_level", a.cfg.GetLogLevel()).
 		Bool("monitor_mode", a.cfg.IsMonitorMode()).
 		Bool("performance_mode", a.cfg.IsPerformanceMode()).
 		Bool("metrics", a.cfg.IsMetricsEnabled()).
+		Time("started_at", a.startedAt).
 		Msg("Configuration loaded and applied")
 
+	if !a.degraded {
+		a.logStartupSummary()
+		if a.cfg.IsBannerEnabled() {
+			a.printBanner()
+		}
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
-	// Handle shutdown signal
+	if a.statusServer != nil {
+		go func() {
+			if err := a.statusServer.Start(ctx); err != nil {
+				var domainErr errors.Error
+				if !errors.As(err, &domainErr) {
+					domainErr = errFactory.Wrap(status.ErrServeFailed, err)
+				}
+				logger.ErrorWithCode(domainErr).Msg("Status endpoint stopped")
+			}
+		}()
+		go a.serveStatus(ctx)
+	}
+
+	if a.watcher != nil {
+		go a.watchConfig(ctx)
+	}
+	go a.reloadOnSignal(ctx)
+
+	// A termination signal stops the loop. The loop finishes its current tick,
+	// including the metrics write, before cleanup closes the database.
 	go func() {
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		sig := <-sigChan
 		logger.Info().Msgf("Received termination signal: %v", sig)
-
-		// First cancel the context to stop the main loop
 		cancel()
 
-		// Then perform cleanup once
-		cleanupDone := make(chan struct{})
-		go func() {
-			a.cleanup()
-			close(cleanupDone)
-		}()
-
-		select {
-		case <-cleanupDone:
-			logger.Info().Msg("Graceful shutdown completed")
-			os.Exit(0)
-		case <-time.After(cleanupTimeout):
-			logger.Error().Msg("Forced shutdown after timeout")
-			os.Exit(1)
-		}
+		// A tick stuck in the driver must not block shutdown forever
+		time.Sleep(2 * cleanupTimeout)
+		logger.Error().Msg("Forced shutdown after timeout")
+		os.Exit(1)
 	}()
 
+	exitCode := 0
 	if err := a.loop(ctx); err != nil {
 		var domainErr errors.Error
 		if !errors.As(err, &domainErr) {
 			domainErr = errFactory.Wrap(errors.ErrMainLoop, err)
 		}
 		logger.ErrorWithCode(domainErr).Send()
+		exitCode = 1
+	}
+	cancel()
+
+	cleanupDone := make(chan struct{})
+	go func() {
 		a.cleanup()
-		os.Exit(1)
+		close(cleanupDone)
+	}()
+
+	select {
+	case <-cleanupDone:
+		if exitCode == 0 {
+			logger.Info().Msg("Graceful shutdown completed")
+		}
+	case <-time.After(cleanupTimeout):
+		logger.Error().Msg("Forced shutdown after timeout")
+		exitCode = 1
 	}
+
+	os.Exit(exitCode)
 }
 
 func New() (*AppState, error) {
@@ -128,24 +322,78 @@ func New() (*AppState, error) {
 		return nil, errFactory.Wrap(errors.ErrInitApp, err)
 	}
 
-	logger.Init(cfg.GetLogLevel(), logger.IsService())
+	logger.Init(cfg.GetLogLevel(), string(cfg.GetLogFormat()), logger.IsService())
+
+	// Taken before NVML is touched, a second controlling instance must not
+	// write to the card even once
+	readOnly := cfg.IsMonitorMode() && cfg.IsMonitorReadOnly()
+	instanceLock, err := acquireInstanceLock(cfg.GetLockFile(), readOnly)
+	if err != nil {
+		return nil, errFactory.Wrap(errors.ErrInitApp, err)
+	}
 
-	gpuDevice, err := gpu.New()
+	gpuDevice, err := gpu.New(
+		gpu.WithReturnCodeTracing(cfg.IsMetricsEnabled() && cfg.IsNVMLDebugEnabled()),
+		gpu.WithTemperatureStatistic(gpu.TemperatureStatistic(cfg.GetTemperatureStatistic())),
+		gpu.WithTemperatureSmoothing(gpu.TemperatureSmoothing(cfg.GetTemperatureSmoothing()), cfg.GetEMAAlpha()),
+		gpu.WithTemperatureFilter(gpu.TemperatureFilter(cfg.GetTemperatureFilter()), gpu.Temperature(cfg.GetTemperatureFilterDelta())),
+		gpu.WithTemperatureSensorIndex(cfg.GetTemperatureSensorIndex()),
+		gpu.WithWarmupReads(cfg.GetWarmupReads()),
+		gpu.WithUtilizationWindow(cfg.GetUtilizationWindow()),
+		gpu.WithTemperatureWindow(cfg.GetTemperatureWindow()),
+		gpu.WithPowerWindow(cfg.GetPowerWindow()),
+		gpu.WithReadOnly(readOnly),
+		gpu.WithDevice(cfg.GetDeviceSelector()),
+		gpu.WithInitRetries(cfg.GetInitRetries(), cfg.GetInitBackoff()),
+		gpu.WithReadRetries(cfg.GetNVMLReadRetries(), cfg.GetNVMLReadRetryDelay()),
+	)
 	if err != nil {
 		logger.Debug().Err(err).Msg("Failed to create GPU controller")
 		return nil, errFactory.Wrap(errors.ErrInitApp, err)
 	}
 
+	// A GPU still unavailable after the retries leaves the daemon degraded,
+	// every tick retries until it can be initialized
+	degraded := false
 	if err := gpuDevice.Initialize(); err != nil {
-		logger.Debug().Err(err).Msg("Failed to initialize GPU controller")
+		logger.Warn().Err(err).Msg("GPU unavailable, running degraded and retrying every interval")
+		degraded = true
+	}
+
+	if offset := cfg.GetTemperatureOffset(); offset != 0 {
+		logger.Info().Int("temperature_offset", offset).Msg("Applying temperature offset to all readings")
+	}
+
+	var autoFanControl bool
+	if !degraded {
+		if autoFanControl, err = initGPU(cfg, gpuDevice); err != nil {
+			return nil, err
+		}
+	}
+
+	policy, err := control.NewPolicy(control.Priority(cfg.GetCoolingPriority()))
+	if err != nil {
+		return nil, errFactory.Wrap(errors.ErrInitApp, err)
+	}
+
+	fanStrategy, err := newFanStrategy(cfg)
+	if err != nil {
 		return nil, errFactory.Wrap(errors.ErrInitApp, err)
 	}
 
 	var collector metrics.MetricsCollector
 	if cfg.IsMetricsEnabled() {
 		collector, err = metrics.NewService(metrics.Config{
-			DBPath:  cfg.GetMetricsDBPath(),
-			Enabled: true,
+			DBPath:                cfg.GetMetricsDBPath(),
+			Enabled:               true,
+			Synchronous:           string(cfg.GetMetricsSynchronous()),
+			JournalMode:           string(cfg.GetMetricsJournalMode()),
+			Backends:              metricsBackends(cfg),
+			TextfilePath:          cfg.GetMetricsTextfile(),
+			InfluxSocket:          cfg.GetMetricsInfluxSocket(),
+			MaxSize:               int64(cfg.GetMetricsMaxSize()) << 20,
+			Retention:             time.Duration(cfg.GetMetricsRetentionDays()) * 24 * time.Hour,
+			DestructiveMigrations: cfg.IsMetricsDestructiveMigrationEnabled(),
 		})
 		if err != nil {
 			var appErr errors.Error
@@ -157,21 +405,242 @@ func New() (*AppState, error) {
 		}
 	}
 
+	var statusServer status.Server
+	if socketPath := cfg.GetStatusSocket(); socketPath != "" {
+		statusServer, err = status.NewServer(status.Config{SocketPath: socketPath})
+		if err != nil {
+			// The status endpoint is optional, the daemon runs fine without it
+			logger.Warn().Err(err).Str("path", socketPath).Msg("Status endpoint unavailable")
+		} else if !degraded {
+			statusServer.PublishCapabilities(gpuDevice.GetCapabilities())
+		}
+	}
+
+	// Set before dropping privileges, raising priority needs root
+	if err := process.SetNiceness(cfg.GetNiceness()); err != nil {
+		logger.Warn().Err(err).Int("niceness", cfg.GetNiceness()).Msg("Failed to set process priority, continuing")
+	}
+
+	// Everything needing root is set up by now: NVML, the metrics database
+	// and the status socket
+	if username := cfg.GetRunAsUser(); username != "" {
+		if err := process.DropPrivileges(username); err != nil {
+			return nil, errFactory.Wrap(errors.ErrInitApp, err)
+		}
+	}
+
+	var watcher config.Watcher
+	if cfg.IsConfigWatchEnabled() {
+		if loader.ConfigFile() == "" {
+			logger.Warn().Msg("No config file loaded, not watching for changes")
+		} else {
+			watcher = config.NewWatcher(loader, 0)
+		}
+	}
+
+	startedAt := time.Now()
+
+	if watchdog := systemd.WatchdogInterval(); watchdog > 0 && cfg.GetIntervalDuration() > watchdog/2 {
+		logger.Warn().
+			Dur("interval", cfg.GetIntervalDuration()).
+			Dur("watchdog", watchdog).
+			Msg("Interval longer than half the systemd watchdog timeout, raise WatchdogSec")
+	}
+	if err := systemd.Ready(); err != nil {
+		logger.Warn().Err(err).Msg("Failed to notify systemd")
+	}
+
 	return &AppState{
-		cfg:       cfg,
-		gpuDevice: gpuDevice,
-		metrics:   collector,
+		cfg:            cfg,
+		autoFanControl: autoFanControl,
+		gpuDevice:      gpuDevice,
+		metrics:        collector,
+		statusServer:   statusServer,
+		states:         newBroadcaster(),
+		policy:         policy,
+		fanStrategy:    fanStrategy,
+		clock:          time.Now,
+		startedAt:      startedAt,
+		lastHeartbeat:  startedAt,
+		watcher:        watcher,
+		loader:         loader,
+		reloads:        make(chan config.Provider, 1),
+		instanceLock:   instanceLock,
+		degraded:       degraded,
 	}, nil
 }
 
+// initGPU checks the configuration against the hardware limits and applies
+// the initial fan control state, returning whether the fans are under driver
+// control. It needs an initialized GPU.
+func initGPU(cfg config.Provider, gpuDevice gpu.Controller) (bool, error) {
+	errFactory := errors.New()
+
+	if cfg.IsPerformanceMode() {
+		if fanCap := cfg.GetPerformanceFanCap(); fanCap < int(gpuDevice.GetFanSpeedLimits().Min) {
+			return false, errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field   string
+				Value   int
+				Minimum int
+			}{
+				Field:   "performance_fan_cap",
+				Value:   fanCap,
+				Minimum: int(gpuDevice.GetFanSpeedLimits().Min),
+			})
+		}
+	}
+
+	// Stopping the fans needs a 0% minimum, anything else is rejected by
+	// the card
+	if cfg.IsFanStopEnabled() {
+		if minimum := gpuDevice.GetFanSpeedLimits().Min; minimum > 0 {
+			return false, errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field           string
+				Value           bool
+				FanSpeedMinimum int
+			}{
+				Field:           "fan_stop",
+				Value:           true,
+				FanSpeedMinimum: int(minimum),
+			})
+		}
+	}
+
+	autoFanControl, err := initFanControl(cfg, gpuDevice)
+	if err != nil {
+		return false, errFactory.Wrap(errors.ErrInitApp, err)
+	}
+
+	return autoFanControl, nil
+}
+
+// recoverGPU retries initializing the GPU while degraded. Once it succeeds,
+// the setup skipped at startup is completed and it returns true.
+func (a *AppState) recoverGPU() (bool, error) {
+	if err := a.gpuDevice.Initialize(); err != nil {
+		logger.Warn().Err(err).Msg("GPU still unavailable, retrying next interval")
+		a.readErr = err
+		// Retrying is progress, the loop isn't wedged
+		a.pingWatchdog()
+		return false, nil
+	}
+
+	autoFanControl, err := initGPU(a.cfg, a.gpuDevice)
+	if err != nil {
+		return false, err
+	}
+	a.autoFanControl = autoFanControl
+	a.degraded = false
+
+	if a.statusServer != nil {
+		a.statusServer.PublishCapabilities(a.gpuDevice.GetCapabilities())
+	}
+	logger.Info().Msg("GPU initialized, leaving degraded mode")
+	a.logStartupSummary()
+
+	return true, nil
+}
+
+// metricsBackends returns the configured metrics backend names
+func metricsBackends(cfg config.Provider) []string {
+	backends := make([]string, 0, len(cfg.GetMetricsBackends()))
+	for _, backend := range cfg.GetMetricsBackends() {
+		backends = append(backends, string(backend))
+	}
+	return backends
+}
+
+// initFanControl applies the configured initial fan control state and returns
+// whether the fans are under driver control
+func initFanControl(cfg config.Provider, gpuDevice gpu.Controller) (bool, error) {
+	errFactory := errors.New()
+
+	mode := cfg.GetInitialFanControl()
+	if cfg.IsMonitorMode() && mode != config.InitialFanControlDetect {
+		logger.Debug().Str("initial_fan_control", string(mode)).Msg("Monitor mode, not changing fan control")
+		mode = config.InitialFanControlDetect
+	}
+
+	switch mode {
+	case config.InitialFanControlAuto:
+		if err := gpuDevice.EnableAutoFanControl(); err != nil {
+			return false, errFactory.Wrap(errors.ErrEnableAutoFan, err)
+		}
+	case config.InitialFanControlManual:
+		if !gpuDevice.IsFanControllable() {
+			logger.Warn().Msg("Fan control unavailable, leaving fans to the driver")
+			break
+		}
+		if err := gpuDevice.DisableAutoFanControl(); err != nil {
+			return false, errFactory.Wrap(gpu.ErrDisableAutoFan, err)
+		}
+	case config.InitialFanControlDetect:
+	}
+
+	if err := applyInitialFanSpeed(cfg, gpuDevice); err != nil {
+		return false, err
+	}
+
+	autoFanControl := gpuDevice.IsAutoFanControl()
+	logger.Debug().Bool("auto_fan_control", autoFanControl).Msg("Initial fan control state")
+
+	return autoFanControl, nil
+}
+
+// applyInitialFanSpeed sets the configured initial fan speed, so the card
+// starts from a known state until the first computed target
+func applyInitialFanSpeed(cfg config.Provider, gpuDevice gpu.Controller) error {
+	errFactory := errors.New()
+
+	speed := cfg.GetInitialFanSpeed()
+	if speed == 0 {
+		return nil
+	}
+	if cfg.IsMonitorMode() {
+		logger.Debug().Int("initial_fan_speed", speed).Msg("Monitor mode, not setting initial fan speed")
+		return nil
+	}
+	if !gpuDevice.IsFanControllable() {
+		logger.Warn().Msg("Fan control unavailable, not setting initial fan speed")
+		return nil
+	}
+
+	limits := gpuDevice.GetFanSpeedLimits()
+	if speed < int(limits.Min) || speed > int(limits.Max) {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Minimum int
+			Maximum int
+		}{
+			Field:   "initial_fan_speed",
+			Value:   speed,
+			Minimum: int(limits.Min),
+			Maximum: int(limits.Max),
+		})
+	}
+
+	if gpuDevice.IsAutoFanControl() {
+		if err := gpuDevice.DisableAutoFanControl(); err != nil {
+			return errFactory.Wrap(gpu.ErrDisableAutoFan, err)
+		}
+	}
+	if err := gpuDevice.SetFanSpeed(gpu.FanSpeed(speed)); err != nil {
+		return errFactory.Wrap(gpu.ErrSetFanSpeed, err)
+	}
+	logger.Info().Int("fan_speed", speed).Msg("Initial fan speed applied")
+
+	return nil
+}
+
 func (a *AppState) loop(ctx context.Context) error {
 	errFactory := errors.New()
 
-	if a.cfg.GetInterval() <= 0 {
+	interval := a.cfg.GetIntervalDuration()
+	if interval <= 0 {
 		return errFactory.New(errors.ErrInvalidInterval)
 	}
 
-	interval := time.Duration(a.cfg.GetInterval()) * time.Second
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -181,34 +650,254 @@ func (a *AppState) loop(ctx context.Context) error {
 
 	logger.Debug().Msgf("Starting main loop with %v interval", interval)
 
+	// SIGUSR1 manually resets the failsafe
+	resetSignals := make(chan os.Signal, 1)
+	signal.Notify(resetSignals, syscall.SIGUSR1)
+	defer signal.Stop(resetSignals)
+
+	events := a.watchEvents(ctx)
+
+	if a.cfg.IsSplitControl() && !a.cfg.IsMonitorMode() {
+		powerCtx, stopPower := context.WithCancel(ctx)
+		powerDone := a.startPowerLoop(powerCtx)
+		// The power loop must be stopped before cleanup touches the GPU
+		defer func() {
+			stopPower()
+			<-powerDone
+		}()
+		a.scope = scopeFans
+
+		return a.runLoop(ctx, ticker, resetSignals, events, powerDone)
+	}
+
+	return a.runLoop(ctx, ticker, resetSignals, events, nil)
+}
+
+// runLoop multiplexes the control ticks with signals, config reloads and
+// NVML events. A nil powerDone disables waiting on the power loop.
+func (a *AppState) runLoop(
+	ctx context.Context, ticker *time.Ticker, resetSignals <-chan os.Signal,
+	events <-chan gpu.Event, powerDone <-chan error,
+) error {
 	for {
 		select {
 		case <-ctx.Done():
 			logger.Debug().Msg("Context canceled, exiting loop")
 			return nil
+		case err := <-powerDone:
+			return err
+		case <-resetSignals:
+			a.mu.Lock()
+			a.resetFailsafe("manual reset")
+			a.mu.Unlock()
+		case cfg := <-a.reloads:
+			a.mu.Lock()
+			a.applyConfig(cfg, ticker)
+			a.mu.Unlock()
+		case event, ok := <-events:
+			if !ok {
+				logger.Info().Msg("NVML events stopped, polling only")
+				events = nil
+				continue
+			}
+			if err := a.withLock(func() error { return a.handleEvent(event) }); err != nil {
+				return err
+			}
 		case <-ticker.C:
-			logger.Debug().Msg("Updating GPU state...")
-
-			state, err := a.getGPUState()
-			if err != nil {
-				logger.Debug().Err(err).Msg("Failed to get GPU state")
+			if err := a.withLock(func() error { return a.tick(ctx) }); err != nil {
 				return err
 			}
+		}
+	}
+}
 
-			if !a.cfg.IsMonitorMode() {
-				state, err = a.setGPUState(&state)
-				if err != nil {
-					logger.Debug().Err(err).Msg("Failed to set GPU state")
-					return err
-				}
-			} else {
-				state.TargetFanSpeed = a.calculateFanSpeed(state.AverageTemperature, a.cfg.GetTemperature(), a.cfg.GetFanSpeed())
-				state.TargetPowerLimit = a.calculatePowerLimit(state.CurrentTemperature, a.cfg.GetTemperature(),
-					state.CurrentFanSpeed, a.cfg.GetFanSpeed(), state.CurrentPowerLimit)
-			}
+// tick runs a single control iteration. Control failures are returned as
+// errors unless the failsafe is enabled, in which case they are counted
+// towards its threshold instead.
+func (a *AppState) tick(ctx context.Context) error {
+	logger.Debug().Msg("Updating GPU state...")
+
+	a.ticks++
+	a.detectResume()
+	a.logHeartbeat()
+	defer a.publishHealth()
+	if a.degraded {
+		if recovered, err := a.recoverGPU(); !recovered {
+			return err
+		}
+	}
+	a.refreshLimits()
+	a.selectProfile()
+
+	state, ok, err := a.adjust()
+	if !ok {
+		return err
+	}
+
+	a.logGPUState(ctx, state)
+	a.states.Publish(a.snapshot(state))
+
+	return nil
+}
+
+// adjust reads the GPU state and applies the fan and power targets, the part
+// of a tick shared with event ticks. It returns false when the tick ended
+// early, along with the error to return.
+func (a *AppState) adjust() (GPUState, bool, error) {
+	state, err := a.getGPUState()
+	a.readErr = err
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to get GPU state")
+		return state, false, a.failTick(err, "read_failed")
+	}
+	a.lastRead = a.clock()
+	a.pingWatchdog()
+
+	if a.failsafe {
+		a.updateFailsafeRecovery()
+		return state, true, nil
+	}
+
+	if state.FanSpeedUnreadable {
+		skip, err := a.handleFanReadFailure()
+		if err != nil {
+			return state, false, a.failTick(err, "fan_read_failed")
+		}
+		if skip {
+			return state, false, a.skipTick("fan_read_failed")
+		}
+	}
 
-			a.logGPUState(ctx, state)
+	if !a.cfg.IsMonitorMode() {
+		state, err = a.setGPUState(&state, a.scope)
+		if err != nil {
+			logger.Debug().Err(err).Msg("Failed to set GPU state")
+			return state, false, a.failTick(err, "apply_failed")
 		}
+		a.controlFailures = 0
+		a.skippedTicks = 0
+	} else {
+		state.TargetFanSpeed, state.TargetPowerLimit = a.calculateTargets(&state)
+	}
+
+	last := state
+	a.lastState = &last
+
+	return state, true, nil
+}
+
+// detectResume resets the control state when the gap since the last tick
+// exceeds resume_gap, typically after a system suspend. The averages would
+// otherwise still contain pre-sleep samples.
+func (a *AppState) detectResume() {
+	// Round(0) strips the monotonic reading, which doesn't advance while
+	// the system is suspended
+	now := a.clock().Round(0)
+	last := a.lastTick
+	a.lastTick = now
+
+	resumeGap := a.cfg.GetResumeGap()
+	if resumeGap <= 0 || last.IsZero() {
+		return
+	}
+
+	gap := now.Sub(last)
+	if gap < resumeGap {
+		a.checkJitter(now, gap)
+		return
+	}
+
+	// There is no control state before the GPU is initialized
+	if a.degraded {
+		return
+	}
+
+	a.gpuDevice.ResetState()
+	a.autoFanControl = a.gpuDevice.IsAutoFanControl()
+	a.fanStrategy.Reset()
+
+	logger.Info().
+		Dur("gap", gap).
+		Dur("resume_gap", resumeGap).
+		Bool("auto_fan_control", a.autoFanControl).
+		Msg("Long gap since last update, resetting control state")
+}
+
+// checkJitter warns when ticks arrive later than jitter_tolerance allows,
+// at most once every jitterWarningInterval. Early ticks are expected, NVML
+// events trigger extra ones.
+func (a *AppState) checkJitter(now time.Time, gap time.Duration) {
+	tolerance := a.cfg.GetJitterTolerance()
+	if tolerance <= 0 {
+		return
+	}
+
+	interval := a.cfg.GetIntervalDuration()
+	if gap <= interval+interval*time.Duration(tolerance)/100 {
+		return
+	}
+
+	a.lateTicks++
+	if now.Sub(a.lastJitterWarning) < jitterWarningInterval {
+		return
+	}
+	a.lastJitterWarning = now
+
+	logger.Warn().
+		Dur("gap", gap).
+		Dur("interval", interval).
+		Int("tolerance_percent", tolerance).
+		Int("late_ticks", a.lateTicks).
+		Msg("Update later than expected, system load may be delaying the control loop")
+	a.lateTicks = 0
+}
+
+// uptime returns how long the daemon has been running, rounded to seconds
+func (a *AppState) uptime() time.Duration {
+	return a.clock().Sub(a.startedAt).Round(time.Second)
+}
+
+// logHeartbeat periodically logs uptime and tick count, to tell a daemon
+// that runs continuously from one that keeps restarting
+func (a *AppState) logHeartbeat() {
+	now := a.clock()
+	if now.Sub(a.lastHeartbeat) < heartbeatInterval {
+		return
+	}
+	a.lastHeartbeat = now
+
+	uptime := a.uptime()
+	logger.Info().
+		Dur("uptime", uptime).
+		Uint64("ticks", a.ticks).
+		Int64("expected_ticks", int64(uptime/a.cfg.GetIntervalDuration())+1).
+		Msg("Heartbeat")
+}
+
+// pingWatchdog pings the systemd watchdog, which restarts the service once
+// the loop stops reading the GPU
+func (a *AppState) pingWatchdog() {
+	if err := systemd.Watchdog(); err != nil {
+		logger.Debug().Err(err).Msg("Failed to ping the systemd watchdog")
+	}
+}
+
+// refreshLimits re-reads the hardware limits every limits_refresh_interval,
+// they are cached otherwise. A failed refresh keeps the cached limits.
+func (a *AppState) refreshLimits() {
+	interval := a.cfg.GetLimitsRefreshInterval()
+	if interval <= 0 {
+		return
+	}
+
+	now := a.clock()
+	if now.Sub(a.lastLimitsRefresh) < interval {
+		return
+	}
+	a.lastLimitsRefresh = now
+
+	if err := a.gpuDevice.RefreshLimits(); err != nil {
+		logger.Warn().Err(err).Msg("Failed to refresh hardware limits, keeping the cached ones")
 	}
 }
 
@@ -216,15 +905,16 @@ func (a *AppState) cleanup() {
 	errFactory := errors.New()
 	logger.Debug().Msg("Starting application cleanup...")
 
-	if a.gpuDevice != nil {
-		powerLimits := a.gpuDevice.GetPowerLimits()
-		powerLimitToSet := min(powerLimits.Default, powerLimits.Max)
-		if err := a.gpuDevice.SetPowerLimit(powerLimitToSet); err != nil {
-			logger.ErrorWithCode(errFactory.Wrap(errors.ErrResetPowerLimit, err)).Send()
+	// A degraded GPU was never initialized, there is nothing to restore
+	if a.gpuDevice != nil && !a.degraded {
+		// A read-only controller never changed anything to restore
+		powerLimit := a.gpuDevice.GetCurrentPowerLimit()
+		if !a.gpuDevice.IsReadOnly() {
+			powerLimit = a.restoreDefaults()
 		}
 
-		if err := a.gpuDevice.EnableAutoFanControl(); err != nil {
-			logger.ErrorWithCode(errFactory.Wrap(errors.ErrEnableAutoFan, err)).Send()
+		if a.cfg.IsMetricsEnabled() && a.cfg.IsMetricsShutdownSnapshotEnabled() && a.metrics != nil {
+			a.recordShutdownSnapshot(powerLimit)
 		}
 
 		if err := a.gpuDevice.Shutdown(); err != nil {
@@ -237,18 +927,48 @@ func (a *AppState) cleanup() {
 			logger.Error().Err(err).Msg("Failed to close metrics")
 		}
 	}
-	logger.Info().Msg("Exiting...")
+
+	if a.statusServer != nil {
+		if err := a.statusServer.Close(); err != nil {
+			logger.Debug().Err(err).Msg("Failed to close status endpoint")
+		}
+	}
+	logger.Info().
+		Dur("uptime", a.uptime()).
+		Uint64("ticks", a.ticks).
+		Msg("Exiting...")
+}
+
+// restoreDefaults hands the fans back to the driver and restores the default
+// power limit, returning the limit set
+func (a *AppState) restoreDefaults() gpu.PowerLimit {
+	errFactory := errors.New()
+
+	powerLimits := a.gpuDevice.GetPowerLimits()
+	powerLimitToSet := min(powerLimits.Default, powerLimits.Max)
+	if err := a.gpuDevice.SetPowerLimit(powerLimitToSet); err != nil {
+		logger.ErrorWithCode(errFactory.Wrap(errors.ErrResetPowerLimit, err)).Send()
+	}
+
+	if err := a.gpuDevice.ResetFanControl(); err != nil {
+		logger.ErrorWithCode(errFactory.Wrap(errors.ErrEnableAutoFan, err)).Send()
+	} else {
+		a.autoFanControl = true
+	}
+
+	return powerLimitToSet
 }
 
 func (a *AppState) getGPUState() (GPUState, error) {
 	errFactory := errors.New()
 	logger.Debug().Msg("Getting GPU state...")
 
-	// Get temperature with timeout
-	tempChan := make(chan gpu.Temperature)
-	tempErrChan := make(chan error)
+	// Get temperature with timeout. The channels are buffered so a read
+	// finishing after the timeout doesn't block forever.
+	tempChan := make(chan gpu.Temperature, 1)
+	tempErrChan := make(chan error, 1)
 	go func() {
-		temp, err := a.gpuDevice.GetTemperature()
+		temp, err := a.readTemperature()
 		if err != nil {
 			tempErrChan <- err
 			return
@@ -256,45 +976,103 @@ func (a *AppState) getGPUState() (GPUState, error) {
 		tempChan <- temp
 	}()
 
-	var currentTemperature gpu.Temperature
+	var rawTemperature, currentTemperature gpu.Temperature
 	select {
 	case temp := <-tempChan:
-		currentTemperature = temp
-		logger.Debug().Int("temperature", int(currentTemperature)).Msg("Current temperature retrieved")
+		rawTemperature = temp
+		currentTemperature = temp + gpu.Temperature(a.cfg.GetTemperatureOffset())
+		logger.Debug().
+			Int("temperature", int(currentTemperature)).
+			Int("raw_temperature", int(rawTemperature)).
+			Msg("Current temperature retrieved")
 	case err := <-tempErrChan:
-		logger.Debug().Err(err).Msg("Failed to get temperature")
+		warnReadFailure(err, "Failed to get temperature")
 		return GPUState{}, errFactory.Wrap(errors.ErrGetGPUState, err)
 	case <-time.After(operationTimeout):
 		return GPUState{}, errFactory.New(errors.ErrGetGPUState)
 	}
 
+	gpus := a.readAllTemperatures()
+	if gpus != nil && a.cfg.GetGPUCoordination() == config.GPUCoordinationHottest {
+		hottest := gpus.Max + gpu.Temperature(a.cfg.GetTemperatureOffset())
+		if hottest > currentTemperature {
+			logger.Debug().
+				Int("gpu", gpus.Hottest).
+				Int("temperature", int(hottest)).
+				Msg("Controlling on the hottest GPU")
+			currentTemperature = hottest
+		}
+	}
+
 	// Get fan speeds
 	logger.Debug().Msg("Getting current fan speeds...")
-	currentFanSpeeds := a.gpuDevice.GetCurrentFanSpeeds()
+	currentFanSpeeds, err := a.gpuDevice.GetCurrentFanSpeeds()
+	fanSpeedUnreadable := err != nil
+	if fanSpeedUnreadable {
+		logger.Debug().Err(err).Msg("Failed to get current fan speeds")
+	}
 	logger.Debug().Interface("fanSpeeds", currentFanSpeeds).Msg("Current fan speeds retrieved")
 
+	var currentFanSpeed int
+	if len(currentFanSpeeds) > 0 {
+		currentFanSpeed = int(currentFanSpeeds[0])
+	}
+
 	// Get power limit
 	logger.Debug().Msg("Getting current power limit...")
 	currentPowerLimit := a.gpuDevice.GetCurrentPowerLimit()
 
-	// Update histories with timeout
-	historyChan := make(chan struct{})
-	var avgTemp gpu.Temperature
-	var avgPowerLimit gpu.PowerLimit
+	// Get performance state, -1 when unknown
+	performanceState, err := a.gpuDevice.GetPerformanceState()
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to get performance state")
+		performanceState = -1
+	}
+
+	boardPower := a.readBoardPower()
+	enforcedPowerLimit := a.readEnforcedPowerLimit()
+	powerDraw := a.readPowerDraw()
+	utilization, avgUtilization := a.readUtilization()
+	memory := a.readMemoryInfo()
+	fanPolicies := a.readFanPolicies(len(currentFanSpeeds))
+
+	// Update histories with timeout. The results are sent rather than
+	// written to shared variables, so an update finishing after the timeout
+	// can't race the fallback values.
+	type historyResult struct {
+		avgTemp, filteredTemp gpu.Temperature
+		avgPowerLimit         gpu.PowerLimit
+	}
+	historyChan := make(chan historyResult, 1)
 
 	go func() {
-		avgTemp = a.gpuDevice.UpdateTemperatureHistory(currentTemperature)
+		var result historyResult
+		result.avgTemp = a.gpuDevice.UpdateTemperatureHistory(currentTemperature)
+		result.filteredTemp = a.gpuDevice.GetFilteredTemperature()
 
-		avgPowerLimit = a.gpuDevice.UpdatePowerLimitHistory(currentPowerLimit)
-		logger.Debug().Int("avgPowerLimit", int(avgPowerLimit)).Msg("Power limit history updated")
+		result.avgPowerLimit = a.gpuDevice.UpdatePowerLimitHistory(currentPowerLimit)
+		logger.Debug().Int("avgPowerLimit", int(result.avgPowerLimit)).Msg("Power limit history updated")
 
-		close(historyChan)
+		historyChan <- result
 	}()
 
+	var avgTemp gpu.Temperature
+	var avgPowerLimit gpu.PowerLimit
 	select {
-	case <-historyChan:
+	case result := <-historyChan:
 		// History updates completed successfully
 		logger.Debug().Msg("Power and temperature history updates completed successfully")
+		avgTemp = result.avgTemp
+		avgPowerLimit = result.avgPowerLimit
+
+		// A rejected spike is only kept as the raw temperature
+		if result.filteredTemp != currentTemperature {
+			logger.Warn().
+				Int("temperature", int(currentTemperature)).
+				Int("filtered", int(result.filteredTemp)).
+				Msg("Temperature reading rejected as a spike")
+			currentTemperature = result.filteredTemp
+		}
 	case <-time.After(operationTimeout):
 		logger.Warn().Msg("Power and temperature history updates timed out")
 		// Use current values as averages if history update times out
@@ -304,40 +1082,347 @@ func (a *AppState) getGPUState() (GPUState, error) {
 
 	state := GPUState{
 		CurrentTemperature: int(currentTemperature),
+		RawTemperature:     int(rawTemperature),
 		AverageTemperature: int(avgTemp),
-		CurrentFanSpeed:    int(currentFanSpeeds[0]),
+		CurrentFanSpeed:    currentFanSpeed,
 		CurrentPowerLimit:  int(currentPowerLimit),
 		AveragePowerLimit:  int(avgPowerLimit),
+		FanSpeedUnreadable: fanSpeedUnreadable,
+		PerformanceState:   performanceState,
+		BoardPower:         boardPower,
+		EnforcedPowerLimit: enforcedPowerLimit,
+		PowerConstrained:   a.checkPowerConstrained(int(currentPowerLimit), enforcedPowerLimit),
+		FanPolicies:        fanPolicies,
+		GPUs:               gpus,
+		Utilization:        utilization,
+		AverageUtilization: avgUtilization,
+		MemoryUsed:         memory.Used,
+		MemoryTotal:        memory.Total,
+		PowerDraw:          powerDraw,
 	}
 
 	return state, nil
 }
 
-func (a *AppState) setGPUState(state *GPUState) (GPUState, error) {
-	errFactory := errors.New()
+// warnReadFailure logs a failed GPU read at warn level, at most once every
+// readWarningInterval for each error code so a read failing every tick
+// doesn't flood the log
+func warnReadFailure(err error, msg string) {
+	code := errors.ErrOperationFailed
+	var domainErr errors.Error
+	if errors.As(err, &domainErr) {
+		code = domainErr.Code()
+	}
 
-	targetFanSpeed := a.calculateFanSpeed(state.AverageTemperature, a.cfg.GetTemperature(), a.cfg.GetFanSpeed())
-	targetPowerLimit := a.calculatePowerLimit(state.CurrentTemperature, a.cfg.GetTemperature(),
-		state.CurrentFanSpeed, a.cfg.GetFanSpeed(), state.CurrentPowerLimit)
+	logger.WarnEvery(code, readWarningInterval).Err(err).Msg(msg)
+}
 
-	if err := a.handleFanControl(state, targetFanSpeed); err != nil {
-		return *state, errFactory.Wrap(errors.ErrSetGPUState, err)
+// readBoardPower returns the total board power in watts, or -1 if the card
+// doesn't report it. Cards without the sensor are only queried once.
+func (a *AppState) readBoardPower() int {
+	if a.boardPowerUnsupported {
+		return -1
 	}
 
-	if err := a.handlePowerLimit(state, targetPowerLimit); err != nil {
-		return *state, errFactory.Wrap(errors.ErrSetGPUState, err)
+	power, err := a.gpuDevice.GetTotalBoardPower()
+	if err != nil {
+		var domainErr errors.Error
+		if errors.As(err, &domainErr) && domainErr.Code() == gpu.ErrBoardPowerUnsupported {
+			logger.Debug().Msg("Board power not reported by this GPU")
+			a.boardPowerUnsupported = true
+		} else {
+			warnReadFailure(err, "Failed to get board power")
+		}
+		return -1
 	}
 
-	state.TargetFanSpeed = targetFanSpeed
-	state.TargetPowerLimit = targetPowerLimit
-
-	return *state, nil
+	return int(power)
 }
 
-func (a *AppState) logGPUState(ctx context.Context, state GPUState) {
-	if a.cfg.GetLogLevel() == "debug" {
-		lastFanSpeeds := a.gpuDevice.GetLastFanSpeeds()
-		powerLimits := a.gpuDevice.GetPowerLimits()
+// readEnforcedPowerLimit returns the power limit the card enforces in
+// watts, or -1 if it can't be read. Cards without it are only queried once.
+func (a *AppState) readEnforcedPowerLimit() int {
+	if a.enforcedLimitUnsupported {
+		return -1
+	}
+
+	limit, err := a.gpuDevice.GetEnforcedPowerLimit()
+	if err != nil {
+		var domainErr errors.Error
+		if errors.As(err, &domainErr) && domainErr.Code() == gpu.ErrEnforcedPowerLimitUnsupported {
+			logger.Debug().Msg("Enforced power limit not reported by this GPU")
+			a.enforcedLimitUnsupported = true
+		} else {
+			warnReadFailure(err, "Failed to get enforced power limit")
+		}
+		return -1
+	}
+
+	return int(limit)
+}
+
+// readPowerDraw returns the current power draw in watts, or -1 if it can't
+// be read. Cards without it are only queried once.
+func (a *AppState) readPowerDraw() int {
+	if a.powerUsageUnsupported {
+		return -1
+	}
+
+	usage, err := a.gpuDevice.GetPowerUsage()
+	if err != nil {
+		var domainErr errors.Error
+		if errors.As(err, &domainErr) && domainErr.Code() == gpu.ErrPowerUsageUnsupported {
+			logger.Debug().Msg("Power draw not reported by this GPU")
+			a.powerUsageUnsupported = true
+		} else {
+			warnReadFailure(err, "Failed to get power draw")
+		}
+		return -1
+	}
+
+	return int(usage)
+}
+
+// checkPowerConstrained reports whether the card enforces a lower power
+// limit than the one set, logging when that starts and stops. The card is
+// then limiting power itself, e.g. for thermal or board constraints, and
+// raising the limit has no effect.
+func (a *AppState) checkPowerConstrained(powerLimit, enforcedPowerLimit int) bool {
+	constrained := enforcedPowerLimit >= 0 && powerLimit-enforcedPowerLimit > powerReadbackTolerance
+	if constrained == a.powerConstrained {
+		return constrained
+	}
+	a.powerConstrained = constrained
+
+	if constrained {
+		logger.Warn().
+			Int("power_limit", powerLimit).
+			Int("enforced_power_limit", enforcedPowerLimit).
+			Msg("GPU enforces a lower power limit than the one set, not raising it")
+	} else {
+		logger.Info().
+			Int("power_limit", powerLimit).
+			Int("enforced_power_limit", enforcedPowerLimit).
+			Msg("GPU enforces the power limit set again")
+	}
+
+	return constrained
+}
+
+// readUtilization returns the current and smoothed GPU utilization, -1 for
+// both if it can't be read
+func (a *AppState) readUtilization() (int, int) {
+	utilization, err := a.gpuDevice.GetUtilization()
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to get utilization")
+		return -1, -1
+	}
+
+	return int(utilization), int(a.gpuDevice.UpdateUtilizationHistory(utilization))
+}
+
+// readMemoryInfo returns the frame buffer memory usage, -1 for both if it
+// can't be read
+func (a *AppState) readMemoryInfo() gpu.MemoryInfo {
+	memory, err := a.gpuDevice.GetMemoryInfo()
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to get memory info")
+		return gpu.MemoryInfo{Used: -1, Total: -1}
+	}
+
+	return memory
+}
+
+// readFanPolicies returns the control policy of each fan as reported by the
+// device, nil if the card doesn't report it. A fan found under automatic
+// control while nvidiactl controls them was taken back by the driver or
+// VBIOS, which is logged once until it changes again.
+func (a *AppState) readFanPolicies(fanCount int) []gpu.FanControlPolicy {
+	if a.fanPolicyUnsupported || fanCount == 0 {
+		return nil
+	}
+
+	policies := make([]gpu.FanControlPolicy, 0, fanCount)
+	for i := 0; i < fanCount; i++ {
+		policy, err := a.gpuDevice.GetFanControlPolicy(i)
+		if err != nil {
+			var domainErr errors.Error
+			if errors.As(err, &domainErr) && domainErr.Code() == gpu.ErrFanPolicyUnsupported {
+				logger.Debug().Msg("Fan control policy not reported by this GPU")
+				a.fanPolicyUnsupported = true
+			} else {
+				logger.Debug().Err(err).Int("fan", i).Msg("Failed to get fan control policy")
+			}
+			return nil
+		}
+		logger.Debug().Int("fan", i).Stringer("policy", policy).Msg("Fan control policy")
+		policies = append(policies, policy)
+	}
+
+	reverted := !a.autoFanControl && slices.Contains(policies, gpu.FanPolicyAuto)
+	if reverted && !a.fanPolicyReverted {
+		logger.Warn().Msg("Fans returned to automatic control outside nvidiactl")
+	}
+	a.fanPolicyReverted = reverted
+
+	return policies
+}
+
+// readAllTemperatures returns the aggregate core temperature of all GPUs in
+// the system, nil when GPU coordination is disabled or no GPU could be read
+func (a *AppState) readAllTemperatures() *gpu.TemperatureAggregate {
+	if a.cfg.GetGPUCoordination() == config.GPUCoordinationNone {
+		return nil
+	}
+
+	temperatures, err := a.gpuDevice.GetAllTemperatures()
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to get temperatures of all GPUs")
+		return nil
+	}
+
+	aggregate := gpu.AggregateTemperatures(temperatures)
+	logger.Debug().
+		Interface("gpus", temperatures).
+		Int("max", int(aggregate.Max)).
+		Int("hottest", aggregate.Hottest).
+		Int("spread", int(aggregate.Spread)).
+		Msg("GPU temperatures retrieved")
+
+	return &aggregate
+}
+
+// readTemperature returns the temperature used as control input. When a
+// temperature blend is configured, it is the weighted average of the blended
+// sensors; unreadable sensors are left out and the remaining weights rescaled.
+// Without a blend, the temperature_source sensor is used.
+func (a *AppState) readTemperature() (gpu.Temperature, error) {
+	errFactory := errors.New()
+
+	blend := a.cfg.GetTemperatureBlend()
+	if len(blend) == 0 {
+		return a.readTemperatureSource()
+	}
+
+	var weighted, totalWeight float64
+	var lastErr error
+	for sensor, weight := range blend {
+		temp, err := a.gpuDevice.GetTemperatureBySensor(gpu.TemperatureSensor(sensor))
+		if err != nil {
+			logger.Debug().Err(err).Str("sensor", string(sensor)).Msg("Failed to read blended temperature sensor")
+			lastErr = err
+			continue
+		}
+		weighted += float64(temp) * weight
+		totalWeight += weight
+	}
+
+	if totalWeight == 0 {
+		return 0, errFactory.Wrap(gpu.ErrTemperatureReadFailed, lastErr)
+	}
+
+	blended := gpu.Temperature(math.Round(weighted / totalWeight))
+	logger.Debug().Int("temperature", int(blended)).Msg("Blended temperature computed")
+
+	return blended, nil
+}
+
+// readTemperatureSource reads the configured temperature source. An
+// unreadable memory sensor falls back to the core sensor, warning once.
+func (a *AppState) readTemperatureSource() (gpu.Temperature, error) {
+	source := a.cfg.GetTemperatureSource()
+	if source == config.TemperatureSourceCore {
+		return a.gpuDevice.GetTemperature()
+	}
+
+	memory, err := a.gpuDevice.GetTemperatureBySensor(gpu.SensorMemory)
+	if err != nil {
+		if !a.memoryTemperatureWarned {
+			logger.Warn().
+				Err(err).
+				Str("temperature_source", string(source)).
+				Msg("Memory temperature not readable, using the core temperature instead")
+			a.memoryTemperatureWarned = true
+		}
+		return a.gpuDevice.GetTemperature()
+	}
+	if source == config.TemperatureSourceMemory {
+		return memory, nil
+	}
+
+	core, err := a.gpuDevice.GetTemperature()
+	if err != nil {
+		return 0, err
+	}
+
+	return max(core, memory), nil
+}
+
+// handleFanReadFailure applies the configured action when no fan speed could
+// be read. Returns true if the rest of the tick should be skipped.
+func (a *AppState) handleFanReadFailure() (bool, error) {
+	errFactory := errors.New()
+
+	switch a.cfg.GetFanReadFailureAction() {
+	case config.FanReadFailureSkip:
+		logger.Warn().Msg("Fan speeds unreadable, skipping this update")
+		return true, nil
+	case config.FanReadFailureAuto:
+		if a.cfg.IsMonitorMode() || a.autoFanControl {
+			return true, nil
+		}
+		if err := a.gpuDevice.EnableAutoFanControl(); err != nil {
+			return true, errFactory.Wrap(errors.ErrEnableAutoFan, err)
+		}
+		a.autoFanControl = true
+		logger.Warn().Msg("Fan speeds unreadable, handed fan control back to the driver")
+		return true, nil
+	default:
+		logger.Warn().Msg("Fan speeds unreadable, proceeding with last known speeds")
+		return false, nil
+	}
+}
+
+// setGPUState applies the targets for the actuators in scope. Targets outside
+// the scope are reported as last set by their own loop.
+func (a *AppState) setGPUState(state *GPUState, scope controlScope) (GPUState, error) {
+	errFactory := errors.New()
+
+	var targetFanSpeed, targetPowerLimit int
+	if scope == scopePower {
+		targetPowerLimit = a.calculatePowerOnlyTarget(state)
+	} else {
+		targetFanSpeed, targetPowerLimit = a.calculateTargets(state)
+	}
+
+	if scope != scopePower {
+		if err := a.handleFanControl(state, targetFanSpeed); err != nil {
+			return *state, errFactory.Wrap(errors.ErrSetGPUState, err)
+		}
+	} else {
+		targetFanSpeed = state.TargetFanSpeed
+	}
+
+	if scope != scopeFans {
+		if err := a.handlePowerLimit(state, targetPowerLimit); err != nil {
+			return *state, errFactory.Wrap(errors.ErrSetGPUState, err)
+		}
+	} else if a.powerTarget > 0 {
+		targetPowerLimit = a.powerTarget
+	} else {
+		targetPowerLimit = state.CurrentPowerLimit
+	}
+
+	state.TargetFanSpeed = targetFanSpeed
+	state.TargetPowerLimit = targetPowerLimit
+
+	return *state, nil
+}
+
+func (a *AppState) logGPUState(ctx context.Context, state GPUState) {
+	if a.cfg.GetLogLevel() == "debug" {
+		lastFanSpeeds := a.gpuDevice.GetLastFanSpeeds()
+		powerLimits := a.gpuDevice.GetPowerLimits()
 
 		// If auto fan control is enabled, show target fan speed as 0
 		targetFanSpeed := state.TargetFanSpeed
@@ -349,11 +1434,11 @@ func (a *AppState) logGPUState(ctx context.Context, state GPUState) {
 			Int("current_fan_speed", state.CurrentFanSpeed).
 			Int("target_fan_speed", targetFanSpeed).
 			Interface("last_set_fan_speeds", lastFanSpeeds).
-			Int("max_fan_speed", a.cfg.GetFanSpeed()).
+			Int("max_fan_speed", a.maxFanSpeed()).
 			Int("current_temperature", state.CurrentTemperature).
 			Int("average_temperature", state.AverageTemperature).
 			Int("min_temperature", minTemperature).
-			Int("max_temperature", a.cfg.GetTemperature()).
+			Int("max_temperature", a.targetTemperature()).
 			Int("current_power_limit", state.CurrentPowerLimit).
 			Int("target_power_limit", state.TargetPowerLimit).
 			Int("average_power_limit", state.AveragePowerLimit).
@@ -362,10 +1447,19 @@ func (a *AppState) logGPUState(ctx context.Context, state GPUState) {
 			Int("average_power_limit", state.AveragePowerLimit).
 			Int("min_power_limit", int(powerLimits.Min)).
 			Int("max_power_limit", int(powerLimits.Max)).
+			Int("pstate", state.PerformanceState).
+			Int("board_power", state.BoardPower).
+			Int("power_draw", state.PowerDraw).
+			Int("enforced_power_limit", state.EnforcedPowerLimit).
+			Int("utilization", state.Utilization).
+			Int("average_utilization", state.AverageUtilization).
+			Int("memory_used", state.MemoryUsed).
+			Int("memory_total", state.MemoryTotal).
 			Int("hysteresis", a.cfg.GetHysteresis()).
 			Bool("monitor", a.cfg.IsMonitorMode()).
 			Bool("performance", a.cfg.IsPerformanceMode()).
 			Bool("auto_fan_control", a.autoFanControl).
+			Interface("reason", state.Reason).
 			Msg("")
 	} else if a.cfg.GetLogLevel() == "info" {
 		targetFanSpeed := state.TargetFanSpeed
@@ -375,10 +1469,10 @@ func (a *AppState) logGPUState(ctx context.Context, state GPUState) {
 
 		logger.Info().
 			Int("current_fan_speed", state.CurrentFanSpeed).
-			Int("max_fan_speed", a.cfg.GetFanSpeed()).
+			Int("max_fan_speed", a.maxFanSpeed()).
 			Int("target_fan_speed", targetFanSpeed).
 			Int("current_temperature", state.CurrentTemperature).
-			Int("max_temperature", a.cfg.GetTemperature()).
+			Int("max_temperature", a.targetTemperature()).
 			Int("current_power_limit", state.CurrentPowerLimit).
 			Int("target_power_limit", state.TargetPowerLimit).
 			Msg("")
@@ -386,38 +1480,174 @@ func (a *AppState) logGPUState(ctx context.Context, state GPUState) {
 
 	// Collect metrics in database, if enabled
 	if a.cfg.IsMetricsEnabled() && a.metrics != nil {
-		snapshot := &metrics.MetricsSnapshot{
-			Timestamp: time.Now(),
-			FanSpeed: metrics.FanMetrics{
-				Current: state.CurrentFanSpeed,
-				Target:  state.TargetFanSpeed,
-			},
-			Temperature: metrics.TempMetrics{
-				Current: state.CurrentTemperature,
-				Average: state.AverageTemperature,
-			},
-			PowerLimit: metrics.PowerMetrics{
-				Current: state.CurrentPowerLimit,
-				Target:  state.TargetPowerLimit,
-				Average: state.AveragePowerLimit,
-			},
-			SystemState: metrics.StateMetrics{
-				AutoFanControl:  a.autoFanControl,
-				PerformanceMode: a.cfg.IsPerformanceMode(),
-			},
-		}
-
-		if err := a.metrics.Record(ctx, snapshot); err != nil {
-			errFactory := errors.New()
-			logger.ErrorWithCode(errFactory.Wrap(errors.ErrCollectMetrics, err)).Send()
+		a.recordMetrics(ctx, a.metricsSnapshot(state))
+	}
+}
+
+// metricsSnapshot converts the state of a tick to a metrics snapshot
+func (a *AppState) metricsSnapshot(state GPUState) *metrics.MetricsSnapshot {
+	return &metrics.MetricsSnapshot{
+		Timestamp: a.clock(),
+		FanSpeed: metrics.FanMetrics{
+			Current: state.CurrentFanSpeed,
+			Target:  state.TargetFanSpeed,
+		},
+		Temperature: metrics.TempMetrics{
+			Current: state.CurrentTemperature,
+			Average: state.AverageTemperature,
+			Raw:     state.RawTemperature,
+		},
+		PowerLimit: metrics.PowerMetrics{
+			Current:  state.CurrentPowerLimit,
+			Target:   state.TargetPowerLimit,
+			Average:  state.AveragePowerLimit,
+			Board:    state.BoardPower,
+			Enforced: state.EnforcedPowerLimit,
+			Draw:     state.PowerDraw,
+		},
+		SystemState: metrics.StateMetrics{
+			AutoFanControl:   a.autoFanControl,
+			PerformanceMode:  a.cfg.IsPerformanceMode() && !a.performanceSuspended,
+			PerformanceState: state.PerformanceState,
+		},
+		Load: metrics.LoadMetrics{
+			Utilization: state.Utilization,
+			MemoryUsed:  state.MemoryUsed,
+			MemoryTotal: state.MemoryTotal,
+		},
+		ReturnCodes: a.collectReturnCodes(),
+	}
+}
+
+// recordShutdownSnapshot records the state after control was handed back,
+// so the history shows the daemon letting go. It must run before NVML is
+// shut down and the metrics are closed.
+func (a *AppState) recordShutdownSnapshot(powerLimit gpu.PowerLimit) {
+	state, err := a.getGPUState()
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to get GPU state for the shutdown snapshot")
+		return
+	}
+	state.TargetPowerLimit = int(powerLimit)
+
+	logger.Debug().Msg("Recording shutdown snapshot")
+	a.recordMetrics(context.Background(), a.metricsSnapshot(state))
+}
+
+// recordMetrics writes a snapshot. Repeated failures are logged with
+// exponential backoff, on the 1st, 2nd, 4th, 8th... failure in a row, and
+// metrics collection is switched off after metrics_max_failures.
+func (a *AppState) recordMetrics(ctx context.Context, snapshot *metrics.MetricsSnapshot) {
+	errFactory := errors.New()
+
+	// The tick's sample is written even when shutdown began during the
+	// tick, cleanup only closes the database after the loop returned
+	err := a.metrics.Record(context.WithoutCancel(ctx), snapshot)
+	if err == nil {
+		if a.metricsFailures > 0 {
+			logger.Info().Int("failures", a.metricsFailures).Msg("Metrics collection recovered")
+			a.metricsFailures = 0
+		}
+		return
+	}
+
+	a.metricsFailures++
+	if backoffDue(a.metricsFailures) {
+		logger.ErrorWithCode(errFactory.Wrap(errors.ErrCollectMetrics, err)).
+			Int("consecutive_failures", a.metricsFailures).
+			Send()
+	}
+
+	maxFailures := a.cfg.GetMetricsMaxFailures()
+	if maxFailures <= 0 || a.metricsFailures < maxFailures {
+		return
+	}
+
+	logger.ErrorWithCode(errFactory.Wrap(errors.ErrMetricsDisabled, err)).
+		Int("consecutive_failures", a.metricsFailures).
+		Msg("Disabling metrics collection until restart")
+
+	if err := a.metrics.Close(); err != nil {
+		logger.Debug().Err(err).Msg("Failed to close metrics after disabling")
+	}
+
+	// Disabled metrics never fail to be created
+	a.metrics, _ = metrics.NewService(metrics.Config{Enabled: false})
+	a.metricsFailures = 0
+	a.metricsDisabled = true
+}
+
+// backoffDue reports whether the given consecutive failure is logged, the
+// 1st, 2nd, 4th, 8th...
+func backoffDue(failures int) bool {
+	return failures > 0 && failures&(failures-1) == 0
+}
+
+// collectReturnCodes drains the NVML return codes traced since the last tick
+func (a *AppState) collectReturnCodes() []metrics.ReturnCodeMetrics {
+	codes := a.gpuDevice.DrainReturnCodes()
+	if len(codes) == 0 {
+		return nil
+	}
+
+	result := make([]metrics.ReturnCodeMetrics, len(codes))
+	for i, code := range codes {
+		result[i] = metrics.ReturnCodeMetrics{
+			Timestamp: code.Timestamp,
+			Operation: code.Operation,
+			Code:      code.Code,
+			Message:   code.Message,
 		}
 	}
+
+	return result
 }
 
 func (a *AppState) handleFanControl(state *GPUState, targetFanSpeed int) error {
 	errFactory := errors.New()
 
-	if state.AverageTemperature <= minTemperature {
+	// Without a usable speed range the fans stay with the driver, power
+	// control carries on
+	if !a.gpuDevice.IsFanControllable() {
+		if !a.fanControlSkipped {
+			limits := a.gpuDevice.GetFanSpeedLimits()
+			logger.Info().
+				Int("min", int(limits.Min)).
+				Int("max", int(limits.Max)).
+				Msg("Fan control unsupported, skipping fan adjustments and leaving fans to the driver")
+			a.fanControlSkipped = true
+		}
+		state.Reason.FanAction = reasonFanControlUnavailable
+		a.autoFanControl = true
+		return nil
+	}
+	if a.fanControlSkipped {
+		logger.Info().Msg("Fan control available again, resuming fan adjustments")
+		a.fanControlSkipped = false
+	}
+
+	if a.fanStopped {
+		state.Reason.FanAction = reasonFanStop
+		if a.autoFanControl || state.CurrentFanSpeed != 0 {
+			if err := a.gpuDevice.SetFanSpeed(0); err != nil {
+				return errFactory.Wrap(gpu.ErrSetFanSpeed, err)
+			}
+			a.autoFanControl = false
+			state.Reason.FanAction = reasonApplied
+			logger.Debug().Msgf("Fans stopped at %d°C", state.AverageTemperature)
+		}
+		return nil
+	}
+
+	temperature := state.AverageTemperature
+	if state.FastPath {
+		temperature = max(temperature, state.CurrentTemperature)
+	}
+
+	// With fan_stop the fans stay under manual control down to the stop
+	// temperature
+	if temperature <= minTemperature && !state.FanOverrun && !a.cfg.IsFanStopEnabled() {
+		state.Reason.FanAction = reasonAutoFanControl
 		if !a.autoFanControl {
 			if err := a.gpuDevice.EnableAutoFanControl(); err != nil {
 				return errFactory.Wrap(errors.ErrEnableAutoFan, err)
@@ -427,13 +1657,19 @@ func (a *AppState) handleFanControl(state *GPUState, targetFanSpeed int) error {
 	} else {
 		if a.autoFanControl {
 			logger.Debug().Msgf("Temperature (%d°C) above minimum (%d°C). Switching to manual fan control.",
-				state.AverageTemperature, minTemperature)
+				temperature, minTemperature)
 			a.autoFanControl = false
 		}
-		if !a.autoFanControl && !applyHysteresis(targetFanSpeed, state.CurrentFanSpeed, a.cfg.GetHysteresis()) {
+		state.Reason.FanAction = reasonHysteresis
+		if !a.autoFanControl && (state.FastPath ||
+			!applyHysteresis(targetFanSpeed, state.CurrentFanSpeed, a.cfg.GetHysteresis())) {
 			if err := a.gpuDevice.SetFanSpeed(gpu.FanSpeed(targetFanSpeed)); err != nil {
 				return errFactory.Wrap(gpu.ErrSetFanSpeed, err)
 			}
+			state.Reason.FanAction = reasonApplied
+			if state.FastPath {
+				state.Reason.FanAction = reasonFastPath
+			}
 			logger.Debug().Msgf("Fan speed changed from %d to %d", state.CurrentFanSpeed, targetFanSpeed)
 		}
 	}
@@ -444,19 +1680,60 @@ func (a *AppState) handleFanControl(state *GPUState, targetFanSpeed int) error {
 func (a *AppState) handlePowerLimit(state *GPUState, targetPowerLimit int) error {
 	errFactory := errors.New()
 
-	if !a.cfg.IsPerformanceMode() {
-		if !applyHysteresis(targetPowerLimit, state.CurrentPowerLimit, powerLimitHysteresis) {
-			if err := a.gpuDevice.SetPowerLimit(gpu.PowerLimit(targetPowerLimit)); err != nil {
+	// Brief spikes above the target don't lower power, the temperature has
+	// to stay above it for power_reaction_ticks first. Fans react at once.
+	if state.CurrentTemperature > a.targetTemperature() {
+		a.overTargetStreak++
+	} else {
+		a.overTargetStreak = 0
+	}
+
+	if a.powerUnreliable {
+		state.Reason.PowerAction = reasonPowerUnreliable
+		return nil
+	}
+
+	if !a.performanceModeActive(state.CurrentTemperature) {
+		if targetPowerLimit < state.CurrentPowerLimit && !state.PreThrottle && a.overTargetStreak > 0 &&
+			a.overTargetStreak <= a.cfg.GetPowerReactionTicks() {
+			state.Reason.PowerAction = reasonConfirmingOverTarget
+			return nil
+		}
+
+		// Raising the limit can't win power back from the card
+		if targetPowerLimit > state.CurrentPowerLimit && state.PowerConstrained {
+			state.Reason.PowerAction = reasonEnforcedByCard
+			return nil
+		}
+
+		state.Reason.PowerAction = reasonHysteresis
+		step := a.cfg.GetPowerMinStep()
+		if !applyHysteresis(targetPowerLimit, state.CurrentPowerLimit, powerLimitHysteresis) &&
+			abs(targetPowerLimit-state.CurrentPowerLimit) >= step {
+			// Near the slowdown threshold the cut is applied at once
+			newPowerLimit := targetPowerLimit
+			if !state.PreThrottle {
+				newPowerLimit = a.getGradualPowerLimit(targetPowerLimit, state.CurrentPowerLimit)
+			}
+			newPowerLimit = a.stepPowerLimit(newPowerLimit, state.CurrentPowerLimit, step)
+			if err := a.gpuDevice.SetPowerLimit(gpu.PowerLimit(newPowerLimit)); err != nil {
 				return errFactory.Wrap(gpu.ErrSetPowerLimit, err)
 			}
-			logger.Debug().Msgf("Power limit changed from %d to %d", state.CurrentPowerLimit, targetPowerLimit)
+			a.verifyPowerLimit(newPowerLimit)
+			state.Reason.PowerAction = reasonApplied
+			logger.Debug().Msgf("Power limit changed from %d to %d (target %d)",
+				state.CurrentPowerLimit, newPowerLimit, targetPowerLimit)
 		}
 	} else {
+		state.Reason.Power = reasonPerformanceMode
+		state.Reason.PowerAction = reasonUnchanged
 		maxPowerLimit := a.gpuDevice.GetPowerLimits().Max
 		if state.CurrentPowerLimit < int(maxPowerLimit) {
 			if err := a.gpuDevice.SetPowerLimit(maxPowerLimit); err != nil {
 				return errFactory.Wrap(gpu.ErrSetPowerLimit, err)
 			}
+			a.verifyPowerLimit(int(maxPowerLimit))
+			state.Reason.PowerAction = reasonApplied
 			logger.Debug().Msgf("Power limit set to max: %d", maxPowerLimit)
 		}
 	}
@@ -464,37 +1741,345 @@ func (a *AppState) handlePowerLimit(state *GPUState, targetPowerLimit int) error
 	return nil
 }
 
-func (a *AppState) calculateFanSpeed(averageTemperature, maxTemperature, configMaxFanSpeed int) int {
+// verifyPowerLimit reads the power limit back after a write when
+// power_readback is enabled. Some cards accept writes but ignore them; power
+// control is then stopped rather than fighting the hardware every tick.
+func (a *AppState) verifyPowerLimit(requested int) {
+	if !a.cfg.IsPowerReadbackEnabled() {
+		return
+	}
+
+	applied, err := a.gpuDevice.GetPowerControl().GetLimit()
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to read back power limit")
+		return
+	}
+
+	if abs(int(applied)-requested) <= powerReadbackTolerance {
+		return
+	}
+
+	a.powerUnreliable = true
+	logger.Warn().
+		Int("requested", requested).
+		Int("applied", int(applied)).
+		Msg("Power limit write ignored by the GPU, stopping power control until restart")
+}
+
+// performanceModeActive returns whether performance mode pins the power limit
+// to its maximum. Above performance_max_temperature it is suspended until the
+// temperature drops hysteresis degrees below the limit.
+func (a *AppState) performanceModeActive(temperature int) bool {
+	if !a.cfg.IsPerformanceMode() {
+		return false
+	}
+
+	limit := a.cfg.GetPerformanceMaxTemperature()
+	if limit <= 0 {
+		a.performanceSuspended = false
+		return true
+	}
+
+	switch {
+	case !a.performanceSuspended && temperature > limit:
+		a.performanceSuspended = true
+		logger.Warn().
+			Int("temperature", temperature).
+			Int("performance_max_temperature", limit).
+			Msg("Temperature above limit, suspending performance mode")
+	case a.performanceSuspended && temperature <= limit-a.cfg.GetHysteresis():
+		a.performanceSuspended = false
+		logger.Info().
+			Int("temperature", temperature).
+			Int("performance_max_temperature", limit).
+			Msg("Temperature back below limit, resuming performance mode")
+	}
+
+	return !a.performanceSuspended
+}
+
+// calculateTargets calculates the fan speed and power limit targets and
+// coordinates them according to the cooling policy. The reasoning is
+// recorded in the state.
+func (a *AppState) calculateTargets(state *GPUState) (int, int) {
+	targetTemperature := a.targetTemperature()
+	maxFanSpeed := a.maxFanSpeed()
+
+	state.Reason = decisionReason{
+		TemperatureSource: temperatureSource(a.cfg),
+		TemperatureOffset: a.cfg.GetTemperatureOffset(),
+		CoolWith:          string(a.cfg.GetCoolingPriority()),
+	}
+	if a.profileActive {
+		state.Reason.Profile = a.profile.Name
+	}
+
+	targetFanSpeed := a.calculateFanTarget(state, targetTemperature, maxFanSpeed)
+	targetPowerLimit := a.calculatePowerTarget(state, targetTemperature, maxFanSpeed)
+	a.logPreThrottle(state)
+
+	targets := a.coordinateTargets(state, targetTemperature, targetFanSpeed, targetPowerLimit)
+	if targets.FanSpeed != targetFanSpeed {
+		state.Reason.Fan = reasonHeldByPolicy
+	}
+
+	return targets.FanSpeed, targets.PowerLimit
+}
+
+// calculatePowerOnlyTarget calculates the power limit target for the power
+// loop. The fan target is taken as last set by the fan loop, and none of the
+// fan state (fan stop, overrun, fast path, pre-throttle logging) is touched,
+// since the state is a copy of the last fan tick.
+func (a *AppState) calculatePowerOnlyTarget(state *GPUState) int {
+	targetTemperature := a.targetTemperature()
+	targetPowerLimit := a.calculatePowerTarget(state, targetTemperature, a.maxFanSpeed())
+
+	return a.coordinateTargets(state, targetTemperature, state.TargetFanSpeed, targetPowerLimit).PowerLimit
+}
+
+// calculateFanTarget returns the fan speed target before coordination,
+// updating the fan stop, overrun and fast path state
+func (a *AppState) calculateFanTarget(state *GPUState, targetTemperature, maxFanSpeed int) int {
+	// A large jump is acted on at once instead of waiting for the average
+	// to catch up
+	fanTemperature := state.AverageTemperature
+	state.FastPath = a.isFastPath(state)
+	if state.FastPath {
+		fanTemperature = max(state.AverageTemperature, state.CurrentTemperature)
+	}
+
+	targetFanSpeed, curvePosition := a.calculateFanSpeed(fanTemperature, targetTemperature, maxFanSpeed)
+	state.Reason.CurvePosition = math.Round(curvePosition*100) / 100
+	switch {
+	case fanTemperature <= minTemperature:
+		state.Reason.Fan = reasonBelowMinTemperature
+	case a.cfg.GetFanStrategy() == control.StrategySteps:
+		state.Reason.Fan = reasonFanStep
+	case a.cfg.GetFanStrategy() != control.StrategyCurve:
+		state.Reason.Fan = a.cfg.GetFanStrategy()
+	case fanTemperature >= targetTemperature:
+		state.Reason.Fan = reasonAtTargetTemperature
+	default:
+		state.Reason.Fan = reasonCurve
+	}
+
+	targetFanSpeed, state.FanOverrun = a.applyFanOverrun(fanTemperature, targetTemperature, targetFanSpeed)
+	if state.FanOverrun {
+		state.Reason.Fan = reasonFanOverrun
+	}
+	if a.updateFanStop(fanTemperature, state.FanOverrun) {
+		targetFanSpeed = 0
+		state.Reason.Fan = reasonFanStop
+	}
+
+	return targetFanSpeed
+}
+
+// calculatePowerTarget returns the power limit target before coordination,
+// lowered near the slowdown threshold by pre-throttle protection
+func (a *AppState) calculatePowerTarget(state *GPUState, targetTemperature, maxFanSpeed int) int {
+	// Power normally only drops once the fans are saturated
+	fanSpeedForPower := state.CurrentFanSpeed
+	if a.policy.LowersPowerFirst() {
+		fanSpeedForPower = maxFanSpeed
+	}
+	targetPowerLimit := a.calculatePowerLimit(state.CurrentTemperature, targetTemperature,
+		fanSpeedForPower, maxFanSpeed, state.CurrentPowerLimit)
+	limit, preThrottle := a.preThrottleLimit(state.CurrentTemperature, state.CurrentPowerLimit)
+	state.PreThrottle = preThrottle
+	if preThrottle {
+		targetPowerLimit = min(targetPowerLimit, limit)
+	}
+	switch tempDiff := state.CurrentTemperature - targetTemperature; {
+	case state.PreThrottle:
+		state.Reason.Power = reasonPreThrottle
+	case len(a.cfg.GetPowerCurve()) > 0:
+		state.Reason.Power = reasonPowerCurve
+	case tempDiff > a.cfg.GetPowerLowerThreshold() && fanSpeedForPower >= maxFanSpeed:
+		state.Reason.Power = reasonOverTarget
+	case tempDiff > a.cfg.GetPowerLowerThreshold():
+		state.Reason.Power = reasonFansNotSaturated
+	case tempDiff < -a.cfg.GetPowerRaiseThreshold():
+		state.Reason.Power = reasonUnderTarget
+	default:
+		state.Reason.Power = reasonOnTarget
+	}
+
+	return targetPowerLimit
+}
+
+// coordinateTargets coordinates the fan speed and power limit targets
+// according to the cooling policy
+func (a *AppState) coordinateTargets(state *GPUState, targetTemperature, targetFanSpeed, targetPowerLimit int) control.Targets {
+	targets := a.policy.Coordinate(control.Input{
+		Temperature:       state.CurrentTemperature,
+		TargetTemperature: targetTemperature,
+		CurrentFanSpeed:   state.CurrentFanSpeed,
+		FanSpeed:          targetFanSpeed,
+		CurrentPowerLimit: state.CurrentPowerLimit,
+		PowerLimit:        targetPowerLimit,
+		MinPowerLimit:     int(a.gpuDevice.GetPowerLimits().Min),
+	})
+	// Throttling would cost more than the policy saves
+	if state.PreThrottle {
+		targets.PowerLimit = targetPowerLimit
+	}
+	if targets.PowerLimit != targetPowerLimit {
+		state.Reason.Power = reasonNotLoweredByPolicy
+	}
+
+	return targets
+}
+
+// applyFanOverrun holds the fans at the speed reached at the target
+// temperature for fan_overrun after the temperature drops below it. It
+// returns the fan speed target and whether the overrun raised it.
+func (a *AppState) applyFanOverrun(temperature, targetTemperature, targetFanSpeed int) (int, bool) {
+	overrun := a.cfg.GetFanOverrun()
+	if overrun <= 0 {
+		return targetFanSpeed, false
+	}
+
+	now := a.clock()
+	if temperature >= targetTemperature {
+		if now.Sub(a.lastHigh) > overrun {
+			a.overrunFanSpeed = 0
+		}
+		a.lastHigh = now
+		a.overrunFanSpeed = max(a.overrunFanSpeed, targetFanSpeed)
+		return targetFanSpeed, false
+	}
+
+	if a.lastHigh.IsZero() || now.Sub(a.lastHigh) > overrun || targetFanSpeed >= a.overrunFanSpeed {
+		return targetFanSpeed, false
+	}
+
+	return a.overrunFanSpeed, true
+}
+
+// preThrottleLimit returns a power limit cut harder the closer the
+// temperature gets to the slowdown threshold, once it is within
+// pre_throttle_margin of it. A controlled reduction costs less sustained
+// performance than the clock drop of hardware throttling.
+func (a *AppState) preThrottleLimit(temperature, currentPowerLimit int) (int, bool) {
+	margin := a.cfg.GetPreThrottleMargin()
+	slowdown := int(a.gpuDevice.GetTemperatureThresholds().Slowdown)
+	if margin <= 0 || slowdown <= 0 || temperature < slowdown-margin {
+		return 0, false
+	}
+
+	adjustment := min((temperature-(slowdown-margin)+1)*wattsPerDegree*2, 2*maxPowerLimitChange)
+	powerLimits := a.gpuDevice.GetPowerLimits()
+
+	return clamp(currentPowerLimit-adjustment, int(powerLimits.Min), int(powerLimits.Max)), true
+}
+
+// logPreThrottle logs when pre-throttle protection engages and releases
+func (a *AppState) logPreThrottle(state *GPUState) {
+	if state.PreThrottle == a.preThrottle {
+		return
+	}
+	a.preThrottle = state.PreThrottle
+
+	slowdown := int(a.gpuDevice.GetTemperatureThresholds().Slowdown)
+	if state.PreThrottle {
+		logger.Info().
+			Int("temperature", state.CurrentTemperature).
+			Int("slowdown", slowdown).
+			Int("margin", a.cfg.GetPreThrottleMargin()).
+			Msg("Temperature close to the slowdown threshold, lowering power to avoid throttling")
+		return
+	}
+	logger.Info().
+		Int("temperature", state.CurrentTemperature).
+		Int("slowdown", slowdown).
+		Msg("Temperature clear of the slowdown threshold, pre-throttle protection released")
+}
+
+// calculateFanSpeed returns the fan speed target of the fan strategy and its
+// position on the curve, from 0 at the minimum to 1 at the maximum
+func (a *AppState) calculateFanSpeed(averageTemperature, maxTemperature, configMaxFanSpeed int) (int, float64) {
 	fanSpeedLimits := a.gpuDevice.GetFanSpeedLimits()
 	minFanSpeed := fanSpeedLimits.Min
 	maxFanSpeed := fanSpeedLimits.Max
 
+	// Performance mode has a steeper curve, the cap keeps it bearable
+	if a.cfg.IsPerformanceMode() {
+		configMaxFanSpeed = min(configMaxFanSpeed, a.cfg.GetPerformanceFanCap())
+	}
+
 	maxFanSpeed = gpu.FanSpeed(min(int(maxFanSpeed), configMaxFanSpeed))
 
 	if averageTemperature <= minTemperature {
-		return int(minFanSpeed)
+		// The driver isn't given the fans with fan_stop, they keep the floor
+		if a.cfg.IsFanStopEnabled() {
+			return clamp(a.cfg.GetFanFloor(), int(minFanSpeed), int(maxFanSpeed)), 0
+		}
+		return int(minFanSpeed), 0
 	}
 
-	if averageTemperature >= maxTemperature {
-		return int(maxFanSpeed)
+	// Some cards stop their fans at the hardware minimum, a floor keeps
+	// them spinning while under manual control
+	if floor := a.cfg.GetFanFloor(); floor > int(minFanSpeed) {
+		minFanSpeed = gpu.FanSpeed(min(floor, int(maxFanSpeed)))
 	}
 
-	tempRange := float64(maxTemperature - minTemperature)
-	tempPercentage := float64(averageTemperature-minTemperature) / tempRange
+	target := a.fanStrategy.Compute(control.ControlInput{
+		Temperature:    averageTemperature,
+		MinTemperature: minTemperature,
+		MaxTemperature: maxTemperature,
+		MinFanSpeed:    int(minFanSpeed),
+		MaxFanSpeed:    int(maxFanSpeed),
+		Hysteresis:     a.cfg.GetHysteresis(),
+		FanStep:        a.cfg.GetFanStep(),
+		Performance:    a.cfg.IsPerformanceMode(),
+		Interval:       a.cfg.GetIntervalDuration(),
+	})
+
+	return clamp(target.Percent, int(minFanSpeed), int(maxFanSpeed)), target.CurvePosition
+}
+
+// updateFanStop reports whether fan_stop holds the fans stopped. They stop
+// below fan_stop_temperature and only restart above it plus the hysteresis.
+func (a *AppState) updateFanStop(temperature int, overrun bool) bool {
+	if !a.cfg.IsFanStopEnabled() || overrun {
+		a.fanStopped = false
+		return false
+	}
 
-	fanSpeedPercentage := a.calculateFanSpeedPercentage(tempPercentage)
-	fanSpeedRange := int(maxFanSpeed) - int(minFanSpeed)
-	targetFanSpeed := int(float64(fanSpeedRange)*fanSpeedPercentage) + int(minFanSpeed)
+	threshold := a.cfg.GetFanStopTemperature()
+	if a.fanStopped {
+		threshold += a.cfg.GetHysteresis()
+	}
+	a.fanStopped = temperature < threshold
 
-	return clamp(targetFanSpeed, int(minFanSpeed), int(maxFanSpeed))
+	return a.fanStopped
 }
 
-func (a *AppState) calculateFanSpeedPercentage(tempPercentage float64) float64 {
-	if a.cfg.IsPerformanceMode() {
-		return math.Pow(tempPercentage, performancePowFactor)
+// newFanStrategy creates the configured fan strategy
+func newFanStrategy(cfg config.Provider) (control.FanStrategy, error) {
+	steps := make([]control.Step, 0, len(cfg.GetFanSteps()))
+	for _, step := range cfg.GetFanSteps() {
+		steps = append(steps, control.Step{Temperature: step.Temperature, FanSpeed: step.Value})
+	}
+
+	curve := make([]control.Step, 0, len(cfg.GetFanCurve()))
+	for _, point := range cfg.GetFanCurve() {
+		curve = append(curve, control.Step{Temperature: point.Temperature, FanSpeed: point.Value})
 	}
 
-	return math.Pow(tempPercentage, normalPowFactor)
+	gains := cfg.GetFanPIDGains()
+
+	return control.NewFanStrategy(cfg.GetFanStrategy(), control.StrategyConfig{
+		Steps:      steps,
+		Curve:      curve,
+		FixedSpeed: cfg.GetFanFixedSpeed(),
+		Gains: control.PIDGains{
+			Proportional: gains.Proportional,
+			Integral:     gains.Integral,
+			Derivative:   gains.Derivative,
+		},
+	})
 }
 
 func (a *AppState) calculatePowerLimit(
@@ -502,14 +2087,21 @@ func (a *AppState) calculatePowerLimit(
 ) int {
 	powerLimits := a.gpuDevice.GetPowerLimits()
 
+	if curve := a.cfg.GetPowerCurve(); len(curve) > 0 {
+		targetPowerLimit := int(math.Round(interpolateCurve(curve, currentTemperature)))
+		return clamp(targetPowerLimit, int(powerLimits.Min), int(powerLimits.Max))
+	}
+
+	// Asymmetric thresholds keep the limit from oscillating when the card
+	// hovers around the target
 	tempDiff := currentTemperature - targetTemperature
-	if tempDiff > 0 && currentFanSpeed >= maxFanSpeed {
+	if tempDiff > a.cfg.GetPowerLowerThreshold() && currentFanSpeed >= maxFanSpeed {
 		adjustment := min(tempDiff*wattsPerDegree, maxPowerLimitChange)
 
 		return clamp(currentPowerLimit-adjustment, int(powerLimits.Min), int(powerLimits.Max))
 	}
 
-	if tempDiff < 0 {
+	if tempDiff < -a.cfg.GetPowerRaiseThreshold() {
 		adjustment := min(-tempDiff*wattsPerDegree, maxPowerLimitChange)
 
 		return clamp(currentPowerLimit+adjustment, int(powerLimits.Min), int(powerLimits.Max))
@@ -518,6 +2110,71 @@ func (a *AppState) calculatePowerLimit(
 	return currentPowerLimit
 }
 
+// getGradualPowerLimit moves the power limit halfway towards the target,
+// capped at maxPowerLimitChange. The step is halved again when the direction
+// reverses compared to the previous change, damping oscillation.
+func (a *AppState) getGradualPowerLimit(targetPowerLimit, currentPowerLimit int) int {
+	diff := targetPowerLimit - currentPowerLimit
+	if diff == 0 {
+		return currentPowerLimit
+	}
+
+	step := min(max(abs(diff)/2, 1), maxPowerLimitChange)
+
+	lastChange := currentPowerLimit - int(a.gpuDevice.GetLastPowerLimit())
+	if lastChange != 0 && (lastChange > 0) != (diff > 0) {
+		step = max(step/2, 1)
+	}
+
+	if diff < 0 {
+		step = -step
+	}
+
+	powerLimits := a.gpuDevice.GetPowerLimits()
+
+	return clamp(currentPowerLimit+step, int(powerLimits.Min), int(powerLimits.Max))
+}
+
+// stepPowerLimit rounds the change from currentPowerLimit to a multiple of
+// step, moving at least one step so large steps aren't stalled by the
+// gradual ramp. The result stays within the hardware limits.
+func (a *AppState) stepPowerLimit(powerLimit, currentPowerLimit, step int) int {
+	if step <= 1 || powerLimit == currentPowerLimit {
+		return powerLimit
+	}
+
+	change := quantize(powerLimit-currentPowerLimit, step)
+	if change == 0 {
+		change = step
+		if powerLimit < currentPowerLimit {
+			change = -step
+		}
+	}
+
+	powerLimits := a.gpuDevice.GetPowerLimits()
+
+	return clamp(currentPowerLimit+change, int(powerLimits.Min), int(powerLimits.Max))
+}
+
+// isFastPath reports whether the temperature rose by at least
+// fast_path_threshold since the last tick
+func (a *AppState) isFastPath(state *GPUState) bool {
+	threshold := a.cfg.GetFastPathThreshold()
+	if threshold <= 0 || a.lastState == nil {
+		return false
+	}
+
+	jump := state.CurrentTemperature - a.lastState.CurrentTemperature
+	if jump < threshold {
+		return false
+	}
+
+	logger.Debug().Msgf("Temperature jumped by %d°C (from %d°C to %d°C), bypassing hysteresis",
+		jump, a.lastState.CurrentTemperature, state.CurrentTemperature)
+
+	return true
+}
+
 func applyHysteresis(newSpeed, currentSpeed, hysteresis int) bool {
 	return abs(newSpeed-currentSpeed) <= hysteresis
 }
@@ -531,6 +2188,15 @@ func abs(x int) int {
 	return x
 }
 
+// quantize rounds value to the nearest multiple of step
+func quantize(value, step int) int {
+	if step <= 1 {
+		return value
+	}
+
+	return int(math.Round(float64(value)/float64(step))) * step
+}
+
 func clamp(value, minValue, maxValue int) int {
 	if value < minValue {
 		return minValue
@@ -0,0 +1,676 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/control"
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/gpu"
+	"codeberg.org/mutker/nvidiactl/internal/metrics"
+)
+
+// testConfigPath is the config file the test loader reads, rewritten by
+// newTestConfig
+var testConfigPath string
+
+var (
+	testLoaderOnce sync.Once
+	testLoader     config.Loader
+	testLoaderErr  error
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "nvidiactl-test")
+	if err != nil {
+		panic(err)
+	}
+	testConfigPath = filepath.Join(dir, "nvidiactl.conf")
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestConfig returns the configuration of a config file with content.
+// Flags can only be defined once per process, so the loader is created once
+// and reloaded for every config.
+func newTestConfig(t *testing.T, content string) config.Provider {
+	t.Helper()
+
+	if err := os.WriteFile(testConfigPath, []byte(content), 0o600); err != nil {
+		t.Fatalf("writing the config file: %v", err)
+	}
+
+	testLoaderOnce.Do(func() {
+		testLoader = config.NewLoader()
+		_, testLoaderErr = testLoader.Load(context.Background(),
+			config.WithConfigFile(testConfigPath), config.WithArgs([]string{}))
+	})
+	if testLoaderErr != nil {
+		t.Fatalf("loading the config: %v", testLoaderErr)
+	}
+
+	cfg, err := testLoader.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("loading config %q: %v", content, err)
+	}
+	return cfg
+}
+
+// fakeGPU is a controller for a card with two fans reporting fixed readings.
+// Calls it doesn't implement panic on the embedded nil Controller.
+type fakeGPU struct {
+	gpu.Controller
+
+	temperature gpu.Temperature
+	fanSpeed    gpu.FanSpeed
+	fanLimits   gpu.FanSpeedLimits
+	powerLimit  gpu.PowerLimit
+	powerLimits gpu.PowerLimits
+	autoFan     bool
+	readOnly    bool
+	// filtered is the temperature kept by the spike filter, the reading
+	// itself if 0
+	filtered gpu.Temperature
+
+	lastPowerLimit gpu.PowerLimit
+	// fanWrites and powerWrites record the values set
+	fanWrites   []gpu.FanSpeed
+	powerWrites []gpu.PowerLimit
+	shutdown    bool
+}
+
+func newFakeGPU() *fakeGPU {
+	return &fakeGPU{
+		temperature: 60,
+		fanSpeed:    40,
+		fanLimits:   gpu.FanSpeedLimits{Min: 30, Max: 100, Default: 40},
+		powerLimit:  250,
+		powerLimits: gpu.PowerLimits{Min: 100, Max: 300, Default: 250},
+	}
+}
+
+func (f *fakeGPU) Shutdown() error {
+	f.shutdown = true
+	return nil
+}
+
+func (f *fakeGPU) IsReadOnly() bool { return f.readOnly }
+
+func (f *fakeGPU) GetTemperature() (gpu.Temperature, error) { return f.temperature, nil }
+
+func (f *fakeGPU) UpdateTemperatureHistory(temp gpu.Temperature) gpu.Temperature { return temp }
+
+func (f *fakeGPU) GetFilteredTemperature() gpu.Temperature {
+	if f.filtered != 0 {
+		return f.filtered
+	}
+	return f.temperature
+}
+
+func (f *fakeGPU) GetTemperatureThresholds() gpu.TemperatureThresholds {
+	return gpu.TemperatureThresholds{}
+}
+
+func (f *fakeGPU) GetPerformanceState() (int, error) { return 2, nil }
+
+func (f *fakeGPU) GetUtilization() (gpu.Utilization, error) { return 50, nil }
+
+func (f *fakeGPU) UpdateUtilizationHistory(u gpu.Utilization) gpu.Utilization { return u }
+
+func (f *fakeGPU) GetMemoryInfo() (gpu.MemoryInfo, error) {
+	return gpu.MemoryInfo{Used: 1024, Total: 8192}, nil
+}
+
+func (f *fakeGPU) EnableAutoFanControl() error {
+	f.autoFan = true
+	return nil
+}
+
+func (f *fakeGPU) DisableAutoFanControl() error {
+	f.autoFan = false
+	return nil
+}
+
+func (f *fakeGPU) ResetFanControl() error { return f.EnableAutoFanControl() }
+
+func (f *fakeGPU) IsAutoFanControl() bool { return f.autoFan }
+
+func (f *fakeGPU) IsFanControllable() bool { return f.fanLimits.Max > f.fanLimits.Min }
+
+func (f *fakeGPU) GetFanControlPolicy(int) (gpu.FanControlPolicy, error) {
+	return 0, errors.New().New(gpu.ErrFanPolicyUnsupported)
+}
+
+func (f *fakeGPU) GetCurrentFanSpeeds() ([]gpu.FanSpeed, error) {
+	return []gpu.FanSpeed{f.fanSpeed, f.fanSpeed}, nil
+}
+
+func (f *fakeGPU) SetFanSpeed(speed gpu.FanSpeed) error {
+	f.fanSpeed = speed
+	f.autoFan = false
+	f.fanWrites = append(f.fanWrites, speed)
+	return nil
+}
+
+func (f *fakeGPU) GetLastFanSpeeds() []gpu.FanSpeed { return []gpu.FanSpeed{f.fanSpeed, f.fanSpeed} }
+
+func (f *fakeGPU) GetFanSpeedLimits() gpu.FanSpeedLimits { return f.fanLimits }
+
+func (f *fakeGPU) GetCurrentPowerLimit() gpu.PowerLimit { return f.powerLimit }
+
+func (f *fakeGPU) GetCachedPowerLimit() gpu.PowerLimit { return f.powerLimit }
+
+func (f *fakeGPU) GetLastPowerLimit() gpu.PowerLimit { return f.lastPowerLimit }
+
+func (f *fakeGPU) SetPowerLimit(limit gpu.PowerLimit) error {
+	f.lastPowerLimit = f.powerLimit
+	f.powerLimit = limit
+	f.powerWrites = append(f.powerWrites, limit)
+	return nil
+}
+
+func (f *fakeGPU) GetPowerLimits() gpu.PowerLimits { return f.powerLimits }
+
+func (f *fakeGPU) UpdatePowerLimitHistory(limit gpu.PowerLimit) gpu.PowerLimit { return limit }
+
+func (f *fakeGPU) GetTotalBoardPower() (gpu.PowerLimit, error) {
+	return 0, errors.New().New(gpu.ErrBoardPowerUnsupported)
+}
+
+func (f *fakeGPU) GetEnforcedPowerLimit() (gpu.PowerLimit, error) { return f.powerLimit, nil }
+
+func (f *fakeGPU) GetPowerUsage() (gpu.PowerLimit, error) { return 180, nil }
+
+func (f *fakeGPU) DrainReturnCodes() []gpu.ReturnCode { return nil }
+
+// fakeMetrics records snapshots in memory and refuses them once closed, or
+// with err if set
+type fakeMetrics struct {
+	snapshots []*metrics.MetricsSnapshot
+	closed    bool
+	err       error
+}
+
+func (m *fakeMetrics) Record(_ context.Context, snapshot *metrics.MetricsSnapshot) error {
+	if m.closed {
+		return errors.New().New(metrics.ErrStorageAccess)
+	}
+	if m.err != nil {
+		return m.err
+	}
+	m.snapshots = append(m.snapshots, snapshot)
+	return nil
+}
+
+func (m *fakeMetrics) Query(context.Context, time.Time, time.Time) ([]*metrics.MetricsSnapshot, error) {
+	return m.snapshots, nil
+}
+
+func (m *fakeMetrics) Close() error {
+	m.closed = true
+	return nil
+}
+
+// newTestAppState returns the state of a daemon controlling device with cfg
+func newTestAppState(t *testing.T, cfg config.Provider, device gpu.Controller) *AppState {
+	t.Helper()
+
+	policy, err := control.NewPolicy(control.Priority(cfg.GetCoolingPriority()))
+	if err != nil {
+		t.Fatalf("creating the cooling policy: %v", err)
+	}
+	fanStrategy, err := newFanStrategy(cfg)
+	if err != nil {
+		t.Fatalf("creating the fan strategy: %v", err)
+	}
+
+	now := time.Now()
+	return &AppState{
+		cfg:           cfg,
+		gpuDevice:     device,
+		states:        newBroadcaster(),
+		policy:        policy,
+		fanStrategy:   fanStrategy,
+		clock:         time.Now,
+		startedAt:     now,
+		lastHeartbeat: now,
+		reloads:       make(chan config.Provider, 1),
+	}
+}
+
+func TestCleanupRecordsShutdownSnapshotBeforeClose(t *testing.T) {
+	cfg := newTestConfig(t, "metrics = true\nmetrics_shutdown_snapshot = true\n")
+	device := newFakeGPU()
+	device.powerLimit = 200
+	collector := &fakeMetrics{}
+
+	a := newTestAppState(t, cfg, device)
+	a.metrics = collector
+
+	a.cleanup()
+
+	if !collector.closed {
+		t.Fatal("cleanup() didn't close the metrics")
+	}
+	if len(collector.snapshots) != 1 {
+		t.Fatalf("cleanup() recorded %d snapshots before closing the metrics, want 1", len(collector.snapshots))
+	}
+
+	snapshot := collector.snapshots[0]
+	if snapshot.PowerLimit.Target != int(device.powerLimits.Default) {
+		t.Errorf("shutdown snapshot power target = %d, want the default %d",
+			snapshot.PowerLimit.Target, device.powerLimits.Default)
+	}
+	if !snapshot.SystemState.AutoFanControl {
+		t.Error("shutdown snapshot doesn't show the fans handed back to the driver")
+	}
+	if !device.shutdown {
+		t.Error("cleanup() didn't shut the GPU down")
+	}
+}
+
+func TestCleanupSkipsShutdownSnapshotWhenDisabled(t *testing.T) {
+	cfg := newTestConfig(t, "metrics = true\nmetrics_shutdown_snapshot = false\n")
+	collector := &fakeMetrics{}
+
+	a := newTestAppState(t, cfg, newFakeGPU())
+	a.metrics = collector
+
+	a.cleanup()
+
+	if len(collector.snapshots) != 0 {
+		t.Errorf("cleanup() recorded %d snapshots with the shutdown snapshot disabled, want 0", len(collector.snapshots))
+	}
+}
+
+func TestShutdownSnapshotAtTheTimeOfTheLastTick(t *testing.T) {
+	cfg := newTestConfig(t, "metrics = true\nmetrics_shutdown_snapshot = true\n")
+
+	metricsConfig := metrics.DefaultConfig()
+	metricsConfig.Enabled = true
+	metricsConfig.DBPath = filepath.Join(t.TempDir(), "metrics.db")
+	collector, err := metrics.NewService(metricsConfig)
+	if err != nil {
+		t.Fatalf("creating the metrics: %v", err)
+	}
+
+	// The shutdown snapshot lands on the timestamp of the last tick
+	a := newTestAppState(t, cfg, newFakeGPU())
+	a.metrics = collector
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	a.clock = func() time.Time { return now }
+
+	state, err := a.getGPUState()
+	if err != nil {
+		t.Fatalf("getGPUState() unexpected error: %v", err)
+	}
+	a.recordMetrics(context.Background(), a.metricsSnapshot(state))
+	a.cleanup()
+
+	if a.metricsFailures != 0 {
+		t.Errorf("metrics failures = %d, want 0", a.metricsFailures)
+	}
+	history, err := metrics.ReadHistory(metricsConfig.DBPath, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("ReadHistory() unexpected error: %v", err)
+	}
+	if len(history) != 1 {
+		t.Fatalf("recorded %d samples, want the shutdown snapshot replacing the last tick", len(history))
+	}
+	if !history[0].SystemState.AutoFanControl {
+		t.Error("recorded sample isn't the shutdown snapshot with the fans handed back")
+	}
+}
+
+func TestRecordMetricsDisablesAfterMaxFailures(t *testing.T) {
+	cfg := newTestConfig(t, "metrics = true\nmetrics_max_failures = 3\n")
+	collector := &fakeMetrics{err: errors.New().New(metrics.ErrStorageAccess)}
+
+	a := newTestAppState(t, cfg, newFakeGPU())
+	a.metrics = collector
+
+	for failures := 1; failures < 3; failures++ {
+		a.recordMetrics(context.Background(), &metrics.MetricsSnapshot{Timestamp: time.Now()})
+		if a.metricsFailures != failures || a.metricsDisabled {
+			t.Fatalf("after %d failures: consecutive failures %d, disabled %v", failures, a.metricsFailures, a.metricsDisabled)
+		}
+	}
+
+	// A success in between starts the count over
+	collector.err = nil
+	a.recordMetrics(context.Background(), &metrics.MetricsSnapshot{Timestamp: time.Now()})
+	if a.metricsFailures != 0 {
+		t.Fatalf("consecutive failures after a success = %d, want 0", a.metricsFailures)
+	}
+
+	collector.err = errors.New().New(metrics.ErrStorageAccess)
+	for i := 0; i < 3; i++ {
+		a.recordMetrics(context.Background(), &metrics.MetricsSnapshot{Timestamp: time.Now()})
+	}
+	if !a.metricsDisabled {
+		t.Fatal("metrics still enabled after metrics_max_failures consecutive failures")
+	}
+	if !collector.closed {
+		t.Error("the failing metrics weren't closed when disabled")
+	}
+	if a.metrics == collector {
+		t.Error("the failing metrics are still recorded to")
+	}
+}
+
+func TestBackoffDue(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     bool
+	}{
+		{failures: 0, want: false},
+		{failures: 1, want: true},
+		{failures: 2, want: true},
+		{failures: 3, want: false},
+		{failures: 4, want: true},
+		{failures: 6, want: false},
+		{failures: 8, want: true},
+		{failures: 9, want: false},
+		{failures: 1024, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := backoffDue(tt.failures); got != tt.want {
+			t.Errorf("backoffDue(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestGetGPUStateRejectsSpike(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		reading     gpu.Temperature
+		filtered    gpu.Temperature
+		wantCurrent int
+		wantRaw     int
+	}{
+		{name: "accepted", reading: 62, wantCurrent: 62, wantRaw: 62},
+		{name: "spike rejected", reading: 95, filtered: 61, wantCurrent: 61, wantRaw: 95},
+		{name: "spike rejected with offset", config: "temperature_offset = 5\n", reading: 95, filtered: 66, wantCurrent: 66, wantRaw: 95},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newFakeGPU()
+			device.temperature = tt.reading
+			device.filtered = tt.filtered
+			a := newTestAppState(t, newTestConfig(t, tt.config), device)
+
+			state, err := a.getGPUState()
+			if err != nil {
+				t.Fatalf("getGPUState() unexpected error: %v", err)
+			}
+			if state.CurrentTemperature != tt.wantCurrent {
+				t.Errorf("current temperature = %d, want %d", state.CurrentTemperature, tt.wantCurrent)
+			}
+			if state.RawTemperature != tt.wantRaw {
+				t.Errorf("raw temperature = %d, want the reading %d", state.RawTemperature, tt.wantRaw)
+			}
+		})
+	}
+}
+
+func TestMetricsSnapshotUsesTheClock(t *testing.T) {
+	a := newTestAppState(t, newTestConfig(t, ""), newFakeGPU())
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	a.clock = func() time.Time { return now }
+
+	if got := a.metricsSnapshot(GPUState{}).Timestamp; !got.Equal(now) {
+		t.Errorf("snapshot timestamp = %v, want the clock's %v", got, now)
+	}
+}
+
+func TestTickLeavesUncontrollableFansToTheDriver(t *testing.T) {
+	cfg := newTestConfig(t, "")
+	device := newFakeGPU()
+	device.temperature = 85
+	device.fanLimits = gpu.FanSpeedLimits{Min: 100, Max: 100, Default: 100}
+
+	a := newTestAppState(t, cfg, device)
+	for i := 0; i < 2; i++ {
+		if err := a.tick(context.Background()); err != nil {
+			t.Fatalf("tick() unexpected error: %v", err)
+		}
+	}
+
+	if len(device.fanWrites) != 0 {
+		t.Errorf("tick() set the fan speed to %v without a usable range", device.fanWrites)
+	}
+	if !a.autoFanControl {
+		t.Error("fans without a usable range aren't left to the driver")
+	}
+	if a.lastState.Reason.FanAction != reasonFanControlUnavailable {
+		t.Errorf("fan action = %q, want %q", a.lastState.Reason.FanAction, reasonFanControlUnavailable)
+	}
+	if !a.fanControlSkipped {
+		t.Error("skipping fan adjustments wasn't recorded for the log")
+	}
+
+	device.fanLimits = gpu.FanSpeedLimits{Min: 30, Max: 100, Default: 40}
+	if err := a.tick(context.Background()); err != nil {
+		t.Fatalf("tick() unexpected error: %v", err)
+	}
+	if a.fanControlSkipped {
+		t.Error("fan adjustments still recorded as skipped once the range is back")
+	}
+	if len(device.fanWrites) == 0 {
+		t.Error("fan adjustments didn't resume once the range is back")
+	}
+}
+
+func TestMonitorModeNeverWrites(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		readOnly bool
+	}{
+		{name: "monitor", config: "monitor = true\n"},
+		{name: "monitor read-only", config: "monitor = true\nmonitor_read_only = true\n", readOnly: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			device := newFakeGPU()
+			device.readOnly = tt.readOnly
+			a := newTestAppState(t, newTestConfig(t, tt.config), device)
+
+			// Hot enough that a controlling daemon would change both
+			for _, temperature := range []gpu.Temperature{85, 90, 95, 60} {
+				device.temperature = temperature
+				if err := a.tick(context.Background()); err != nil {
+					t.Fatalf("tick() at %d°C unexpected error: %v", temperature, err)
+				}
+			}
+
+			if len(device.fanWrites) != 0 {
+				t.Errorf("monitor mode set the fan speed to %v", device.fanWrites)
+			}
+			if len(device.powerWrites) != 0 {
+				t.Errorf("monitor mode set the power limit to %v", device.powerWrites)
+			}
+		})
+	}
+}
+
+func TestInitialFanSpeed(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     string
+		fanLimits  gpu.FanSpeedLimits
+		wantWrites []gpu.FanSpeed
+		wantErr    bool
+	}{
+		{name: "unset", config: ""},
+		{name: "set", config: "initial_fan_speed = 60\n", wantWrites: []gpu.FanSpeed{60}},
+		{name: "monitor mode", config: "monitor = true\ninitial_fan_speed = 60\n"},
+		{
+			name:      "fan control unavailable",
+			config:    "initial_fan_speed = 60\n",
+			fanLimits: gpu.FanSpeedLimits{Min: 100, Max: 100, Default: 100},
+		},
+		{name: "below the hardware minimum", config: "initial_fan_speed = 20\n", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, tt.config)
+			device := newFakeGPU()
+			if tt.fanLimits != (gpu.FanSpeedLimits{}) {
+				device.fanLimits = tt.fanLimits
+			}
+
+			_, err := initFanControl(cfg, device)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("initFanControl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(device.fanWrites) != len(tt.wantWrites) {
+				t.Fatalf("initFanControl() set the fan speed to %v, want %v", device.fanWrites, tt.wantWrites)
+			}
+			for i := range tt.wantWrites {
+				if device.fanWrites[i] != tt.wantWrites[i] {
+					t.Errorf("initFanControl() set the fan speed to %v, want %v", device.fanWrites, tt.wantWrites)
+				}
+			}
+		})
+	}
+}
+
+func TestInitialFanSpeedOnlyAtStartup(t *testing.T) {
+	cfg := newTestConfig(t, "initial_fan_speed = 60\n")
+	device := newFakeGPU()
+	if _, err := initFanControl(cfg, device); err != nil {
+		t.Fatalf("initFanControl() unexpected error: %v", err)
+	}
+
+	// The ticks take over from the initial speed, without setting it again
+	device.temperature = 40
+	a := newTestAppState(t, cfg, device)
+	for i := 0; i < 3; i++ {
+		if err := a.tick(context.Background()); err != nil {
+			t.Fatalf("tick() unexpected error: %v", err)
+		}
+	}
+
+	initial := 0
+	for _, speed := range device.fanWrites {
+		if speed == 60 {
+			initial++
+		}
+	}
+	if initial != 1 || device.fanWrites[0] != 60 {
+		t.Errorf("fan speed writes %v, want the initial 60%% first and only once", device.fanWrites)
+	}
+}
+
+func TestQuantize(t *testing.T) {
+	tests := []struct {
+		value, step, want int
+	}{
+		{value: 47, step: 0, want: 47},
+		{value: 47, step: 1, want: 47},
+		{value: 47, step: 5, want: 45},
+		{value: 48, step: 5, want: 50},
+		// Halves round away from zero
+		{value: 45, step: 10, want: 50},
+		{value: 44, step: 10, want: 40},
+		{value: 100, step: 5, want: 100},
+		{value: 0, step: 5, want: 0},
+		{value: -7, step: 5, want: -5},
+		{value: -8, step: 5, want: -10},
+	}
+
+	for _, tt := range tests {
+		if got := quantize(tt.value, tt.step); got != tt.want {
+			t.Errorf("quantize(%d, %d) = %d, want %d", tt.value, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestCalculatePowerLimitThresholds(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		temperature int
+		fanSpeed    int
+		powerLimit  int
+		want        int
+	}{
+		{name: "on target", temperature: 80, fanSpeed: 100, powerLimit: 250, want: 250},
+		{name: "over target", temperature: 81, fanSpeed: 100, powerLimit: 250, want: 245},
+		{name: "over target, capped", temperature: 90, fanSpeed: 100, powerLimit: 250, want: 240},
+		{name: "over target, fans not saturated", temperature: 90, fanSpeed: 80, powerLimit: 250, want: 250},
+		{name: "under target", temperature: 79, fanSpeed: 50, powerLimit: 250, want: 255},
+		{name: "under target, capped", temperature: 60, fanSpeed: 50, powerLimit: 250, want: 260},
+		{name: "raised to the hardware maximum", temperature: 60, fanSpeed: 50, powerLimit: 295, want: 300},
+		{name: "lowered to the hardware minimum", temperature: 95, fanSpeed: 100, powerLimit: 105, want: 100},
+		{
+			name:   "within the lower threshold",
+			config: "power_lower_threshold = 3\n", temperature: 83, fanSpeed: 100, powerLimit: 250, want: 250,
+		},
+		{
+			name:   "past the lower threshold",
+			config: "power_lower_threshold = 3\n", temperature: 84, fanSpeed: 100, powerLimit: 250, want: 240,
+		},
+		{
+			name:   "within the raise threshold",
+			config: "power_raise_threshold = 5\n", temperature: 75, fanSpeed: 50, powerLimit: 250, want: 250,
+		},
+		{
+			name:   "past the raise threshold",
+			config: "power_raise_threshold = 5\n", temperature: 74, fanSpeed: 50, powerLimit: 250, want: 260,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := newTestConfig(t, "temperature = 80\n"+tt.config)
+			a := newTestAppState(t, cfg, newFakeGPU())
+
+			got := a.calculatePowerLimit(tt.temperature, cfg.GetTemperature(), tt.fanSpeed, 100, tt.powerLimit)
+			if got != tt.want {
+				t.Errorf("calculatePowerLimit() at %d°C with fans at %d%% from %dW = %dW, want %dW",
+					tt.temperature, tt.fanSpeed, tt.powerLimit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStepPowerLimit(t *testing.T) {
+	tests := []struct {
+		name                      string
+		powerLimit, current, step int
+		want                      int
+	}{
+		{name: "no step", powerLimit: 247, current: 250, step: 0, want: 247},
+		{name: "single watt step", powerLimit: 247, current: 250, step: 1, want: 247},
+		{name: "unchanged", powerLimit: 250, current: 250, step: 5, want: 250},
+		{name: "rounded down", powerLimit: 237, current: 250, step: 5, want: 235},
+		{name: "rounded up", powerLimit: 262, current: 250, step: 5, want: 260},
+		{name: "at least one step down", powerLimit: 248, current: 250, step: 5, want: 245},
+		{name: "at least one step up", powerLimit: 252, current: 250, step: 5, want: 255},
+		{name: "kept below the hardware maximum", powerLimit: 298, current: 295, step: 10, want: 300},
+		{name: "kept above the hardware minimum", powerLimit: 103, current: 105, step: 10, want: 100},
+	}
+
+	a := newTestAppState(t, newTestConfig(t, ""), newFakeGPU())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := a.stepPowerLimit(tt.powerLimit, tt.current, tt.step); got != tt.want {
+				t.Errorf("stepPowerLimit(%d, %d, %d) = %d, want %d", tt.powerLimit, tt.current, tt.step, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// selectProfile picks the time-of-day profile for the tick. It's re-read
+// every tick so a reload changing a profile takes effect at once.
+func (a *AppState) selectProfile() {
+	profile, active := a.cfg.ActiveProfile(a.clock())
+
+	switch {
+	case active && (!a.profileActive || profile.Name != a.profile.Name):
+		logger.Info().
+			Str("profile", profile.Name).
+			Int("fanspeed", profile.FanSpeed).
+			Int("temperature", profile.Temperature).
+			Msg("Profile active")
+	case !active && a.profileActive:
+		logger.Info().
+			Str("profile", a.profile.Name).
+			Msg("Profile ended, using the base settings")
+	}
+
+	a.profile = profile
+	a.profileActive = active
+}
+
+// targetTemperature returns the target temperature of the active profile,
+// falling back to temperature
+func (a *AppState) targetTemperature() int {
+	if a.profileActive && a.profile.Temperature > 0 {
+		return a.profile.Temperature
+	}
+	return a.cfg.GetTemperature()
+}
+
+// maxFanSpeed returns the maximum fan speed of the active profile, falling
+// back to fanspeed
+func (a *AppState) maxFanSpeed() int {
+	if a.profileActive && a.profile.FanSpeed > 0 {
+		return a.profile.FanSpeed
+	}
+	return a.cfg.GetFanSpeed()
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+)
+
+// Reasons recorded for a fan speed or power limit decision
+const (
+	reasonBelowMinTemperature   = "below_min_temperature"
+	reasonAtTargetTemperature   = "at_target_temperature"
+	reasonCurve                 = "curve"
+	reasonFanStep               = "fan_step"
+	reasonFanOverrun            = "fan_overrun"
+	reasonFanStop               = "fan_stop"
+	reasonPowerCurve            = "power_curve"
+	reasonPreThrottle           = "pre_throttle"
+	reasonOverTarget            = "over_target"
+	reasonFansNotSaturated      = "fans_not_saturated"
+	reasonUnderTarget           = "under_target"
+	reasonOnTarget              = "on_target"
+	reasonHeldByPolicy          = "held_by_policy"
+	reasonNotLoweredByPolicy    = "not_lowered_by_policy"
+	reasonPerformanceMode       = "performance_mode"
+	reasonAutoFanControl        = "auto_fan_control"
+	reasonFanControlUnavailable = "fan_control_unavailable"
+	reasonHysteresis            = "hysteresis"
+	reasonConfirmingOverTarget  = "confirming_over_target"
+	reasonPowerUnreliable       = "power_control_unreliable"
+	reasonEnforcedByCard        = "enforced_by_card"
+	reasonApplied               = "applied"
+	reasonFastPath              = "fast_path"
+	reasonUnchanged             = "unchanged"
+)
+
+// decisionReason explains why the targets of a tick were chosen. It is only
+// logged in debug mode.
+type decisionReason struct {
+	TemperatureSource string  `json:"temperature_source"`
+	TemperatureOffset int     `json:"temperature_offset,omitempty"`
+	Fan               string  `json:"fan"`
+	CurvePosition     float64 `json:"curve_position,omitempty"`
+	FanAction         string  `json:"fan_action,omitempty"`
+	Power             string  `json:"power"`
+	PowerAction       string  `json:"power_action,omitempty"`
+	CoolWith          string  `json:"cool_with"`
+	Profile           string  `json:"profile,omitempty"`
+}
+
+// temperatureSource describes the sensors feeding the control temperature
+func temperatureSource(cfg config.Provider) string {
+	blend := cfg.GetTemperatureBlend()
+	if len(blend) == 0 {
+		core := string(config.SensorCore)
+		if index := cfg.GetTemperatureSensorIndex(); index >= 0 {
+			core = fmt.Sprintf("sensor(%d)", index)
+		}
+
+		switch cfg.GetTemperatureSource() {
+		case config.TemperatureSourceMemory:
+			return string(config.SensorMemory)
+		case config.TemperatureSourceMax:
+			return "max(" + core + "," + string(config.SensorMemory) + ")"
+		default:
+			return core
+		}
+	}
+
+	parts := make([]string, 0, len(blend))
+	for sensor, weight := range blend {
+		parts = append(parts, fmt.Sprintf("%s=%.2f", sensor, weight))
+	}
+	sort.Strings(parts)
+
+	return "blend(" + strings.Join(parts, ",") + ")"
+}
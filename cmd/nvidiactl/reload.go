@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/control"
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// restartOnlySettings are only read during startup. Changing them in a
+// reloaded config has no effect until the daemon is restarted.
+var restartOnlySettings = []struct {
+	key     string
+	changed func(old, updated config.Provider) bool
+}{
+	{"monitor", func(o, u config.Provider) bool { return o.IsMonitorMode() != u.IsMonitorMode() }},
+	{"monitor_read_only", func(o, u config.Provider) bool { return o.IsMonitorReadOnly() != u.IsMonitorReadOnly() }},
+	{"metrics", func(o, u config.Provider) bool { return o.IsMetricsEnabled() != u.IsMetricsEnabled() }},
+	{"database", func(o, u config.Provider) bool { return o.GetMetricsDBPath() != u.GetMetricsDBPath() }},
+	{"metrics_synchronous", func(o, u config.Provider) bool { return o.GetMetricsSynchronous() != u.GetMetricsSynchronous() }},
+	{"metrics_journal_mode", func(o, u config.Provider) bool { return o.GetMetricsJournalMode() != u.GetMetricsJournalMode() }},
+	{"metrics_backends", func(o, u config.Provider) bool {
+		return !slices.Equal(o.GetMetricsBackends(), u.GetMetricsBackends())
+	}},
+	{"metrics_textfile", func(o, u config.Provider) bool { return o.GetMetricsTextfile() != u.GetMetricsTextfile() }},
+	{"metrics_influx_socket", func(o, u config.Provider) bool {
+		return o.GetMetricsInfluxSocket() != u.GetMetricsInfluxSocket()
+	}},
+	{"metrics_max_size", func(o, u config.Provider) bool { return o.GetMetricsMaxSize() != u.GetMetricsMaxSize() }},
+	{"metrics_destructive_migrations", func(o, u config.Provider) bool {
+		return o.IsMetricsDestructiveMigrationEnabled() != u.IsMetricsDestructiveMigrationEnabled()
+	}},
+	{"metrics_retention_days", func(o, u config.Provider) bool {
+		return o.GetMetricsRetentionDays() != u.GetMetricsRetentionDays()
+	}},
+	{"metrics_nvml_debug", func(o, u config.Provider) bool { return o.IsNVMLDebugEnabled() != u.IsNVMLDebugEnabled() }},
+	{"temperature_statistic", func(o, u config.Provider) bool { return o.GetTemperatureStatistic() != u.GetTemperatureStatistic() }},
+	{"temperature_smoothing", func(o, u config.Provider) bool { return o.GetTemperatureSmoothing() != u.GetTemperatureSmoothing() }},
+	{"ema_alpha", func(o, u config.Provider) bool { return o.GetEMAAlpha() != u.GetEMAAlpha() }},
+	{"temperature_filter", func(o, u config.Provider) bool { return o.GetTemperatureFilter() != u.GetTemperatureFilter() }},
+	{"temperature_filter_delta", func(o, u config.Provider) bool {
+		return o.GetTemperatureFilterDelta() != u.GetTemperatureFilterDelta()
+	}},
+	{"temperature_sensor_index", func(o, u config.Provider) bool {
+		return o.GetTemperatureSensorIndex() != u.GetTemperatureSensorIndex()
+	}},
+	{"utilization_window", func(o, u config.Provider) bool { return o.GetUtilizationWindow() != u.GetUtilizationWindow() }},
+	{"temperature_window", func(o, u config.Provider) bool { return o.GetTemperatureWindow() != u.GetTemperatureWindow() }},
+	{"power_window", func(o, u config.Provider) bool { return o.GetPowerWindow() != u.GetPowerWindow() }},
+	{"warmup_reads", func(o, u config.Provider) bool { return o.GetWarmupReads() != u.GetWarmupReads() }},
+	{"init_retries", func(o, u config.Provider) bool { return o.GetInitRetries() != u.GetInitRetries() }},
+	{"init_backoff", func(o, u config.Provider) bool { return o.GetInitBackoff() != u.GetInitBackoff() }},
+	{"nvml_read_retries", func(o, u config.Provider) bool { return o.GetNVMLReadRetries() != u.GetNVMLReadRetries() }},
+	{"nvml_read_retry_delay", func(o, u config.Provider) bool {
+		return o.GetNVMLReadRetryDelay() != u.GetNVMLReadRetryDelay()
+	}},
+	{"fan_stop", func(o, u config.Provider) bool { return o.IsFanStopEnabled() != u.IsFanStopEnabled() }},
+	{"initial_fan_control", func(o, u config.Provider) bool { return o.GetInitialFanControl() != u.GetInitialFanControl() }},
+	{"device", func(o, u config.Provider) bool { return o.GetDeviceSelector() != u.GetDeviceSelector() }},
+	{"initial_fan_speed", func(o, u config.Provider) bool { return o.GetInitialFanSpeed() != u.GetInitialFanSpeed() }},
+	{"status_socket", func(o, u config.Provider) bool { return o.GetStatusSocket() != u.GetStatusSocket() }},
+	{"decision_history", func(o, u config.Provider) bool { return o.GetDecisionHistory() != u.GetDecisionHistory() }},
+	{"lock_file", func(o, u config.Provider) bool { return o.GetLockFile() != u.GetLockFile() }},
+	{"run_as_user", func(o, u config.Provider) bool { return o.GetRunAsUser() != u.GetRunAsUser() }},
+	{"niceness", func(o, u config.Provider) bool { return o.GetNiceness() != u.GetNiceness() }},
+	{"watch_config", func(o, u config.Provider) bool { return o.IsConfigWatchEnabled() != u.IsConfigWatchEnabled() }},
+	{"split_control", func(o, u config.Provider) bool { return o.IsSplitControl() != u.IsSplitControl() }},
+	{"power_interval", func(o, u config.Provider) bool { return o.GetPowerIntervalDuration() != u.GetPowerIntervalDuration() }},
+}
+
+// watchConfig runs the config watcher, handing each validated configuration
+// to the control loop
+func (a *AppState) watchConfig(ctx context.Context) {
+	errFactory := errors.New()
+
+	err := a.watcher.Watch(ctx, a.queueReload)
+	if err != nil {
+		var domainErr errors.Error
+		if !errors.As(err, &domainErr) {
+			domainErr = errFactory.Wrap(errors.ErrReloadConfig, err)
+		}
+		logger.ErrorWithCode(domainErr).Msg("Config watcher stopped")
+	}
+}
+
+// reloadOnSignal reloads the configuration on SIGHUP, as with the config
+// watcher. A configuration that fails to validate is logged and the running
+// one is kept.
+func (a *AppState) reloadOnSignal(ctx context.Context) {
+	errFactory := errors.New()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+		}
+
+		logger.Info().Msg("Received SIGHUP, reloading configuration")
+		cfg, err := a.loader.Reload(ctx)
+		if err != nil {
+			logger.ErrorWithCode(reloadError(errFactory.Wrap(errors.ErrReloadConfig, err))).
+				Msg("Keeping the running configuration")
+			continue
+		}
+		a.queueReload(cfg)
+	}
+}
+
+// queueReload hands a validated configuration to the control loop. Only the
+// newest pending configuration is kept, whether it came from the watcher or
+// SIGHUP.
+func (a *AppState) queueReload(cfg config.Provider) {
+	select {
+	case <-a.reloads:
+	default:
+	}
+	a.reloads <- cfg
+}
+
+// reloadError returns the validation error behind a failed reload, so it is
+// logged as invalid configuration rather than a generic reload failure
+func reloadError(err errors.Error) errors.Error {
+	for cause := error(err); cause != nil; cause = errors.Unwrap(cause) {
+		var domainErr errors.Error
+		if errors.As(cause, &domainErr) && domainErr.Code() == errors.ErrInvalidConfig {
+			return domainErr
+		}
+	}
+	return err
+}
+
+// applyConfig switches the control loop to a reloaded configuration. The
+// configuration has already been validated, so the only failures left are an
+// unknown cooling priority or fan strategy, in which case the current
+// configuration is kept.
+func (a *AppState) applyConfig(cfg config.Provider, ticker *time.Ticker) {
+	errFactory := errors.New()
+
+	policy, err := control.NewPolicy(control.Priority(cfg.GetCoolingPriority()))
+	if err != nil {
+		logger.ErrorWithCode(errFactory.Wrap(errors.ErrReloadConfig, err)).Send()
+		return
+	}
+
+	fanStrategy, err := newFanStrategy(cfg)
+	if err != nil {
+		logger.ErrorWithCode(errFactory.Wrap(errors.ErrReloadConfig, err)).Send()
+		return
+	}
+
+	for _, setting := range restartOnlySettings {
+		if setting.changed(a.cfg, cfg) {
+			logger.Warn().Str("setting", setting.key).Msg("Setting changed, restart nvidiactl to apply it")
+		}
+	}
+
+	if interval := cfg.GetIntervalDuration(); interval != a.cfg.GetIntervalDuration() {
+		ticker.Reset(interval)
+	}
+
+	if cfg.GetLogLevel() != a.cfg.GetLogLevel() || cfg.GetLogFormat() != a.cfg.GetLogFormat() {
+		logger.Init(cfg.GetLogLevel(), string(cfg.GetLogFormat()), logger.IsService())
+	}
+
+	a.cfg = cfg
+	a.policy = policy
+	a.fanStrategy = fanStrategy
+
+	logger.Info().
+		Str("log_level", cfg.GetLogLevel()).
+		Str("interval", cfg.GetIntervalDuration().String()).
+		Int("temperature", cfg.GetTemperature()).
+		Int("fanspeed", cfg.GetFanSpeed()).
+		Bool("performance_mode", cfg.IsPerformanceMode()).
+		Msg("Configuration reloaded")
+}
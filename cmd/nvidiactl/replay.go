@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/control"
+	"codeberg.org/mutker/nvidiactl/internal/gpu"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"codeberg.org/mutker/nvidiactl/internal/metrics"
+	"github.com/spf13/pflag"
+)
+
+const defaultReplayFanMin = 30
+
+var replayColumns = []string{
+	"time", "temperature", "average",
+	"fan_recorded", "fan_replayed", "power_recorded", "power_replayed",
+	"fan_reason", "power_reason",
+}
+
+// replayDevice stands in for the GPU during a replay. Only the limits are
+// needed to calculate targets; any other call is a bug and panics.
+type replayDevice struct {
+	gpu.Controller
+	fanLimits   gpu.FanSpeedLimits
+	powerLimits gpu.PowerLimits
+}
+
+func (d *replayDevice) GetFanSpeedLimits() gpu.FanSpeedLimits { return d.fanLimits }
+func (d *replayDevice) GetPowerLimits() gpu.PowerLimits       { return d.powerLimits }
+
+// GetTemperatureThresholds reports no thresholds, they aren't recorded
+func (*replayDevice) GetTemperatureThresholds() gpu.TemperatureThresholds {
+	return gpu.TemperatureThresholds{}
+}
+
+// runReplay runs the control algorithm with the current configuration over
+// the samples of a metrics database and prints the targets it would choose
+// next to the recorded ones. Each sample is evaluated against its recorded
+// state, so the replayed targets don't feed back into later samples. No NVML
+// calls are made. It returns the process exit code.
+func runReplay(args []string) int {
+	flags := pflag.NewFlagSet("replay", pflag.ContinueOnError)
+	dbPath := flags.String("db", "/var/lib/nvidiactl/metrics.db", "metrics database to replay")
+	configPath := flags.String("config", "", "config file to evaluate (default: /etc/nvidiactl.conf)")
+	format := flags.String("format", "table", "output format (table, csv)")
+	since := flags.Duration("since", 0, "only replay samples from the last duration, e.g. 24h (0 for all)")
+	fanMin := flags.Int("fan-min", defaultReplayFanMin, "hardware minimum fan speed of the recorded card in percent")
+	fanMax := flags.Int("fan-max", 100, "hardware maximum fan speed of the recorded card in percent")
+	powerMin := flags.Int("power-min", 0, "minimum power limit of the recorded card in watts (0 for the lowest recorded)")
+	powerMax := flags.Int("power-max", 0, "maximum power limit of the recorded card in watts (0 for the highest recorded)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *format != "table" && *format != "csv" {
+		fmt.Fprintf(os.Stderr, "nvidiactl replay: unknown format %q\n", *format)
+		return 2
+	}
+
+	logger.Init(string(config.LogLevelError), logger.FormatConsole, false)
+
+	cfg, err := config.NewLoader().Load(context.Background(),
+		config.WithConfigFile(*configPath), config.WithArgs(nil))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl replay: %v\n", err)
+		return 1
+	}
+
+	var from time.Time
+	if *since > 0 {
+		from = time.Now().Add(-*since)
+	}
+	history, err := metrics.ReadHistory(*dbPath, from, time.Time{})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl replay: %v\n", err)
+		return 1
+	}
+	if len(history) == 0 {
+		fmt.Fprintf(os.Stderr, "nvidiactl replay: no samples in %s\n", *dbPath)
+		return 1
+	}
+
+	policy, err := control.NewPolicy(control.Priority(cfg.GetCoolingPriority()))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl replay: %v\n", err)
+		return 1
+	}
+	fanStrategy, err := newFanStrategy(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl replay: %v\n", err)
+		return 1
+	}
+
+	a := &AppState{
+		cfg: cfg,
+		gpuDevice: &replayDevice{
+			fanLimits: gpu.FanSpeedLimits{
+				Min: gpu.FanSpeed(*fanMin),
+				Max: gpu.FanSpeed(*fanMax),
+			},
+			powerLimits: replayPowerLimits(history, *powerMin, *powerMax),
+		},
+		policy:      policy,
+		fanStrategy: fanStrategy,
+		clock:       time.Now,
+	}
+
+	if err := a.replay(os.Stdout, *format, history); err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl replay: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// replay writes one row per sample with the recorded and replayed targets
+func (a *AppState) replay(w io.Writer, format string, history []metrics.MetricsSnapshot) error {
+	rows := make([][]string, 0, len(history))
+	for _, sample := range history {
+		// Time based control state, like fan_overrun, follows the samples
+		a.clock = func() time.Time { return sample.Timestamp }
+
+		state := GPUState{
+			CurrentTemperature: sample.Temperature.Current,
+			AverageTemperature: sample.Temperature.Average,
+			CurrentFanSpeed:    sample.FanSpeed.Current,
+			CurrentPowerLimit:  sample.PowerLimit.Current,
+			AveragePowerLimit:  sample.PowerLimit.Average,
+		}
+		fanSpeed, powerLimit := a.calculateTargets(&state)
+
+		rows = append(rows, []string{
+			sample.Timestamp.Format(time.DateTime),
+			strconv.Itoa(sample.Temperature.Current),
+			strconv.Itoa(sample.Temperature.Average),
+			strconv.Itoa(sample.FanSpeed.Target),
+			strconv.Itoa(fanSpeed),
+			strconv.Itoa(sample.PowerLimit.Target),
+			strconv.Itoa(powerLimit),
+			state.Reason.Fan,
+			state.Reason.Power,
+		})
+	}
+
+	if format == "csv" {
+		writer := csv.NewWriter(w)
+		if err := writer.Write(replayColumns); err != nil {
+			return err
+		}
+		if err := writer.WriteAll(rows); err != nil {
+			return err
+		}
+		return writer.Error()
+	}
+
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range append([][]string{replayColumns}, rows...) {
+		for i, cell := range row {
+			if i > 0 {
+				fmt.Fprint(writer, "\t")
+			}
+			fmt.Fprint(writer, cell)
+		}
+		fmt.Fprintln(writer)
+	}
+	return writer.Flush()
+}
+
+// replayPowerLimits returns the power limits to replay with, the recorded
+// range where not given
+func replayPowerLimits(history []metrics.MetricsSnapshot, minLimit, maxLimit int) gpu.PowerLimits {
+	recordedMin, recordedMax := history[0].PowerLimit.Current, history[0].PowerLimit.Current
+	for _, sample := range history {
+		for _, limit := range []int{sample.PowerLimit.Current, sample.PowerLimit.Target} {
+			if limit <= 0 {
+				continue
+			}
+			recordedMin = min(recordedMin, limit)
+			recordedMax = max(recordedMax, limit)
+		}
+	}
+
+	if minLimit <= 0 {
+		minLimit = recordedMin
+	}
+	if maxLimit <= 0 {
+		maxLimit = recordedMax
+	}
+
+	return gpu.PowerLimits{
+		Min:     gpu.PowerLimit(minLimit),
+		Max:     gpu.PowerLimit(maxLimit),
+		Default: gpu.PowerLimit(maxLimit),
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/gpu"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"codeberg.org/mutker/nvidiactl/internal/metrics"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultReportLogLines = 100
+	reportLogTimeout      = 5 * time.Second
+)
+
+// reportBundle is everything a bug report needs in one document. A section
+// that can't be collected is left out and its error recorded instead.
+type reportBundle struct {
+	GeneratedAt time.Time              `json:"generated_at"`
+	Build       buildReport            `json:"build"`
+	ConfigFile  string                 `json:"config_file"`
+	Config      map[string]any         `json:"config,omitempty"`
+	System      *gpu.SystemInfo        `json:"system,omitempty"`
+	Metrics     *metrics.DatabaseStats `json:"metrics,omitempty"`
+	Logs        []string               `json:"logs,omitempty"`
+	Errors      map[string]string      `json:"errors,omitempty"`
+}
+
+type buildReport struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
+// runReport collects the effective config, the driver and hardware details,
+// metrics database stats and recent logs into a JSON bundle to attach to an
+// issue. Everything is read-only. It returns the process exit code.
+func runReport(args []string) int {
+	flags := pflag.NewFlagSet("report", pflag.ContinueOnError)
+	configPath := flags.String("config", "", "config file to report (default: /etc/nvidiactl.conf)")
+	logLines := flags.Int("log-lines", defaultReportLogLines, "number of recent journal lines to include (0 to skip)")
+	unit := flags.String("unit", "nvidiactl", "systemd unit to read the logs of")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger.Init(string(config.LogLevelError), logger.FormatConsole, false)
+
+	bundle := reportBundle{
+		GeneratedAt: time.Now(),
+		Build:       buildInfo(),
+		Errors:      make(map[string]string),
+	}
+
+	loader := config.NewLoader()
+	cfg, err := loader.Load(context.Background(), config.WithConfigFile(*configPath), config.WithArgs(nil))
+	if err != nil {
+		bundle.Errors["config"] = err.Error()
+	} else {
+		bundle.ConfigFile = loader.ConfigFile()
+		bundle.Config = cfg.Settings()
+	}
+
+	var selector string
+	if cfg != nil {
+		selector = cfg.GetDeviceSelector()
+	}
+	if info, err := gpu.ProbeSystem(selector); err != nil {
+		bundle.Errors["system"] = err.Error()
+	} else {
+		bundle.System = &info
+	}
+
+	if cfg != nil && cfg.IsMetricsEnabled() {
+		if stats, err := metrics.ReadStats(cfg.GetMetricsDBPath()); err != nil {
+			bundle.Errors["metrics"] = err.Error()
+		} else {
+			bundle.Metrics = &stats
+		}
+	}
+
+	if *logLines > 0 {
+		if lines, err := journalLines(*unit, *logLines); err != nil {
+			bundle.Errors["logs"] = err.Error()
+		} else {
+			bundle.Logs = lines
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(bundle); err != nil {
+		fmt.Fprintf(os.Stderr, "nvidiactl report: %v\n", err)
+		return 1
+	}
+
+	return 0
+}
+
+// buildInfo describes the running binary
+func buildInfo() buildReport {
+	return buildReport{
+		Version:   appVersion(),
+		Commit:    appCommit(),
+		Date:      appDate(),
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+}
+
+// journalLines returns the most recent journal lines of a systemd unit
+func journalLines(unit string, lines int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), reportLogTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	//nolint:gosec // G204: the unit is passed as a single argument, not through a shell
+	cmd := exec.CommandContext(ctx, "journalctl",
+		"--unit", unit, "--lines", strconv.Itoa(lines), "--no-pager", "--output", "short-iso")
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, err
+	}
+
+	return strings.Split(strings.TrimRight(string(out), "\n"), "\n"), nil
+}
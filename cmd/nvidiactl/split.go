@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// controlScope selects the actuators a tick controls
+type controlScope int
+
+const (
+	// scopeAll controls fans and power in the same tick, the default
+	scopeAll controlScope = iota
+	// scopeFans controls only the fans, power runs in its own loop
+	scopeFans
+	// scopePower controls only the power limit
+	scopePower
+)
+
+// withLock runs fn holding the state lock, which serializes the fan and
+// power loops
+func (a *AppState) withLock(fn func() error) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return fn()
+}
+
+// startPowerLoop runs power control on its own ticker until ctx is canceled.
+// The returned channel receives the error that ended the loop, nil on
+// cancellation, and is closed afterwards.
+func (a *AppState) startPowerLoop(ctx context.Context) <-chan error {
+	done := make(chan error, 1)
+
+	interval := a.cfg.GetPowerIntervalDuration()
+	logger.Debug().Msgf("Starting power loop with %v interval", interval)
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Debug().Msg("Context canceled, exiting power loop")
+				done <- nil
+				return
+			case <-ticker.C:
+				if err := a.withLock(a.powerTick); err != nil {
+					done <- err
+					return
+				}
+			}
+		}
+	}()
+
+	return done
+}
+
+// powerTick adjusts the power limit from the state read by the latest fan
+// tick, so the temperature history only advances at the fan cadence
+func (a *AppState) powerTick() error {
+	if a.failsafe || a.lastState == nil {
+		return nil
+	}
+
+	state := *a.lastState
+	state.CurrentPowerLimit = int(a.gpuDevice.GetCurrentPowerLimit())
+
+	state, err := a.setGPUState(&state, scopePower)
+	if err != nil {
+		logger.Debug().Err(err).Msg("Failed to set power limit")
+		return a.failTick(err, "power_apply_failed")
+	}
+	a.powerTarget = state.TargetPowerLimit
+
+	logger.Debug().
+		Int("current_power_limit", state.CurrentPowerLimit).
+		Int("target_power_limit", state.TargetPowerLimit).
+		Interface("reason", state.Reason).
+		Msg("Power loop tick")
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPowerTickLeavesFanStateAlone(t *testing.T) {
+	cfg := newTestConfig(t, `split_control = true
+fan_stop = true
+fan_stop_temperature = 45
+fan_overrun = "1m"
+fast_path_threshold = 5
+temperature = 70
+`)
+	device := newFakeGPU()
+	a := newTestAppState(t, cfg, device)
+
+	// The last fan tick saw the card well above the target with the fans
+	// saturated
+	last := GPUState{
+		CurrentTemperature: 90,
+		AverageTemperature: 90,
+		CurrentFanSpeed:    100,
+		TargetFanSpeed:     100,
+		CurrentPowerLimit:  250,
+		TargetPowerLimit:   250,
+	}
+	a.lastState = &last
+	a.fanStopped = true
+
+	if err := a.powerTick(); err != nil {
+		t.Fatalf("powerTick() unexpected error: %v", err)
+	}
+
+	if !a.fanStopped {
+		t.Error("powerTick() changed the fan stop state")
+	}
+	if !a.lastHigh.IsZero() || a.overrunFanSpeed != 0 {
+		t.Errorf("powerTick() updated the fan overrun, last high %v at %d%%", a.lastHigh, a.overrunFanSpeed)
+	}
+	if a.preThrottle {
+		t.Error("powerTick() changed the pre-throttle state")
+	}
+	if len(device.fanWrites) != 0 {
+		t.Errorf("powerTick() set the fan speed to %v", device.fanWrites)
+	}
+	if a.lastState.TargetFanSpeed != 100 {
+		t.Errorf("powerTick() changed the last fan target to %d", a.lastState.TargetFanSpeed)
+	}
+	if a.powerTarget >= 250 {
+		t.Errorf("power target at 90°C with a 70°C target = %d, want it lowered from 250", a.powerTarget)
+	}
+}
+
+func TestPowerLoopStopsOnCancel(t *testing.T) {
+	cfg := newTestConfig(t, "split_control = true\npower_interval = \"1s\"\n")
+	a := newTestAppState(t, cfg, newFakeGPU())
+	state, err := a.getGPUState()
+	if err != nil {
+		t.Fatalf("getGPUState() unexpected error: %v", err)
+	}
+	a.lastState = &state
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := a.startPowerLoop(ctx)
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("power loop ended with %v, want nil on cancellation", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("power loop didn't stop after cancellation")
+	}
+	if _, ok := <-done; ok {
+		t.Error("power loop didn't close its channel")
+	}
+}
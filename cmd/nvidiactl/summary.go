@@ -0,0 +1,94 @@
+package main
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/config"
+	"codeberg.org/mutker/nvidiactl/internal/gpu"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+type (
+	// hardwareSummary is what the card allows
+	hardwareSummary struct {
+		FanSpeedMin           int              `json:"fan_speed_min"`
+		FanSpeedMax           int              `json:"fan_speed_max"`
+		PowerLimitMin         int              `json:"power_limit_min"`
+		PowerLimitMax         int              `json:"power_limit_max"`
+		PowerLimitDefault     int              `json:"power_limit_default"`
+		TemperatureSlowdown   int              `json:"temperature_slowdown"`
+		TemperatureShutdown   int              `json:"temperature_shutdown"`
+		TemperatureGPUMax     int              `json:"temperature_gpu_max"`
+		InitialAutoFanControl bool             `json:"initial_auto_fan_control"`
+		Capabilities          gpu.Capabilities `json:"capabilities"`
+	}
+
+	// configSummary is what the user asked for
+	configSummary struct {
+		Interval             string              `json:"interval"`
+		Temperature          int                 `json:"temperature"`
+		MinTemperature       int                 `json:"min_temperature"`
+		TemperatureOffset    int                 `json:"temperature_offset"`
+		TemperatureStatistic string              `json:"temperature_statistic"`
+		TemperatureSmoothing string              `json:"temperature_smoothing"`
+		TemperatureSource    string              `json:"temperature_source"`
+		GPUCoordination      string              `json:"gpu_coordination"`
+		FanSpeed             int                 `json:"fanspeed"`
+		FanStep              int                 `json:"fan_step"`
+		FanFloor             int                 `json:"fan_floor"`
+		FanStrategy          string              `json:"fan_strategy"`
+		FanSteps             []config.CurvePoint `json:"fan_steps,omitempty"`
+		FanCurve             []config.CurvePoint `json:"fan_curve,omitempty"`
+		PowerCurve           []config.CurvePoint `json:"power_curve,omitempty"`
+		PowerMinStep         int                 `json:"power_min_step"`
+		Hysteresis           int                 `json:"hysteresis"`
+		CoolWith             string              `json:"cool_with"`
+		Performance          bool                `json:"performance"`
+		PerformanceFanCap    int                 `json:"performance_fan_cap"`
+		Monitor              bool                `json:"monitor"`
+	}
+)
+
+// logStartupSummary logs the hardware limits next to the configured targets
+// in a single line, the first thing to look at in a bug report
+func (a *AppState) logStartupSummary() {
+	fanSpeedLimits := a.gpuDevice.GetFanSpeedLimits()
+	powerLimits := a.gpuDevice.GetPowerLimits()
+	thresholds := a.gpuDevice.GetTemperatureThresholds()
+
+	logger.Info().
+		Interface("hardware", hardwareSummary{
+			FanSpeedMin:           int(fanSpeedLimits.Min),
+			FanSpeedMax:           int(fanSpeedLimits.Max),
+			PowerLimitMin:         int(powerLimits.Min),
+			PowerLimitMax:         int(powerLimits.Max),
+			PowerLimitDefault:     int(powerLimits.Default),
+			TemperatureSlowdown:   int(thresholds.Slowdown),
+			TemperatureShutdown:   int(thresholds.Shutdown),
+			TemperatureGPUMax:     int(thresholds.GPUMax),
+			InitialAutoFanControl: a.autoFanControl,
+			Capabilities:          a.gpuDevice.GetCapabilities(),
+		}).
+		Interface("config", configSummary{
+			Interval:             a.cfg.GetIntervalDuration().String(),
+			Temperature:          a.cfg.GetTemperature(),
+			MinTemperature:       minTemperature,
+			TemperatureOffset:    a.cfg.GetTemperatureOffset(),
+			TemperatureStatistic: string(a.cfg.GetTemperatureStatistic()),
+			TemperatureSmoothing: string(a.cfg.GetTemperatureSmoothing()),
+			TemperatureSource:    temperatureSource(a.cfg),
+			GPUCoordination:      string(a.cfg.GetGPUCoordination()),
+			FanSpeed:             a.cfg.GetFanSpeed(),
+			FanStep:              a.cfg.GetFanStep(),
+			FanFloor:             a.cfg.GetFanFloor(),
+			FanStrategy:          a.cfg.GetFanStrategy(),
+			FanSteps:             a.cfg.GetFanSteps(),
+			FanCurve:             a.cfg.GetFanCurve(),
+			PowerCurve:           a.cfg.GetPowerCurve(),
+			PowerMinStep:         a.cfg.GetPowerMinStep(),
+			Hysteresis:           a.cfg.GetHysteresis(),
+			CoolWith:             string(a.cfg.GetCoolingPriority()),
+			Performance:          a.cfg.IsPerformanceMode(),
+			PerformanceFanCap:    a.cfg.GetPerformanceFanCap(),
+			Monitor:              a.cfg.IsMonitorMode(),
+		}).
+		Msg("Startup summary")
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/status"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultWatchInterval = 2 * time.Second
+	defaultWatchRetries  = 5
+)
+
+// runWatch renders the status of a running daemon on a single, continuously
+// updated console line. It returns the process exit code.
+func runWatch(args []string) int {
+	flags := pflag.NewFlagSet("watch", pflag.ContinueOnError)
+	socketPath := flags.String("status-socket", status.DefaultSocketPath, "path to the daemon status socket")
+	interval := flags.Duration("interval", defaultWatchInterval, "refresh interval")
+	retries := flags.Int("retries", defaultWatchRetries, "consecutive failed refreshes before giving up")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if *interval <= 0 {
+		fmt.Fprintln(os.Stderr, "nvidiactl watch: interval must be positive")
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	client := status.NewClient(*socketPath)
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		reqCtx, cancel := context.WithTimeout(ctx, *interval)
+		st, err := client.Status(reqCtx)
+		cancel()
+
+		switch {
+		case err == nil:
+			failures = 0
+			fmt.Fprintf(os.Stdout, "\r\033[K%s", formatWatchLine(st))
+		case ctx.Err() != nil:
+			fmt.Fprintln(os.Stdout)
+			return 0
+		default:
+			failures++
+			fmt.Fprintf(os.Stdout, "\r\033[Kwaiting for nvidiactl on %s (%d/%d)", *socketPath, failures, *retries)
+			if failures >= *retries {
+				fmt.Fprintln(os.Stdout)
+				fmt.Fprintf(os.Stderr, "nvidiactl watch: daemon not reachable: %v\n", err)
+				return 1
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stdout)
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+func formatWatchLine(st status.Status) string {
+	fanTarget := fmt.Sprintf("%d%%", st.FanSpeed.Target)
+	if st.State.AutoFanControl {
+		fanTarget = "auto"
+	}
+
+	mode := "normal"
+	switch {
+	case st.State.MonitorMode:
+		mode = "monitor"
+	case st.State.PerformanceMode:
+		mode = "performance"
+	}
+
+	// Daemons predating temperature_unit always report Celsius
+	unit := st.Temperature.Unit
+	if unit == "" {
+		unit = "C"
+	}
+
+	return fmt.Sprintf("%s  temp %d°%s (avg %d°%s)  fan %d%% -> %s  power %dW -> %dW (avg %dW)  mode %s  up %s (%d ticks)",
+		st.Timestamp.Format(time.TimeOnly),
+		st.Temperature.Current, unit, st.Temperature.Average, unit,
+		st.FanSpeed.Current, fanTarget,
+		st.PowerLimit.Current, st.PowerLimit.Target, st.PowerLimit.Average,
+		mode, time.Duration(st.Daemon.UptimeSeconds)*time.Second, st.Daemon.Ticks)
+}
@@ -2,7 +2,12 @@ package config
 
 import (
 	"context"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"codeberg.org/mutker/nvidiactl/internal/errors"
 	"codeberg.org/mutker/nvidiactl/internal/logger"
@@ -10,16 +15,122 @@ import (
 	"github.com/spf13/viper"
 )
 
-const DefaultLogLevel = LogLevelInfo
+const (
+	DefaultLogLevel = LogLevelInfo
+
+	// recommendedMinInterval is the shortest interval most drivers sustain
+	// without NVML calls piling up
+	recommendedMinInterval = time.Second
+
+	// absoluteMinInterval is the floor for min_interval itself
+	absoluteMinInterval = 100 * time.Millisecond
+
+	// minResumeGapIntervals is the smallest resume_gap in intervals
+	minResumeGapIntervals = 3
+
+	// maxPowerThreshold bounds power_lower_threshold and power_raise_threshold
+	maxPowerThreshold = 20
+
+	// maxPowerMinStep bounds power_min_step in watts
+	maxPowerMinStep = 50
+
+	// maxPowerReactionTicks bounds power_reaction_ticks, a longer delay
+	// leaves the card throttling on its own
+	maxPowerReactionTicks = 60
+
+	// maxPowerCurveWatts bounds power_curve values, the hardware limits are
+	// applied at runtime
+	maxPowerCurveWatts = 10000
+
+	// defaultNiceness keeps the daemon from competing with the workloads on
+	// the GPU it manages
+	defaultNiceness = 5
+
+	// defaultPIDProportional and defaultPIDIntegral give a gentle PID
+	// response: 2% per degree over target, plus 3% per degree-minute
+	defaultPIDProportional = 2.0
+	defaultPIDIntegral     = 0.05
+
+	// maxPerformanceTemperature bounds performance_max_temperature, above
+	// any card's shutdown threshold
+	maxPerformanceTemperature = 120
+
+	// maxTemperatureOffset bounds temperature_offset in either direction
+	maxTemperatureOffset = 30
+
+	// maxSampleWindow bounds utilization_window, temperature_window and
+	// power_window
+	maxSampleWindow = 60
+
+	// maxWarmupReads bounds warmup_reads, a few reads are enough for any
+	// driver
+	maxWarmupReads = 10
+
+	// maxInitRetries bounds init_retries, a longer wait is better handled
+	// by the degraded mode retrying every interval
+	maxInitRetries = 10
+
+	// maxReadRetries and maxReadRetryDelay bound nvml_read_retries and
+	// nvml_read_retry_delay, retries block the control loop
+	maxReadRetries    = 5
+	maxReadRetryDelay = time.Second
+
+	// maxTemperatureFilterDelta bounds temperature_filter_delta
+	maxTemperatureFilterDelta = 50
+
+	// maxFastPathThreshold bounds fast_path_threshold
+	maxFastPathThreshold = 50
+
+	// maxPreThrottleMargin bounds pre_throttle_margin
+	maxPreThrottleMargin = 30
+
+	// maxDecisionHistory bounds decision_history, an hour at one second
+	// intervals
+	maxDecisionHistory = 3600
+
+	// maxJitterTolerance bounds jitter_tolerance, later ticks are caught by
+	// resume_gap
+	maxJitterTolerance = 1000
+
+	// maxTemperatureSensorIndex is the last thermal sensor NVML reports
+	maxTemperatureSensorIndex = 2
+)
 
 // viperConfig implements Provider interface using viper
 type viperConfig struct {
 	v *viper.Viper
+
+	// Settings parsed once at load rather than on every tick
+	powerCurve []CurvePoint
+	fanSteps   []CurvePoint
+	fanCurve   []CurvePoint
+	profiles   []Profile
+}
+
+// newViperConfig returns the configuration of v, which must have been
+// validated
+func newViperConfig(v *viper.Viper) *viperConfig {
+	// Validated at load time
+	powerCurve, _ := parseCurvePoints(v, "power_curve", maxPowerCurveWatts)
+	fanSteps, _ := parseCurvePoints(v, "fan_steps", 100)
+	fanCurve, _ := parseCurvePoints(v, "fan_curve", 100)
+	profiles, _ := parseProfiles(v)
+
+	return &viperConfig{
+		v:          v,
+		powerCurve: powerCurve,
+		fanSteps:   fanSteps,
+		fanCurve:   fanCurve,
+		profiles:   profiles,
+	}
 }
 
 // defaultLoader implements Loader interface
 type defaultLoader struct {
-	v *viper.Viper
+	v          *viper.Viper
+	configPath string
+	envPrefix  string
+	mu         sync.Mutex
 }
 
 // NewLoader creates a new configuration loader
@@ -41,31 +152,187 @@ func (l *defaultLoader) Load(_ context.Context, opts ...Option) (Provider, error
 		}
 	}
 
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	setDefaults(l.v)
-	defineFlags(l.v)
+	if err := defineFlags(l.v, o.args); err != nil {
+		return nil, errFactory.Wrap(errors.ErrLoadConfig, err)
+	}
 
-	if err := bindFlags(l.v); err != nil {
-		return nil, err
+	if o.configPath == "" {
+		if f := pflag.Lookup("config"); f != nil {
+			o.configPath = f.Value.String()
+		}
 	}
+	l.configPath = o.configPath
+	l.envPrefix = o.envPrefix
 
-	if err := loadConfigFile(l.v, o.configPath); err != nil {
+	if err := l.load(); err != nil {
 		return nil, err
 	}
 
-	bindEnvVariables(l.v, o.envPrefix)
+	// Reloads read the same file, even if it was found in a search path
+	l.configPath = l.v.ConfigFileUsed()
 
-	if err := l.Validate(); err != nil {
-		return nil, err
+	return newViperConfig(l.v), nil
+}
+
+func (l *defaultLoader) Reload(_ context.Context) (Provider, error) {
+	errFactory := errors.New()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Parse into a throwaway loader so a broken file can't leave a half
+	// applied configuration behind. Flags are already parsed, only bound.
+	fresh := &defaultLoader{
+		v:          viper.New(),
+		configPath: l.configPath,
+		envPrefix:  l.envPrefix,
+	}
+	setDefaults(fresh.v)
+
+	if err := fresh.load(); err != nil {
+		return nil, errFactory.Wrap(errors.ErrReloadConfig, err)
 	}
 
-	return &viperConfig{v: l.v}, nil
+	l.v = fresh.v
+
+	return newViperConfig(fresh.v), nil
+}
+
+func (l *defaultLoader) ConfigFile() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.v.ConfigFileUsed()
+}
+
+// load reads flags, the config file and the environment into the loader's
+// viper instance and validates the result
+func (l *defaultLoader) load() error {
+	if err := bindFlags(l.v); err != nil {
+		return err
+	}
+
+	if err := loadConfigFile(l.v, l.configPath); err != nil {
+		return err
+	}
+
+	bindEnvVariables(l.v, l.envPrefix)
+
+	return l.Validate()
 }
 
 func (l *defaultLoader) Validate() error {
 	errFactory := errors.New()
 
-	if l.v.GetInt("interval") <= 0 {
-		return errFactory.WithData(errors.ErrInvalidInterval, l.v.GetInt("interval"))
+	interval, err := parseInterval(l.v, "interval")
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return errFactory.WithData(errors.ErrInvalidInterval, l.v.Get("interval"))
+	}
+
+	minInterval, err := parseInterval(l.v, "min_interval")
+	if err != nil {
+		return err
+	}
+	if minInterval < absoluteMinInterval {
+		return errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Field   string
+			Value   time.Duration
+			Minimum time.Duration
+		}{
+			Field:   "min_interval",
+			Value:   minInterval,
+			Minimum: absoluteMinInterval,
+		})
+	}
+	if interval < minInterval {
+		return errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Interval time.Duration
+			Minimum  time.Duration
+		}{
+			Interval: interval,
+			Minimum:  minInterval,
+		})
+	}
+	if interval < recommendedMinInterval {
+		logger.Warn().
+			Dur("interval", interval).
+			Dur("recommended_minimum", recommendedMinInterval).
+			Msg("Interval is below the recommended minimum, some drivers may not keep up")
+	}
+
+	resumeGap, err := parseInterval(l.v, "resume_gap")
+	if err != nil {
+		return err
+	}
+	if resumeGap < 0 {
+		return errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Field string
+			Value time.Duration
+		}{
+			Field: "resume_gap",
+			Value: resumeGap,
+		})
+	}
+
+	if tolerance := l.v.GetInt("jitter_tolerance"); tolerance < 0 || tolerance > maxJitterTolerance {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "jitter_tolerance",
+			Value:   tolerance,
+			Maximum: maxJitterTolerance,
+		})
+	}
+
+	fanOverrun, err := parseInterval(l.v, "fan_overrun")
+	if err != nil {
+		return err
+	}
+	if fanOverrun < 0 {
+		return errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Field string
+			Value time.Duration
+		}{
+			Field: "fan_overrun",
+			Value: fanOverrun,
+		})
+	}
+
+	powerInterval, err := parseInterval(l.v, "power_interval")
+	if err != nil {
+		return err
+	}
+	if powerInterval < minInterval {
+		return errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Field   string
+			Value   time.Duration
+			Minimum time.Duration
+		}{
+			Field:   "power_interval",
+			Value:   powerInterval,
+			Minimum: minInterval,
+		})
+	}
+
+	limitsRefresh, err := parseInterval(l.v, "limits_refresh_interval")
+	if err != nil {
+		return err
+	}
+	if limitsRefresh < 0 {
+		return errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Field string
+			Value time.Duration
+		}{
+			Field: "limits_refresh_interval",
+			Value: limitsRefresh,
+		})
 	}
 
 	logLevel := LogLevel(l.v.GetString("log_level"))
@@ -73,26 +340,793 @@ func (l *defaultLoader) Validate() error {
 		return errFactory.WithData(errors.ErrInvalidLogLevel, logLevel)
 	}
 
-	return nil
+	unit := TemperatureUnit(strings.ToUpper(l.v.GetString("temperature_unit")))
+	if !unit.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "temperature_unit",
+			Value: l.v.GetString("temperature_unit"),
+		})
+	}
+
+	// Below the start of the fan curve the curve would run backwards. Only
+	// converted targets are checked, a Fahrenheit value read as Celsius is
+	// the likely mistake; Celsius targets load as they always have.
+	if temperature := configuredTemperature(l.v); unit != TemperatureUnitCelsius && temperature <= MinTemperature {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Minimum int
+		}{
+			Field:   "temperature",
+			Value:   temperature,
+			Minimum: MinTemperature + 1,
+		})
+	}
+
+	logFormat := LogFormat(l.v.GetString("log_format"))
+	if !logFormat.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "log_format",
+			Value: string(logFormat),
+		})
+	}
+
+	fanReadFailure := FanReadFailureAction(l.v.GetString("fan_read_failure"))
+	if !fanReadFailure.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "fan_read_failure",
+			Value: string(fanReadFailure),
+		})
+	}
+
+	statistic := TemperatureStatistic(l.v.GetString("temperature_statistic"))
+	if !statistic.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "temperature_statistic",
+			Value: string(statistic),
+		})
+	}
+
+	smoothing := TemperatureSmoothing(l.v.GetString("temperature_smoothing"))
+	if !smoothing.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "temperature_smoothing",
+			Value: string(smoothing),
+		})
+	}
+
+	if alpha := l.v.GetFloat64("ema_alpha"); alpha <= 0 || alpha > 1 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value float64
+		}{
+			Field: "ema_alpha",
+			Value: alpha,
+		})
+	}
+
+	filter := TemperatureFilter(l.v.GetString("temperature_filter"))
+	if !filter.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "temperature_filter",
+			Value: string(filter),
+		})
+	}
+
+	if delta := celsiusDelta(l.v, "temperature_filter_delta"); delta < 1 || delta > maxTemperatureFilterDelta {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "temperature_filter_delta",
+			Value:   delta,
+			Maximum: maxTemperatureFilterDelta,
+		})
+	}
+
+	if offset := celsiusDelta(l.v, "temperature_offset"); offset < -maxTemperatureOffset || offset > maxTemperatureOffset {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "temperature_offset",
+			Value:   offset,
+			Maximum: maxTemperatureOffset,
+		})
+	}
+
+	if limit := performanceMaxTemperature(l.v); limit < 0 || limit > maxPerformanceTemperature {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "performance_max_temperature",
+			Value:   limit,
+			Maximum: maxPerformanceTemperature,
+		})
+	}
+
+	if margin := celsiusDelta(l.v, "pre_throttle_margin"); margin < 0 || margin > maxPreThrottleMargin {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "pre_throttle_margin",
+			Value:   margin,
+			Maximum: maxPreThrottleMargin,
+		})
+	}
+
+	if floor := l.v.GetInt("fan_floor"); floor < 0 || floor > 100 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value int
+		}{
+			Field: "fan_floor",
+			Value: floor,
+		})
+	}
+
+	if l.v.GetBool("fan_stop") {
+		maxTemperature := configuredTemperature(l.v)
+		if stop := celsius(l.v, "fan_stop_temperature"); stop < 1 || stop >= maxTemperature {
+			return errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field   string
+				Value   int
+				Maximum int
+			}{
+				Field:   "fan_stop_temperature",
+				Value:   stop,
+				Maximum: maxTemperature - 1,
+			})
+		}
+	}
+
+	if fixed := l.v.GetInt("fan_fixed_speed"); fixed < 0 || fixed > 100 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value int
+		}{
+			Field: "fan_fixed_speed",
+			Value: fixed,
+		})
+	}
+
+	for _, key := range []string{"fan_pid_kp", "fan_pid_ki", "fan_pid_kd"} {
+		if gain := l.v.GetFloat64(key); gain < 0 {
+			return errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field string
+				Value float64
+			}{
+				Field: key,
+				Value: gain,
+			})
+		}
+	}
+
+	for _, key := range []string{"performance_fan_cap", "initial_fan_speed"} {
+		if speed := l.v.GetInt(key); speed < 0 || speed > 100 {
+			return errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field string
+				Value int
+			}{
+				Field: key,
+				Value: speed,
+			})
+		}
+	}
+
+	for _, key := range []string{"power_lower_threshold", "power_raise_threshold"} {
+		if threshold := celsiusDelta(l.v, key); threshold < 0 || threshold > maxPowerThreshold {
+			return errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field   string
+				Value   int
+				Maximum int
+			}{
+				Field:   key,
+				Value:   threshold,
+				Maximum: maxPowerThreshold,
+			})
+		}
+	}
+
+	if ticks := l.v.GetInt("power_reaction_ticks"); ticks < 0 || ticks > maxPowerReactionTicks {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "power_reaction_ticks",
+			Value:   ticks,
+			Maximum: maxPowerReactionTicks,
+		})
+	}
+
+	if step := l.v.GetInt("power_min_step"); step < 1 || step > maxPowerMinStep {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "power_min_step",
+			Value:   step,
+			Maximum: maxPowerMinStep,
+		})
+	}
+
+	if niceness := l.v.GetInt("niceness"); niceness < -20 || niceness > 19 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value int
+		}{
+			Field: "niceness",
+			Value: niceness,
+		})
+	}
+
+	if fanStep := l.v.GetInt("fan_step"); fanStep < 1 || fanStep > 100 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value int
+		}{
+			Field: "fan_step",
+			Value: fanStep,
+		})
+	}
+
+	for _, key := range []string{"metrics_max_size", "metrics_retention_days"} {
+		if l.v.GetInt(key) < 0 {
+			return errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field string
+				Value int
+			}{
+				Field: key,
+				Value: l.v.GetInt(key),
+			})
+		}
+	}
+
+	if l.v.GetInt("metrics_max_failures") < 0 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value int
+		}{
+			Field: "metrics_max_failures",
+			Value: l.v.GetInt("metrics_max_failures"),
+		})
+	}
+
+	metricsSynchronous := MetricsSynchronous(l.v.GetString("metrics_synchronous"))
+	if !metricsSynchronous.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "metrics_synchronous",
+			Value: string(metricsSynchronous),
+		})
+	}
+
+	metricsJournalMode := MetricsJournalMode(l.v.GetString("metrics_journal_mode"))
+	if !metricsJournalMode.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "metrics_journal_mode",
+			Value: string(metricsJournalMode),
+		})
+	}
+
+	backends := l.v.GetStringSlice("metrics_backends")
+	if len(backends) == 0 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value []string
+		}{
+			Field: "metrics_backends",
+			Value: backends,
+		})
+	}
+	for i, backend := range backends {
+		if !MetricsBackend(backend).IsValid() || slices.Contains(backends[:i], backend) {
+			return errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field string
+				Value string
+			}{
+				Field: "metrics_backends",
+				Value: backend,
+			})
+		}
+	}
+
+	if slices.Contains(backends, string(MetricsBackendTextfile)) && l.v.GetString("metrics_textfile") == "" {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+			Error string
+		}{
+			Field: "metrics_textfile",
+			Value: "",
+			Error: "required by the textfile metrics backend",
+		})
+	}
+
+	if slices.Contains(backends, string(MetricsBackendInflux)) && l.v.GetString("metrics_influx_socket") == "" {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+			Error string
+		}{
+			Field: "metrics_influx_socket",
+			Value: "",
+			Error: "required by the influx metrics backend",
+		})
+	}
+
+	initialFanControl := InitialFanControl(l.v.GetString("initial_fan_control"))
+	if !initialFanControl.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "initial_fan_control",
+			Value: string(initialFanControl),
+		})
+	}
+
+	fanSteps, err := parseCurvePoints(l.v, "fan_steps", 100)
+	if err != nil {
+		return err
+	}
+
+	// Other strategies may be registered by the control package, unknown
+	// names are rejected when the strategy is created
+	switch strategy := l.v.GetString("fan_strategy"); {
+	case strategy == "":
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "fan_strategy",
+			Value: strategy,
+		})
+	case strategy == FanStrategySteps && len(fanSteps) == 0:
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+			Error string
+		}{
+			Field: "fan_strategy",
+			Value: strategy,
+			Error: "fan_steps is empty",
+		})
+	}
+
+	if _, err := parseCurvePoints(l.v, "fan_curve", 100); err != nil {
+		return err
+	}
+
+	if _, err := parseCurvePoints(l.v, "power_curve", maxPowerCurveWatts); err != nil {
+		return err
+	}
+
+	coolWith := CoolingPriority(l.v.GetString("cool_with"))
+	if !coolWith.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "cool_with",
+			Value: string(coolWith),
+		})
+	}
+
+	if l.v.GetInt("max_consecutive_skips") < 0 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value int
+		}{
+			Field: "max_consecutive_skips",
+			Value: l.v.GetInt("max_consecutive_skips"),
+		})
+	}
+
+	if l.v.GetInt("failsafe_threshold") < 0 {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value int
+		}{
+			Field: "failsafe_threshold",
+			Value: l.v.GetInt("failsafe_threshold"),
+		})
+	}
+
+	failsafeRecovery := FailsafeRecovery(l.v.GetString("failsafe_recovery"))
+	if !failsafeRecovery.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "failsafe_recovery",
+			Value: string(failsafeRecovery),
+		})
+	}
+
+	gpuCoordination := GPUCoordination(l.v.GetString("gpu_coordination"))
+	if !gpuCoordination.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "gpu_coordination",
+			Value: string(gpuCoordination),
+		})
+	}
+
+	if _, err := parseTemperatureBlend(l.v); err != nil {
+		return err
+	}
+
+	if _, err := parseProfiles(l.v); err != nil {
+		return err
+	}
+
+	for _, key := range []string{"utilization_window", "temperature_window", "power_window"} {
+		if window := l.v.GetInt(key); window < 1 || window > maxSampleWindow {
+			return errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field   string
+				Value   int
+				Maximum int
+			}{
+				Field:   key,
+				Value:   window,
+				Maximum: maxSampleWindow,
+			})
+		}
+	}
+
+	if retries := l.v.GetInt("init_retries"); retries < 0 || retries > maxInitRetries {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "init_retries",
+			Value:   retries,
+			Maximum: maxInitRetries,
+		})
+	}
+
+	initBackoff, err := parseInterval(l.v, "init_backoff")
+	if err != nil {
+		return err
+	}
+	if initBackoff < 0 {
+		return errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Field string
+			Value time.Duration
+		}{
+			Field: "init_backoff",
+			Value: initBackoff,
+		})
+	}
+
+	if retries := l.v.GetInt("nvml_read_retries"); retries < 0 || retries > maxReadRetries {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "nvml_read_retries",
+			Value:   retries,
+			Maximum: maxReadRetries,
+		})
+	}
+
+	readRetryDelay, err := parseInterval(l.v, "nvml_read_retry_delay")
+	if err != nil {
+		return err
+	}
+	if readRetryDelay < 0 || readRetryDelay > maxReadRetryDelay {
+		return errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Field   string
+			Value   time.Duration
+			Maximum time.Duration
+		}{
+			Field:   "nvml_read_retry_delay",
+			Value:   readRetryDelay,
+			Maximum: maxReadRetryDelay,
+		})
+	}
+
+	if reads := l.v.GetInt("warmup_reads"); reads < 0 || reads > maxWarmupReads {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "warmup_reads",
+			Value:   reads,
+			Maximum: maxWarmupReads,
+		})
+	}
+
+	if size := l.v.GetInt("decision_history"); size < 0 || size > maxDecisionHistory {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "decision_history",
+			Value:   size,
+			Maximum: maxDecisionHistory,
+		})
+	}
+
+	if threshold := celsiusDelta(l.v, "fast_path_threshold"); threshold < 0 || threshold > maxFastPathThreshold {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "fast_path_threshold",
+			Value:   threshold,
+			Maximum: maxFastPathThreshold,
+		})
+	}
+
+	sensorIndex := l.v.GetInt("temperature_sensor_index")
+	if sensorIndex < -1 || sensorIndex > maxTemperatureSensorIndex {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field   string
+			Value   int
+			Maximum int
+		}{
+			Field:   "temperature_sensor_index",
+			Value:   sensorIndex,
+			Maximum: maxTemperatureSensorIndex,
+		})
+	}
+
+	source := TemperatureSource(l.v.GetString("temperature_source"))
+	if !source.IsValid() {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "temperature_source",
+			Value: string(source),
+		})
+	}
+
+	if device := l.v.GetString("device"); device != "" && !isDeviceSelector(device) {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Value string
+			Error string
+		}{
+			Field: "device",
+			Value: device,
+			Error: "expected a GPU index or a UUID starting with GPU- or MIG-",
+		})
+	}
+
+	return validateExclusive(l.v)
+}
+
+// configuredTemperature returns temperature in Celsius, converted from
+// temperature_unit
+func configuredTemperature(v *viper.Viper) int {
+	return celsius(v, "temperature")
+}
+
+// temperatureUnit returns the unit temperature settings are configured in
+func temperatureUnit(v *viper.Viper) TemperatureUnit {
+	return TemperatureUnit(strings.ToUpper(v.GetString("temperature_unit")))
+}
+
+// celsius returns the temperature setting key in Celsius, converted from
+// temperature_unit
+func celsius(v *viper.Viper, key string) int {
+	return temperatureUnit(v).ToCelsius(v.GetInt(key))
+}
+
+// celsiusDelta returns the temperature difference setting key in Celsius,
+// converted from temperature_unit
+func celsiusDelta(v *viper.Viper, key string) int {
+	return temperatureUnit(v).DeltaToCelsius(v.GetInt(key))
+}
+
+// performanceMaxTemperature returns performance_max_temperature in Celsius,
+// 0 if disabled
+func performanceMaxTemperature(v *viper.Viper) int {
+	if v.GetInt("performance_max_temperature") == 0 {
+		return 0
+	}
+	return celsius(v, "performance_max_temperature")
+}
+
+// isDeviceSelector returns whether a device is a non-negative index or a UUID
+func isDeviceSelector(device string) bool {
+	if index, err := strconv.Atoi(device); err == nil {
+		return index >= 0
+	}
+	return strings.HasPrefix(device, "GPU-") || strings.HasPrefix(device, "MIG-")
 }
 
 // Provider interface implementation
 func (c *viperConfig) GetInterval() int {
-	return c.v.GetInt("interval")
+	return int(c.GetIntervalDuration() / time.Second)
+}
+
+func (c *viperConfig) GetIntervalDuration() time.Duration {
+	// Validated at load time
+	interval, _ := parseInterval(c.v, "interval")
+	return interval
 }
 
 func (c *viperConfig) GetTemperature() int {
-	return c.v.GetInt("temperature")
+	return configuredTemperature(c.v)
+}
+
+func (c *viperConfig) GetTemperatureUnit() TemperatureUnit {
+	return temperatureUnit(c.v)
+}
+
+func (c *viperConfig) GetTemperatureStatistic() TemperatureStatistic {
+	return TemperatureStatistic(c.v.GetString("temperature_statistic"))
+}
+
+func (c *viperConfig) GetTemperatureSmoothing() TemperatureSmoothing {
+	return TemperatureSmoothing(c.v.GetString("temperature_smoothing"))
+}
+
+func (c *viperConfig) GetEMAAlpha() float64 {
+	return c.v.GetFloat64("ema_alpha")
+}
+
+func (c *viperConfig) GetTemperatureFilter() TemperatureFilter {
+	return TemperatureFilter(c.v.GetString("temperature_filter"))
+}
+
+func (c *viperConfig) GetTemperatureFilterDelta() int {
+	return celsiusDelta(c.v, "temperature_filter_delta")
+}
+
+func (c *viperConfig) GetTemperatureOffset() int {
+	return celsiusDelta(c.v, "temperature_offset")
 }
 
 func (c *viperConfig) GetFanSpeed() int {
 	return c.v.GetInt("fanspeed")
 }
 
+func (c *viperConfig) GetPerformanceFanCap() int {
+	fanSpeed := c.GetFanSpeed()
+	if fanCap := c.v.GetInt("performance_fan_cap"); fanCap > 0 {
+		return min(fanCap, fanSpeed)
+	}
+	return fanSpeed
+}
+
+func (c *viperConfig) GetPerformanceMaxTemperature() int {
+	return performanceMaxTemperature(c.v)
+}
+
+func (c *viperConfig) GetFanStrategy() string {
+	strategy := c.v.GetString("fan_strategy")
+	if strategy != FanStrategyAuto {
+		return strategy
+	}
+	if len(c.GetFanSteps()) > 0 {
+		return FanStrategySteps
+	}
+	return FanStrategyCurve
+}
+
+func (c *viperConfig) GetFanFixedSpeed() int {
+	return c.v.GetInt("fan_fixed_speed")
+}
+
+func (c *viperConfig) GetFanPIDGains() PIDGains {
+	return PIDGains{
+		Proportional: c.v.GetFloat64("fan_pid_kp"),
+		Integral:     c.v.GetFloat64("fan_pid_ki"),
+		Derivative:   c.v.GetFloat64("fan_pid_kd"),
+	}
+}
+
+func (c *viperConfig) GetFanFloor() int {
+	return c.v.GetInt("fan_floor")
+}
+
+func (c *viperConfig) IsFanStopEnabled() bool {
+	return c.v.GetBool("fan_stop")
+}
+
+func (c *viperConfig) GetFanStopTemperature() int {
+	return celsius(c.v, "fan_stop_temperature")
+}
+
 func (c *viperConfig) GetHysteresis() int {
 	return c.v.GetInt("hysteresis")
 }
 
+func (c *viperConfig) GetPowerLowerThreshold() int {
+	return celsiusDelta(c.v, "power_lower_threshold")
+}
+
+func (c *viperConfig) GetPowerRaiseThreshold() int {
+	return celsiusDelta(c.v, "power_raise_threshold")
+}
+
+func (c *viperConfig) GetPowerReactionTicks() int {
+	return c.v.GetInt("power_reaction_ticks")
+}
+
+func (c *viperConfig) IsPowerReadbackEnabled() bool {
+	return c.v.GetBool("power_readback")
+}
+
+func (c *viperConfig) GetPowerMinStep() int {
+	return c.v.GetInt("power_min_step")
+}
+
+func (c *viperConfig) GetPowerCurve() []CurvePoint {
+	return c.powerCurve
+}
+
+func (c *viperConfig) GetFanSteps() []CurvePoint {
+	return c.fanSteps
+}
+
+func (c *viperConfig) GetFanCurve() []CurvePoint {
+	return c.fanCurve
+}
+
+func (c *viperConfig) GetFanStep() int {
+	return c.v.GetInt("fan_step")
+}
+
+func (c *viperConfig) GetInitialFanControl() InitialFanControl {
+	return InitialFanControl(c.v.GetString("initial_fan_control"))
+}
+
+func (c *viperConfig) GetDeviceSelector() string {
+	return c.v.GetString("device")
+}
+
+func (c *viperConfig) GetInitialFanSpeed() int {
+	return c.v.GetInt("initial_fan_speed")
+}
+
+func (c *viperConfig) GetCoolingPriority() CoolingPriority {
+	return CoolingPriority(c.v.GetString("cool_with"))
+}
+
 func (c *viperConfig) IsPerformanceMode() bool {
 	return c.v.GetBool("performance")
 }
@@ -101,10 +1135,22 @@ func (c *viperConfig) IsMonitorMode() bool {
 	return c.v.GetBool("monitor")
 }
 
+func (c *viperConfig) IsMonitorReadOnly() bool {
+	return c.v.GetBool("monitor_read_only")
+}
+
+func (c *viperConfig) Settings() map[string]any {
+	return c.v.AllSettings()
+}
+
 func (c *viperConfig) GetLogLevel() string {
 	return c.v.GetString("log_level")
 }
 
+func (c *viperConfig) GetLogFormat() LogFormat {
+	return LogFormat(c.v.GetString("log_format"))
+}
+
 func (c *viperConfig) IsMetricsEnabled() bool {
 	return c.v.GetBool("metrics")
 }
@@ -113,47 +1159,349 @@ func (c *viperConfig) GetMetricsDBPath() string {
 	return c.v.GetString("database")
 }
 
+func (c *viperConfig) GetFanReadFailureAction() FanReadFailureAction {
+	return FanReadFailureAction(c.v.GetString("fan_read_failure"))
+}
+
+func (c *viperConfig) GetMetricsMaxFailures() int {
+	return c.v.GetInt("metrics_max_failures")
+}
+
+func (c *viperConfig) IsMetricsShutdownSnapshotEnabled() bool {
+	return c.v.GetBool("metrics_shutdown_snapshot")
+}
+
+func (c *viperConfig) GetMetricsSynchronous() MetricsSynchronous {
+	return MetricsSynchronous(c.v.GetString("metrics_synchronous"))
+}
+
+func (c *viperConfig) IsSplitControl() bool {
+	return c.v.GetBool("split_control")
+}
+
+func (c *viperConfig) GetPowerIntervalDuration() time.Duration {
+	// Validated at load time
+	d, _ := parseInterval(c.v, "power_interval")
+	return d
+}
+
+func (c *viperConfig) GetResumeGap() time.Duration {
+	// Validated at load time
+	d, _ := parseInterval(c.v, "resume_gap")
+	if d == 0 {
+		return 0
+	}
+
+	// A gap of a few intervals is scheduling jitter, not a resume
+	return max(d, minResumeGapIntervals*c.GetIntervalDuration())
+}
+
+func (c *viperConfig) GetLimitsRefreshInterval() time.Duration {
+	// Validated at load time
+	d, _ := parseInterval(c.v, "limits_refresh_interval")
+	return d
+}
+
+func (c *viperConfig) GetMetricsJournalMode() MetricsJournalMode {
+	return MetricsJournalMode(c.v.GetString("metrics_journal_mode"))
+}
+
+func (c *viperConfig) GetMetricsTextfile() string {
+	return c.v.GetString("metrics_textfile")
+}
+
+func (c *viperConfig) GetDecisionHistory() int {
+	return c.v.GetInt("decision_history")
+}
+
+func (c *viperConfig) GetInitRetries() int {
+	return c.v.GetInt("init_retries")
+}
+
+func (c *viperConfig) GetInitBackoff() time.Duration {
+	// Validated at load time
+	d, _ := parseInterval(c.v, "init_backoff")
+	return d
+}
+
+func (c *viperConfig) GetNVMLReadRetries() int {
+	return c.v.GetInt("nvml_read_retries")
+}
+
+func (c *viperConfig) GetNVMLReadRetryDelay() time.Duration {
+	// Validated at load time
+	d, _ := parseInterval(c.v, "nvml_read_retry_delay")
+	return d
+}
+
+func (c *viperConfig) GetFanOverrun() time.Duration {
+	// Validated at load time
+	d, _ := parseInterval(c.v, "fan_overrun")
+	return d
+}
+
+func (c *viperConfig) GetJitterTolerance() int {
+	return c.v.GetInt("jitter_tolerance")
+}
+
+func (c *viperConfig) IsBannerEnabled() bool {
+	return c.v.GetBool("banner") && !c.v.GetBool("no_banner")
+}
+
+func (c *viperConfig) GetPreThrottleMargin() int {
+	return celsiusDelta(c.v, "pre_throttle_margin")
+}
+
+func (c *viperConfig) GetMetricsMaxSize() int {
+	return c.v.GetInt("metrics_max_size")
+}
+
+func (c *viperConfig) IsMetricsDestructiveMigrationEnabled() bool {
+	return c.v.GetBool("metrics_destructive_migrations")
+}
+
+func (c *viperConfig) GetMetricsRetentionDays() int {
+	return c.v.GetInt("metrics_retention_days")
+}
+
+func (c *viperConfig) GetMetricsInfluxSocket() string {
+	return c.v.GetString("metrics_influx_socket")
+}
+
+func (c *viperConfig) GetMetricsBackends() []MetricsBackend {
+	names := c.v.GetStringSlice("metrics_backends")
+	backends := make([]MetricsBackend, len(names))
+	for i, name := range names {
+		backends[i] = MetricsBackend(name)
+	}
+	return backends
+}
+
+func (c *viperConfig) IsNVMLDebugEnabled() bool {
+	return c.v.GetBool("metrics_nvml_debug")
+}
+
+func (c *viperConfig) GetMaxConsecutiveSkips() int {
+	return c.v.GetInt("max_consecutive_skips")
+}
+
+func (c *viperConfig) GetFailsafeThreshold() int {
+	return c.v.GetInt("failsafe_threshold")
+}
+
+func (c *viperConfig) GetFailsafeRecovery() FailsafeRecovery {
+	return FailsafeRecovery(c.v.GetString("failsafe_recovery"))
+}
+
+func (c *viperConfig) GetRunAsUser() string {
+	return c.v.GetString("run_as_user")
+}
+
+func (c *viperConfig) GetNiceness() int {
+	return c.v.GetInt("niceness")
+}
+
+func (c *viperConfig) IsConfigWatchEnabled() bool {
+	return c.v.GetBool("watch_config")
+}
+
+func (c *viperConfig) IsNVMLEventsEnabled() bool {
+	return c.v.GetBool("nvml_events")
+}
+
+func (c *viperConfig) GetStatusSocket() string {
+	return c.v.GetString("status_socket")
+}
+
+func (c *viperConfig) GetLockFile() string {
+	return c.v.GetString("lock_file")
+}
+
+func (c *viperConfig) GetGPUCoordination() GPUCoordination {
+	return GPUCoordination(c.v.GetString("gpu_coordination"))
+}
+
+func (c *viperConfig) GetUtilizationWindow() int {
+	return c.v.GetInt("utilization_window")
+}
+
+func (c *viperConfig) GetTemperatureWindow() int {
+	return c.v.GetInt("temperature_window")
+}
+
+func (c *viperConfig) GetPowerWindow() int {
+	return c.v.GetInt("power_window")
+}
+
+func (c *viperConfig) GetWarmupReads() int {
+	return c.v.GetInt("warmup_reads")
+}
+
+func (c *viperConfig) GetFastPathThreshold() int {
+	return celsiusDelta(c.v, "fast_path_threshold")
+}
+
+func (c *viperConfig) GetTemperatureSensorIndex() int {
+	return c.v.GetInt("temperature_sensor_index")
+}
+
+func (c *viperConfig) GetTemperatureSource() TemperatureSource {
+	return TemperatureSource(c.v.GetString("temperature_source"))
+}
+
+func (c *viperConfig) GetTemperatureBlend() map[TemperatureSensor]float64 {
+	// Validated at load time
+	blend, _ := parseTemperatureBlend(c.v)
+	return blend
+}
+
+func (c *viperConfig) GetProfiles() []Profile {
+	return c.profiles
+}
+
+func (c *viperConfig) ActiveProfile(now time.Time) (Profile, bool) {
+	return activeProfile(c.profiles, now)
+}
+
 // Internal helper functions
 func setDefaults(v *viper.Viper) {
 	v.SetDefault("interval", 2)
+	v.SetDefault("min_interval", "1s")
+	v.SetDefault("resume_gap", "30s")
+	v.SetDefault("jitter_tolerance", 50)
+	v.SetDefault("fan_overrun", 0)
+	v.SetDefault("init_retries", 3)
+	v.SetDefault("init_backoff", "1s")
+	v.SetDefault("nvml_read_retries", 2)
+	v.SetDefault("nvml_read_retry_delay", "50ms")
+	v.SetDefault("limits_refresh_interval", 0)
 	v.SetDefault("temperature", 80)
+	v.SetDefault("temperature_unit", string(TemperatureUnitCelsius))
+	v.SetDefault("temperature_offset", 0)
+	v.SetDefault("temperature_statistic", string(StatisticMean))
+	v.SetDefault("temperature_smoothing", string(SmoothingSMA))
+	v.SetDefault("ema_alpha", 0.3)
+	v.SetDefault("temperature_filter", string(TemperatureFilterNone))
+	v.SetDefault("temperature_filter_delta", 15)
+	v.SetDefault("temperature_sensor_index", -1)
+	v.SetDefault("temperature_source", string(TemperatureSourceCore))
+	v.SetDefault("warmup_reads", 0)
+	v.SetDefault("fast_path_threshold", 0)
+	v.SetDefault("utilization_window", 5)
+	v.SetDefault("temperature_window", 5)
+	v.SetDefault("power_window", 5)
 	v.SetDefault("fanspeed", 100)
 	v.SetDefault("hysteresis", 4)
+	v.SetDefault("performance_fan_cap", 0)
+	v.SetDefault("performance_max_temperature", 0)
+	v.SetDefault("pre_throttle_margin", 0)
+	v.SetDefault("fan_floor", 0)
+	v.SetDefault("fan_stop", false)
+	v.SetDefault("fan_stop_temperature", 45)
+	v.SetDefault("fan_step", 1)
+	v.SetDefault("fan_steps", "")
+	v.SetDefault("fan_curve", "")
+	v.SetDefault("fan_strategy", FanStrategyAuto)
+	v.SetDefault("fan_fixed_speed", 0)
+	v.SetDefault("fan_pid_kp", defaultPIDProportional)
+	v.SetDefault("fan_pid_ki", defaultPIDIntegral)
+	v.SetDefault("fan_pid_kd", 0.0)
+	v.SetDefault("power_curve", "")
+	v.SetDefault("power_lower_threshold", 0)
+	v.SetDefault("power_raise_threshold", 0)
+	v.SetDefault("power_min_step", 1)
+	v.SetDefault("power_reaction_ticks", 0)
+	v.SetDefault("power_readback", false)
+	v.SetDefault("split_control", false)
+	v.SetDefault("power_interval", "10s")
+	v.SetDefault("cool_with", string(CoolWithBoth))
+	v.SetDefault("initial_fan_control", string(InitialFanControlDetect))
+	v.SetDefault("initial_fan_speed", 0)
+	v.SetDefault("device", "")
+	v.SetDefault("gpu_coordination", string(GPUCoordinationNone))
 	v.SetDefault("performance", false)
 	v.SetDefault("monitor", false)
-	v.SetDefault("log_level", DefaultLogLevel)
+	v.SetDefault("monitor_read_only", true)
+	v.SetDefault("log_level", string(DefaultLogLevel))
+	v.SetDefault("log_format", string(LogFormatConsole))
+	v.SetDefault("banner", !logger.IsService())
 	v.SetDefault("metrics", false)
 	v.SetDefault("database", "/var/lib/nvidiactl/metrics.db")
+	v.SetDefault("metrics_nvml_debug", false)
+	v.SetDefault("metrics_synchronous", string(MetricsSynchronousNormal))
+	v.SetDefault("metrics_max_failures", 0)
+	v.SetDefault("metrics_shutdown_snapshot", true)
+	v.SetDefault("metrics_max_size", 0)
+	v.SetDefault("metrics_retention_days", 0)
+	v.SetDefault("metrics_destructive_migrations", false)
+	v.SetDefault("metrics_backends", []string{string(MetricsBackendSQLite)})
+	v.SetDefault("metrics_textfile", "")
+	v.SetDefault("metrics_influx_socket", "")
+	v.SetDefault("metrics_journal_mode", string(MetricsJournalWAL))
+	v.SetDefault("fan_read_failure", string(FanReadFailureProceed))
+	v.SetDefault("max_consecutive_skips", 0)
+	v.SetDefault("failsafe_threshold", 0)
+	v.SetDefault("failsafe_recovery", string(FailsafeRecoveryManual))
+	v.SetDefault("status_socket", "/run/nvidiactl.sock")
+	v.SetDefault("lock_file", "/run/nvidiactl.lock")
+	v.SetDefault("decision_history", 60)
+	v.SetDefault("run_as_user", "")
+	v.SetDefault("niceness", defaultNiceness)
+	v.SetDefault("watch_config", false)
+	v.SetDefault("nvml_events", false)
 }
 
-func defineFlags(v *viper.Viper) {
+// defineFlags defines the command-line flags and parses args, or the process
+// arguments when args is nil
+func defineFlags(v *viper.Viper, args []string) error {
 	pflag.String("config", "", "path to config file")
 	pflag.String("log-level", v.GetString("log_level"), "log level (debug, info, warning, error)")
-	pflag.Int("interval", v.GetInt("interval"), "interval between updates in seconds")
-	pflag.Int("temperature", v.GetInt("temperature"), "maximum allowed temperature in Celsius")
+	pflag.String("interval", v.GetString("interval"), "interval between updates in seconds or as a duration (e.g. 500ms, 1m)")
+	pflag.Int("temperature", v.GetInt("temperature"), "maximum allowed temperature in temperature_unit (Celsius by default)")
+	pflag.Int("temperature-offset", v.GetInt("temperature_offset"), "offset in temperature_unit added to every temperature reading")
 	pflag.Int("fanspeed", v.GetInt("fanspeed"), "maximum allowed fan speed in percent")
 	pflag.Int("hysteresis", v.GetInt("hysteresis"), "temperature change required before adjusting fan speed")
+	pflag.Int("fan-step", v.GetInt("fan_step"), "round fan speed targets to multiples of this percentage")
+	pflag.String("cool-with", v.GetString("cool_with"), "cooling priority above target temperature (fans, power, both)")
 	pflag.Bool("performance", v.GetBool("performance"), "enable performance mode")
+	pflag.Int("performance-fan-cap", v.GetInt("performance_fan_cap"), "maximum fan speed in percent in performance mode (0 for fanspeed)")
 	pflag.Bool("monitor", v.GetBool("monitor"), "enable monitor mode")
 	pflag.Bool("metrics", v.GetBool("metrics"), "enable metrics collection")
 	pflag.String("database", v.GetString("database"), "path to the metrics database file")
+	pflag.Bool("metrics-nvml-debug", v.GetBool("metrics_nvml_debug"), "record raw NVML return codes in metrics (debugging aid)")
+	pflag.String("status-socket", v.GetString("status_socket"), "path to the status socket (empty to disable)")
+	pflag.Bool("banner", v.GetBool("banner"), "print the startup banner (default when not running as a service)")
+	pflag.Bool("no-banner", false, "don't print the startup banner")
+
+	if args != nil {
+		return pflag.CommandLine.Parse(args)
+	}
 
 	pflag.Parse()
+	return nil
 }
 
 func bindFlags(v *viper.Viper) error {
 	errFactory := errors.New()
 	flags := map[string]string{
-		"config":      "config",
-		"log_level":   "log-level",
-		"interval":    "interval",
-		"temperature": "temperature",
-		"fanspeed":    "fanspeed",
-		"hysteresis":  "hysteresis",
-		"performance": "performance",
-		"monitor":     "monitor",
-		"metrics":     "metrics",
-		"database":    "database",
+		"config":              "config",
+		"log_level":           "log-level",
+		"interval":            "interval",
+		"temperature":         "temperature",
+		"temperature_offset":  "temperature-offset",
+		"fanspeed":            "fanspeed",
+		"hysteresis":          "hysteresis",
+		"fan_step":            "fan-step",
+		"cool_with":           "cool-with",
+		"performance":         "performance",
+		"performance_fan_cap": "performance-fan-cap",
+		"monitor":             "monitor",
+		"metrics":             "metrics",
+		"database":            "database",
+		"metrics_nvml_debug":  "metrics-nvml-debug",
+		"status_socket":       "status-socket",
+		"banner":              "banner",
+		"no_banner":           "no-banner",
 	}
 
 	for configKey, flagName := range flags {
@@ -176,6 +1524,7 @@ func loadConfigFile(v *viper.Viper, configPath string) error {
 
 	if configPath != "" {
 		v.SetConfigFile(configPath)
+		v.SetConfigType(configType(configPath))
 	}
 
 	err := v.ReadInConfig()
@@ -201,8 +1550,194 @@ func loadConfigFile(v *viper.Viper, configPath string) error {
 	return nil
 }
 
+// configType returns the format of a config file from its extension,
+// defaulting to TOML for nvidiactl.conf and unknown extensions
+func configType(path string) string {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	if slices.Contains(viper.SupportedExts, ext) {
+		return ext
+	}
+	return "toml"
+}
+
 func bindEnvVariables(v *viper.Viper, prefix string) {
 	v.SetEnvPrefix(prefix)
 	v.AutomaticEnv()
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 }
+
+// parseTemperatureBlend reads the temperature_blend table and returns the
+// sensor weights normalized to sum to 1. Returns nil if no blend is configured.
+func parseTemperatureBlend(v *viper.Viper) (map[TemperatureSensor]float64, error) {
+	errFactory := errors.New()
+
+	raw := v.GetStringMap("temperature_blend")
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	blend := make(map[TemperatureSensor]float64, len(raw))
+	var sum float64
+	for name, value := range raw {
+		sensor := TemperatureSensor(name)
+		if !sensor.IsValid() {
+			return nil, errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field  string
+				Sensor string
+			}{
+				Field:  "temperature_blend",
+				Sensor: name,
+			})
+		}
+
+		var weight float64
+		switch w := value.(type) {
+		case int:
+			weight = float64(w)
+		case int64:
+			weight = float64(w)
+		case float64:
+			weight = w
+		default:
+			return nil, errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field  string
+				Sensor string
+				Weight any
+			}{
+				Field:  "temperature_blend",
+				Sensor: name,
+				Weight: value,
+			})
+		}
+
+		if weight < 0 {
+			return nil, errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field  string
+				Sensor string
+				Weight float64
+			}{
+				Field:  "temperature_blend",
+				Sensor: name,
+				Weight: weight,
+			})
+		}
+
+		blend[sensor] = weight
+		sum += weight
+	}
+
+	if sum <= 0 {
+		return nil, errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field  string
+			Reason string
+		}{
+			Field:  "temperature_blend",
+			Reason: "weights must sum to a positive value",
+		})
+	}
+
+	for sensor, weight := range blend {
+		blend[sensor] = weight / sum
+	}
+
+	return blend, nil
+}
+
+// parseInterval reads a duration config value. Plain numbers are interpreted
+// as seconds for backward compatibility, strings use Go duration syntax
+// (e.g. "500ms", "2s", "1m").
+func parseInterval(v *viper.Viper, key string) (time.Duration, error) {
+	errFactory := errors.New()
+
+	switch value := v.Get(key).(type) {
+	case int:
+		return time.Duration(value) * time.Second, nil
+	case int64:
+		return time.Duration(value) * time.Second, nil
+	case float64:
+		return time.Duration(value * float64(time.Second)), nil
+	case time.Duration:
+		return value, nil
+	case string:
+		value = strings.TrimSpace(value)
+		if seconds, err := strconv.Atoi(value); err == nil {
+			return time.Duration(seconds) * time.Second, nil
+		}
+
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return 0, errFactory.WithData(errors.ErrInvalidInterval, struct {
+				Field string
+				Value string
+			}{
+				Field: key,
+				Value: value,
+			})
+		}
+
+		return d, nil
+	default:
+		return 0, errFactory.WithData(errors.ErrInvalidInterval, struct {
+			Field string
+			Value any
+		}{
+			Field: key,
+			Value: value,
+		})
+	}
+}
+
+// parseCurvePoints reads a list of "temperature:value" pairs separated by
+// commas, e.g. "50:30,70:50,80:80". Temperatures are converted from
+// temperature_unit to Celsius and must be strictly ascending after the
+// conversion, values must be within [0, maxValue]. Returns nil if the key is
+// empty.
+func parseCurvePoints(v *viper.Viper, key string, maxValue int) ([]CurvePoint, error) {
+	errFactory := errors.New()
+
+	raw := strings.TrimSpace(v.GetString(key))
+	if raw == "" {
+		return nil, nil
+	}
+
+	invalid := func(point string) error {
+		return errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Point string
+		}{
+			Field: key,
+			Point: point,
+		})
+	}
+
+	unit := temperatureUnit(v)
+	parts := strings.Split(raw, ",")
+	points := make([]CurvePoint, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+
+		temperature, value, ok := strings.Cut(part, ":")
+		if !ok {
+			return nil, invalid(part)
+		}
+
+		t, err := strconv.Atoi(strings.TrimSpace(temperature))
+		if err != nil {
+			return nil, invalid(part)
+		}
+		t = unit.ToCelsius(t)
+
+		val, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || val < 0 || val > maxValue {
+			return nil, invalid(part)
+		}
+
+		if len(points) > 0 && t <= points[len(points)-1].Temperature {
+			return nil, invalid(part)
+		}
+
+		points = append(points, CurvePoint{Temperature: t, Value: val})
+	}
+
+	return points, nil
+}
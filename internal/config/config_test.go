@@ -0,0 +1,387 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"github.com/spf13/viper"
+)
+
+func TestParseInterval(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   any
+		want    time.Duration
+		wantErr bool
+	}{
+		{name: "int as seconds", value: 2, want: 2 * time.Second},
+		{name: "int64 as seconds", value: int64(3), want: 3 * time.Second},
+		{name: "float as seconds", value: 1.5, want: 1500 * time.Millisecond},
+		{name: "numeric string as seconds", value: "5", want: 5 * time.Second},
+		{name: "milliseconds", value: "500ms", want: 500 * time.Millisecond},
+		{name: "seconds", value: "2s", want: 2 * time.Second},
+		{name: "minutes", value: "1m", want: time.Minute},
+		{name: "surrounding spaces", value: " 750ms ", want: 750 * time.Millisecond},
+		{name: "duration", value: 250 * time.Millisecond, want: 250 * time.Millisecond},
+		{name: "zero", value: 0, want: 0},
+		{name: "zero string", value: "0s", want: 0},
+		{name: "negative", value: -1, want: -time.Second},
+		{name: "negative string", value: "-2s", want: -2 * time.Second},
+		{name: "garbage", value: "soon", wantErr: true},
+		{name: "missing unit", value: "1.5", wantErr: true},
+		{name: "unsupported type", value: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("interval", tt.value)
+
+			got, err := parseInterval(v, "interval")
+			if tt.wantErr {
+				var domainErr errors.Error
+				if !errors.As(err, &domainErr) || domainErr.Code() != errors.ErrInvalidInterval {
+					t.Fatalf("parseInterval(%v) error = %v, want %s", tt.value, err, errors.ErrInvalidInterval)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseInterval(%v) unexpected error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseInterval(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsNonPositiveInterval(t *testing.T) {
+	for _, interval := range []any{0, "0s", -1, "-500ms"} {
+		l := &defaultLoader{v: viper.New()}
+		setDefaults(l.v)
+		l.v.Set("interval", interval)
+
+		var domainErr errors.Error
+		if err := l.Validate(); !errors.As(err, &domainErr) || domainErr.Code() != errors.ErrInvalidInterval {
+			t.Errorf("Validate() with interval %v error = %v, want %s", interval, err, errors.ErrInvalidInterval)
+		}
+	}
+}
+
+func TestValidateMinInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		wantErr  bool
+	}{
+		{name: "defaults", settings: map[string]any{}},
+		{name: "at the minimum", settings: map[string]any{"interval": "1s"}},
+		{name: "below the default minimum", settings: map[string]any{"interval": "500ms"}, wantErr: true},
+		{name: "lowered minimum", settings: map[string]any{"interval": "500ms", "min_interval": "250ms"}},
+		{name: "raised minimum", settings: map[string]any{"interval": 2, "min_interval": "3s"}, wantErr: true},
+		{
+			name:     "minimum below the absolute minimum",
+			settings: map[string]any{"interval": "500ms", "min_interval": "50ms"},
+			wantErr:  true,
+		},
+		{
+			name:     "power interval below the minimum",
+			settings: map[string]any{"power_interval": "500ms"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestConfig(t, tt.settings)
+			if !tt.wantErr {
+				if err != nil {
+					t.Errorf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+
+			var domainErr errors.Error
+			if !errors.As(err, &domainErr) || domainErr.Code() != errors.ErrInvalidInterval {
+				t.Errorf("Validate() error = %v, want %s", err, errors.ErrInvalidInterval)
+			}
+		})
+	}
+}
+
+// newTestConfig returns the validated config with the defaults and settings
+func newTestConfig(t *testing.T, settings map[string]any) (*viperConfig, error) {
+	t.Helper()
+
+	l := &defaultLoader{v: viper.New()}
+	setDefaults(l.v)
+	for key, value := range settings {
+		l.v.Set(key, value)
+	}
+
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return newViperConfig(l.v), nil
+}
+
+func TestFahrenheitSettingsConvertedToCelsius(t *testing.T) {
+	cfg, err := newTestConfig(t, map[string]any{
+		"temperature_unit":            "f",
+		"temperature":                 176,
+		"fan_stop":                    true,
+		"fan_stop_temperature":        113,
+		"performance_max_temperature": 194,
+		"temperature_offset":          -9,
+		"temperature_filter_delta":    27,
+		"fast_path_threshold":         9,
+		"pre_throttle_margin":         18,
+		"power_lower_threshold":       4,
+		"power_raise_threshold":       5,
+		"fan_curve":                   "122:30,158:50,176:100",
+		"power_curve":                 "158:320,176:250",
+	})
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		got  int
+		want int
+	}{
+		{"temperature", cfg.GetTemperature(), 80},
+		{"fan_stop_temperature", cfg.GetFanStopTemperature(), 45},
+		{"performance_max_temperature", cfg.GetPerformanceMaxTemperature(), 90},
+		{"temperature_offset", cfg.GetTemperatureOffset(), -5},
+		{"temperature_filter_delta", cfg.GetTemperatureFilterDelta(), 15},
+		{"fast_path_threshold", cfg.GetFastPathThreshold(), 5},
+		{"pre_throttle_margin", cfg.GetPreThrottleMargin(), 10},
+		{"power_lower_threshold", cfg.GetPowerLowerThreshold(), 2},
+		{"power_raise_threshold", cfg.GetPowerRaiseThreshold(), 3},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %d°C, want %d°C", tt.name, tt.got, tt.want)
+		}
+	}
+
+	wantFanCurve := []CurvePoint{{50, 30}, {70, 50}, {80, 100}}
+	if got := cfg.GetFanCurve(); !reflect.DeepEqual(got, wantFanCurve) {
+		t.Errorf("fan_curve = %v, want %v", got, wantFanCurve)
+	}
+	wantPowerCurve := []CurvePoint{{70, 320}, {80, 250}}
+	if got := cfg.GetPowerCurve(); !reflect.DeepEqual(got, wantPowerCurve) {
+		t.Errorf("power_curve = %v, want %v", got, wantPowerCurve)
+	}
+}
+
+func TestFahrenheitDisabledPerformanceMaxTemperatureStaysDisabled(t *testing.T) {
+	cfg, err := newTestConfig(t, map[string]any{"temperature_unit": "F", "temperature": 176})
+	if err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+	if got := cfg.GetPerformanceMaxTemperature(); got != 0 {
+		t.Errorf("performance_max_temperature = %d, want 0 (disabled)", got)
+	}
+}
+
+func TestCelsiusTemperaturesAtTheCurveStartLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+	}{
+		{name: "at the curve start", settings: map[string]any{"temperature": MinTemperature}},
+		{name: "below the curve start", settings: map[string]any{"temperature": 45, "temperature_unit": "C"}},
+		{
+			name: "profile below the curve start",
+			settings: map[string]any{
+				"profiles": map[string]any{
+					"night": map[string]any{"start": "22:00", "end": "07:00", "temperature": 45},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := newTestConfig(t, tt.settings); err != nil {
+				t.Errorf("Validate() unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsTemperatureSettings(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+	}{
+		{
+			// 80°F is 27°C, below the start of the fan curve
+			name:     "fahrenheit with the celsius default",
+			settings: map[string]any{"temperature_unit": "F"},
+		},
+		{
+			name:     "converted to the minimum temperature",
+			settings: map[string]any{"temperature_unit": "F", "temperature": 122},
+		},
+		{
+			name: "fan stop above the converted target",
+			settings: map[string]any{
+				"temperature_unit": "F", "temperature": 176,
+				"fan_stop": true, "fan_stop_temperature": 180,
+			},
+		},
+		{
+			// 100°F and 101°F are both 38°C
+			name: "curve points collapsing after conversion",
+			settings: map[string]any{
+				"temperature_unit": "F", "temperature": 176,
+				"fan_curve": "100:30,101:50",
+			},
+		},
+		{
+			name: "profile temperature converted below the minimum",
+			settings: map[string]any{
+				"temperature_unit": "F", "temperature": 176,
+				"profiles": map[string]any{
+					"night": map[string]any{"start": "22:00", "end": "07:00", "temperature": 100},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestConfig(t, tt.settings)
+
+			var domainErr errors.Error
+			if !errors.As(err, &domainErr) || domainErr.Code() != errors.ErrInvalidConfig {
+				t.Errorf("Validate() error = %v, want %s", err, errors.ErrInvalidConfig)
+			}
+		})
+	}
+}
+
+func TestParseCurvePoints(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []CurvePoint
+		wantErr bool
+	}{
+		{name: "empty", value: "", want: nil},
+		{
+			name:  "points",
+			value: "50:30,70:50,80:80",
+			want:  []CurvePoint{{Temperature: 50, Value: 30}, {Temperature: 70, Value: 50}, {Temperature: 80, Value: 80}},
+		},
+		{
+			name:  "spaces",
+			value: " 50 : 30 , 80:100 ",
+			want:  []CurvePoint{{Temperature: 50, Value: 30}, {Temperature: 80, Value: 100}},
+		},
+		{name: "missing separator", value: "50-30", wantErr: true},
+		{name: "not a number", value: "fifty:30", wantErr: true},
+		{name: "value above maximum", value: "50:101", wantErr: true},
+		{name: "negative value", value: "50:-1", wantErr: true},
+		{name: "descending temperatures", value: "70:50,50:30", wantErr: true},
+		{name: "repeated temperature", value: "50:30,50:40", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			v.Set("fan_curve", tt.value)
+
+			got, err := parseCurvePoints(v, "fan_curve", 100)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseCurvePoints(%q) = %v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseCurvePoints(%q) unexpected error: %v", tt.value, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseCurvePoints(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsedSettingsCachedAtLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]any
+		key      string
+		changed  any
+		get      func(c *viperConfig) any
+	}{
+		{
+			name:     "power curve",
+			settings: map[string]any{"power_curve": "70:320,80:250"},
+			key:      "power_curve",
+			changed:  "60:300",
+			get:      func(c *viperConfig) any { return c.GetPowerCurve() },
+		},
+		{
+			name:     "fan steps",
+			settings: map[string]any{"fan_steps": "60:60,70:80"},
+			key:      "fan_steps",
+			changed:  "50:40",
+			get:      func(c *viperConfig) any { return c.GetFanSteps() },
+		},
+		{
+			name:     "fan curve",
+			settings: map[string]any{"fan_curve": "50:30,80:100"},
+			key:      "fan_curve",
+			changed:  "40:20",
+			get:      func(c *viperConfig) any { return c.GetFanCurve() },
+		},
+		{
+			name: "profiles",
+			settings: map[string]any{
+				"profiles": map[string]any{
+					"night": map[string]any{"start": "22:00", "end": "07:00", "temperature": 60},
+				},
+			},
+			key:     "profiles",
+			changed: map[string]any{},
+			get:     func(c *viperConfig) any { return c.GetProfiles() },
+		},
+		{
+			name: "active profile",
+			settings: map[string]any{
+				"profiles": map[string]any{
+					"night": map[string]any{"start": "22:00", "end": "07:00", "temperature": 60},
+				},
+			},
+			key:     "profiles",
+			changed: map[string]any{},
+			get: func(c *viperConfig) any {
+				profile, _ := c.ActiveProfile(time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local))
+				return profile.Name
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newTestConfig(t, tt.settings)
+			if err != nil {
+				t.Fatalf("Validate() unexpected error: %v", err)
+			}
+			want := tt.get(cfg)
+
+			// A getter parsing on every call would see the change
+			cfg.v.Set(tt.key, tt.changed)
+			if got := tt.get(cfg); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s after load = %v, want the value parsed at load %v", tt.key, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"github.com/spf13/viper"
+)
+
+// exclusiveSetting is a setting that may conflict with others when active
+type exclusiveSetting struct {
+	key    string
+	active func(v *viper.Viper) bool
+}
+
+// exclusiveSettings are pairs of settings that can't be active together.
+// Rather than letting one of them silently win, loading fails naming both.
+// Settings are checked after their own values were validated.
+var exclusiveSettings = [][2]exclusiveSetting{
+	{
+		{"temperature_sensor_index", func(v *viper.Viper) bool { return v.GetInt("temperature_sensor_index") >= 0 }},
+		{"temperature_blend", func(v *viper.Viper) bool {
+			blend, _ := parseTemperatureBlend(v)
+			return len(blend) > 0
+		}},
+	},
+	{
+		{"temperature_source", func(v *viper.Viper) bool {
+			return TemperatureSource(v.GetString("temperature_source")) != TemperatureSourceCore
+		}},
+		{"temperature_blend", func(v *viper.Viper) bool {
+			blend, _ := parseTemperatureBlend(v)
+			return len(blend) > 0
+		}},
+	},
+	{
+		{"fan_curve", func(v *viper.Viper) bool { return v.GetString("fan_curve") != "" }},
+		{"fan_steps", func(v *viper.Viper) bool { return v.GetString("fan_steps") != "" }},
+	},
+}
+
+// validateExclusive rejects the first pair of exclusiveSettings that are
+// both active
+func validateExclusive(v *viper.Viper) error {
+	errFactory := errors.New()
+
+	for _, pair := range exclusiveSettings {
+		if pair[0].active(v) && pair[1].active(v) {
+			return errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field         string
+				ConflictsWith string
+			}{
+				Field:         pair[0].key,
+				ConflictsWith: pair[1].key,
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"testing"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"github.com/spf13/viper"
+)
+
+func TestValidateExclusive(t *testing.T) {
+	blend := map[string]any{"core": 0.7, "memory": 0.3}
+
+	tests := []struct {
+		name          string
+		settings      map[string]any
+		wantField     string
+		wantConflicts string
+	}{
+		{name: "defaults", settings: map[string]any{}},
+		{name: "sensor index alone", settings: map[string]any{"temperature_sensor_index": 1}},
+		{name: "blend alone", settings: map[string]any{"temperature_blend": blend}},
+		{name: "fan curve alone", settings: map[string]any{"fan_curve": "50:30,80:100"}},
+		{name: "fan steps alone", settings: map[string]any{"fan_steps": "60:60,70:80"}},
+		{
+			name:          "sensor index and blend",
+			settings:      map[string]any{"temperature_sensor_index": 1, "temperature_blend": blend},
+			wantField:     "temperature_sensor_index",
+			wantConflicts: "temperature_blend",
+		},
+		{
+			name:          "source and blend",
+			settings:      map[string]any{"temperature_source": "memory", "temperature_blend": blend},
+			wantField:     "temperature_source",
+			wantConflicts: "temperature_blend",
+		},
+		{
+			name:     "core source and blend",
+			settings: map[string]any{"temperature_source": "core", "temperature_blend": blend},
+		},
+		{
+			name:          "fan curve and steps",
+			settings:      map[string]any{"fan_curve": "50:30,80:100", "fan_steps": "60:60,70:80"},
+			wantField:     "fan_curve",
+			wantConflicts: "fan_steps",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := viper.New()
+			setDefaults(v)
+			for key, value := range tt.settings {
+				v.Set(key, value)
+			}
+
+			err := validateExclusive(v)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("validateExclusive() unexpected error: %v", err)
+				}
+				return
+			}
+
+			var domainErr errors.Error
+			if !errors.As(err, &domainErr) || domainErr.Code() != errors.ErrInvalidConfig {
+				t.Fatalf("validateExclusive() error = %v, want %s", err, errors.ErrInvalidConfig)
+			}
+			data, ok := domainErr.GetData().(struct {
+				Field         string
+				ConflictsWith string
+			})
+			if !ok || data.Field != tt.wantField || data.ConflictsWith != tt.wantConflicts {
+				t.Errorf("validateExclusive() rejected %+v, want %s conflicting with %s",
+					domainErr.GetData(), tt.wantField, tt.wantConflicts)
+			}
+		})
+	}
+}
@@ -1,37 +1,343 @@
 package config
 
-import "context"
+import (
+	"context"
+	"math"
+	"time"
+)
 
 // Provider defines the interface for accessing configuration values
 // All configuration values are immutable after initial loading unless
 // Watch functionality is implemented
 type Provider interface {
-	// GetInterval returns the update interval in seconds
+	// GetInterval returns the update interval in whole seconds, rounded down.
+	// Kept for backward compatibility, prefer GetIntervalDuration.
 	GetInterval() int
 
-	// GetTemperature returns the maximum allowed temperature in Celsius
+	// GetIntervalDuration returns the update interval as a duration
+	GetIntervalDuration() time.Duration
+
+	// GetTemperature returns the maximum allowed temperature in Celsius,
+	// converted from temperature_unit
 	GetTemperature() int
 
+	// GetTemperatureUnit returns the unit temperature is configured and
+	// reported in
+	GetTemperatureUnit() TemperatureUnit
+
+	// GetTemperatureStatistic returns how the temperature window is summarized
+	// into the control input
+	GetTemperatureStatistic() TemperatureStatistic
+
+	// GetTemperatureSmoothing returns how temperature readings are smoothed
+	// into the control input
+	GetTemperatureSmoothing() TemperatureSmoothing
+
+	// GetEMAAlpha returns the weight of the newest reading in the
+	// exponential moving average, 0 to 1
+	GetEMAAlpha() float64
+
+	// GetTemperatureFilter returns how outlier temperature readings are
+	// handled
+	GetTemperatureFilter() TemperatureFilter
+
+	// GetTemperatureFilterDelta returns the deviation from the average in
+	// Celsius above which a reading is rejected as a spike, converted from
+	// temperature_unit
+	GetTemperatureFilterDelta() int
+
+	// GetTemperatureOffset returns the offset in Celsius added to every
+	// temperature reading, converted from temperature_unit
+	GetTemperatureOffset() int
+
 	// GetFanSpeed returns the maximum allowed fan speed percentage
 	GetFanSpeed() int
 
+	// GetPerformanceFanCap returns the maximum fan speed percentage in
+	// performance mode, the maximum fan speed unless configured
+	GetPerformanceFanCap() int
+
 	// GetHysteresis returns the required temperature change before adjusting fan speed
 	GetHysteresis() int
 
+	// GetInitialFanControl returns the fan control state applied at startup
+	GetInitialFanControl() InitialFanControl
+
+	// GetInitialFanSpeed returns the fan speed percentage set at startup, or
+	// 0 to leave the fan speed untouched
+	GetInitialFanSpeed() int
+
+	// GetCoolingPriority returns which of fans and power is used first above
+	// the target temperature
+	GetCoolingPriority() CoolingPriority
+
+	// GetPowerLowerThreshold returns how many degrees above the target the
+	// temperature must be before power is lowered
+	GetPowerLowerThreshold() int
+
+	// GetPowerRaiseThreshold returns how many degrees below the target the
+	// temperature must be before power is raised
+	GetPowerRaiseThreshold() int
+
+	// IsSplitControl returns whether fan and power control run in separate
+	// loops, fans every interval and power every power interval
+	IsSplitControl() bool
+
+	// GetPowerIntervalDuration returns the power control interval used with
+	// split control
+	GetPowerIntervalDuration() time.Duration
+
+	// GetPowerReactionTicks returns how many consecutive updates the
+	// temperature must stay above the target before power is lowered
+	GetPowerReactionTicks() int
+
+	// IsPowerReadbackEnabled returns whether every power limit write is
+	// verified by reading the limit back
+	IsPowerReadbackEnabled() bool
+
+	// GetPowerMinStep returns the smallest power limit change in watts; the
+	// limit only moves in multiples of it
+	GetPowerMinStep() int
+
+	// GetPowerCurve returns the temperature to power limit curve in watts, nil
+	// to use the reactive power algorithm
+	GetPowerCurve() []CurvePoint
+
+	// GetFanSteps returns the discrete fan speed steps, nil to use the
+	// continuous fan curve
+	GetFanSteps() []CurvePoint
+
+	// GetFanCurve returns the points the curve strategy interpolates fan
+	// speeds between, nil to use the built-in power curve
+	GetFanCurve() []CurvePoint
+
+	// GetFanStrategy returns the name of the fan strategy, resolving auto to
+	// steps when fan steps are configured and curve otherwise
+	GetFanStrategy() string
+
+	// GetFanFixedSpeed returns the fan speed in percent of the fixed strategy
+	GetFanFixedSpeed() int
+
+	// GetFanPIDGains returns the gains of the PID strategy
+	GetFanPIDGains() PIDGains
+
+	// GetPerformanceMaxTemperature returns the temperature in Celsius above
+	// which performance mode is suspended in favor of regular power control,
+	// 0 if disabled
+	GetPerformanceMaxTemperature() int
+
+	// GetFanFloor returns the lowest fan speed in percent under manual
+	// control, 0 to allow the hardware minimum
+	GetFanFloor() int
+
+	// IsFanStopEnabled returns whether the fans are stopped below the fan
+	// stop temperature instead of handed to the driver
+	IsFanStopEnabled() bool
+
+	// GetFanStopTemperature returns the temperature in Celsius below which
+	// the fans are stopped with fan stop enabled, converted from
+	// temperature_unit
+	GetFanStopTemperature() int
+
+	// GetFanStep returns the percentage multiple fan speed targets are rounded to
+	GetFanStep() int
+
 	// IsPerformanceMode returns whether performance mode is enabled
 	IsPerformanceMode() bool
 
 	// IsMonitorMode returns whether monitor-only mode is enabled
 	IsMonitorMode() bool
 
+	// IsMonitorReadOnly returns whether monitor mode refuses every NVML
+	// write, including restoring the defaults on exit
+	IsMonitorReadOnly() bool
+
+	// Settings returns every effective setting by key, including defaults,
+	// for diagnostics
+	Settings() map[string]any
+
 	// GetLogLevel returns the configured logging level
 	GetLogLevel() string
 
+	// GetLogFormat returns the log output format
+	GetLogFormat() LogFormat
+
 	// IsMetricsEnabled returns whether metrics collection is enabled
 	IsMetricsEnabled() bool
 
 	// GetMetricsDBPath returns the path to the metrics database
 	GetMetricsDBPath() string
+
+	// GetMetricsMaxFailures returns the number of consecutive failed metrics
+	// writes after which metrics collection is disabled, 0 to never disable
+	GetMetricsMaxFailures() int
+
+	// IsMetricsShutdownSnapshotEnabled returns whether a final snapshot is
+	// recorded after control was handed back to the driver on shutdown
+	IsMetricsShutdownSnapshotEnabled() bool
+
+	// GetMetricsSynchronous returns the SQLite synchronous mode of the metrics database
+	GetMetricsSynchronous() MetricsSynchronous
+
+	// GetLimitsRefreshInterval returns how often the hardware fan speed and
+	// power limits are re-read, 0 to only read them at startup
+	GetLimitsRefreshInterval() time.Duration
+
+	// GetResumeGap returns the gap between ticks after which control state is
+	// reset as after a system resume, at least three intervals, 0 if disabled
+	GetResumeGap() time.Duration
+
+	// GetMetricsJournalMode returns the SQLite journal mode of the metrics database
+	GetMetricsJournalMode() MetricsJournalMode
+
+	// GetMetricsBackends returns the backends metrics are recorded to
+	GetMetricsBackends() []MetricsBackend
+
+	// GetMetricsTextfile returns the file the textfile metrics backend
+	// writes to
+	GetMetricsTextfile() string
+
+	// GetMetricsInfluxSocket returns the Unix datagram socket the influx
+	// metrics backend sends to
+	GetMetricsInfluxSocket() string
+
+	// IsNVMLDebugEnabled returns whether raw NVML return codes are recorded in metrics
+	IsNVMLDebugEnabled() bool
+
+	// GetGPUCoordination returns how the temperatures of other GPUs in the
+	// system are taken into account
+	GetGPUCoordination() GPUCoordination
+
+	// GetUtilizationWindow returns the number of utilization samples
+	// averaged into the smoothed utilization
+	GetUtilizationWindow() int
+
+	// GetTemperatureWindow returns the number of temperature readings
+	// summarized into the control input
+	GetTemperatureWindow() int
+
+	// GetPowerWindow returns the number of power limits averaged into the
+	// power history
+	GetPowerWindow() int
+
+	// GetWarmupReads returns the number of temperature reads discarded at
+	// startup
+	GetWarmupReads() int
+
+	// GetFastPathThreshold returns the temperature jump between two ticks
+	// above which fan hysteresis is bypassed, 0 when disabled
+	GetFastPathThreshold() int
+
+	// GetMetricsMaxSize returns the size in MiB above which the oldest
+	// samples are evicted from the metrics database, 0 if unlimited
+	GetMetricsMaxSize() int
+
+	// GetMetricsRetentionDays returns how many days samples are kept in the
+	// metrics database, 0 to keep them forever
+	GetMetricsRetentionDays() int
+
+	// IsMetricsDestructiveMigrationEnabled returns whether samples that can't
+	// be migrated to a new schema version are dropped instead of failing
+	IsMetricsDestructiveMigrationEnabled() bool
+
+	// GetPreThrottleMargin returns how many degrees below the slowdown
+	// threshold power is lowered harder to avoid hardware throttling, 0 if
+	// disabled
+	GetPreThrottleMargin() int
+
+	// IsBannerEnabled returns whether the startup banner is printed, by
+	// default only when not running as a service
+	IsBannerEnabled() bool
+
+	// GetJitterTolerance returns how late a tick may be, in percent of the
+	// interval, before it is logged, 0 if disabled
+	GetJitterTolerance() int
+
+	// GetFanOverrun returns how long the fans keep the speed reached at the
+	// target temperature after the temperature drops below it, 0 if disabled
+	GetFanOverrun() time.Duration
+
+	// GetInitRetries returns how many times initializing the GPU is retried
+	// at startup before running degraded
+	GetInitRetries() int
+
+	// GetInitBackoff returns the wait before the first GPU initialization
+	// retry, doubled after each retry
+	GetInitBackoff() time.Duration
+
+	// GetNVMLReadRetries returns how many times a temperature, fan speed or
+	// power read failing with a transient NVML error is retried
+	GetNVMLReadRetries() int
+
+	// GetNVMLReadRetryDelay returns the wait between NVML read retries
+	GetNVMLReadRetryDelay() time.Duration
+
+	// GetDecisionHistory returns how many recent control decisions the
+	// status socket serves, 0 if disabled
+	GetDecisionHistory() int
+
+	// GetTemperatureSensorIndex returns the index of the NVML thermal sensor
+	// read for the control input, -1 for the core sensor
+	GetTemperatureSensorIndex() int
+
+	// GetTemperatureSource returns which sensor feeds the control input when
+	// no temperature blend is configured
+	GetTemperatureSource() TemperatureSource
+
+	// GetTemperatureBlend returns the normalized weight of each temperature
+	// sensor used for the control input, or nil if no blend is configured
+	GetTemperatureBlend() map[TemperatureSensor]float64
+
+	// GetProfiles returns the configured time-of-day profiles sorted by name,
+	// or nil if none is configured
+	GetProfiles() []Profile
+
+	// ActiveProfile returns the profile applying at the local time of day of
+	// now, false if none does and the base settings apply
+	ActiveProfile(now time.Time) (Profile, bool)
+
+	// GetFanReadFailureAction returns what to do when no fan speed can be read
+	GetFanReadFailureAction() FanReadFailureAction
+
+	// GetMaxConsecutiveSkips returns the number of consecutive ticks without a
+	// successful control action that escalate to the failsafe or abort, 0 if
+	// unlimited
+	GetMaxConsecutiveSkips() int
+
+	// GetFailsafeThreshold returns the number of consecutive control failures
+	// that engage the failsafe, 0 if the failsafe is disabled
+	GetFailsafeThreshold() int
+
+	// GetFailsafeRecovery returns how the failsafe is left once engaged
+	GetFailsafeRecovery() FailsafeRecovery
+
+	// GetRunAsUser returns the user to switch to after initialization, empty
+	// to keep running as the current user
+	GetRunAsUser() string
+
+	// GetNiceness returns the scheduling niceness of the daemon, from -20
+	// (highest priority) to 19 (lowest)
+	GetNiceness() int
+
+	// GetStatusSocket returns the path of the status socket, or an empty
+	// string if the status endpoint is disabled
+	GetStatusSocket() string
+
+	// GetLockFile returns the path of the lock file that keeps two
+	// controlling instances apart, or an empty string if disabled
+	GetLockFile() string
+
+	// IsConfigWatchEnabled returns whether changes to the configuration file
+	// are applied while running
+	IsConfigWatchEnabled() bool
+
+	// IsNVMLEventsEnabled returns whether NVML device events trigger an
+	// immediate control tick in addition to the regular interval
+	IsNVMLEventsEnabled() bool
+
+	// GetDevice returns the index or UUID of the GPU to control, or an empty
+	// string for the first GPU
+	GetDeviceSelector() string
 }
 
 // Loader handles the loading and validation of configuration from
@@ -45,6 +351,15 @@ type Loader interface {
 	// Validate checks if the current configuration is valid
 	// Returns nil if valid, error with validation details otherwise
 	Validate() error
+
+	// Reload re-reads the configuration file into a fresh provider and
+	// validates it. On error the previously loaded configuration stays in
+	// effect. Flags given at startup keep precedence over the file.
+	Reload(ctx context.Context) (Provider, error)
+
+	// ConfigFile returns the path of the loaded configuration file, or an
+	// empty string if running on defaults and flags only
+	ConfigFile() string
 }
 
 // Watcher enables live configuration updates
@@ -61,6 +376,8 @@ type Option func(*options) error
 type options struct {
 	configPath string
 	envPrefix  string
+	// args replaces the process arguments when set
+	args []string
 }
 
 // WithConfigFile specifies an explicit configuration file path
@@ -71,6 +388,19 @@ func WithConfigFile(path string) Option {
 	}
 }
 
+// WithArgs parses args as command-line flags instead of the process
+// arguments, e.g. for subcommands with flags of their own. Pass an empty
+// slice to ignore the command line.
+func WithArgs(args []string) Option {
+	return func(o *options) error {
+		if args == nil {
+			args = []string{}
+		}
+		o.args = args
+		return nil
+	}
+}
+
 // WithEnvPrefix specifies a custom environment variable prefix
 // Default is "NVIDIACTL"
 func WithEnvPrefix(prefix string) Option {
@@ -105,6 +435,385 @@ func (l LogLevel) String() string {
 	return string(l)
 }
 
+// LogFormat represents the log output formats
+type LogFormat string
+
+const (
+	// LogFormatConsole writes human readable lines
+	LogFormatConsole LogFormat = "console"
+	// LogFormatJSON writes one JSON object per line
+	LogFormatJSON LogFormat = "json"
+)
+
+// IsValid returns whether the log format is known
+func (f LogFormat) IsValid() bool {
+	switch f {
+	case LogFormatConsole, LogFormatJSON:
+		return true
+	default:
+		return false
+	}
+}
+
+// MinTemperature is the temperature in Celsius the fan curve starts at. It
+// isn't configurable, the target temperature must be above it.
+const MinTemperature = 50
+
+// TemperatureUnit represents the unit temperatures are configured and
+// reported in. Control always runs in Celsius.
+type TemperatureUnit string
+
+const (
+	// TemperatureUnitCelsius configures temperatures in degrees Celsius
+	TemperatureUnitCelsius TemperatureUnit = "C"
+	// TemperatureUnitFahrenheit configures temperatures in degrees Fahrenheit
+	TemperatureUnitFahrenheit TemperatureUnit = "F"
+)
+
+// IsValid returns whether the temperature unit is known
+func (u TemperatureUnit) IsValid() bool {
+	switch u {
+	case TemperatureUnitCelsius, TemperatureUnitFahrenheit:
+		return true
+	default:
+		return false
+	}
+}
+
+// ToCelsius converts a temperature in this unit to Celsius, rounded to the
+// nearest degree
+func (u TemperatureUnit) ToCelsius(temperature int) int {
+	if u != TemperatureUnitFahrenheit {
+		return temperature
+	}
+	return int(math.Round(float64(temperature-32) * 5 / 9))
+}
+
+// FromCelsius converts a temperature in Celsius to this unit, rounded to the
+// nearest degree
+func (u TemperatureUnit) FromCelsius(temperature int) int {
+	if u != TemperatureUnitFahrenheit {
+		return temperature
+	}
+	return int(math.Round(float64(temperature)*9/5 + 32))
+}
+
+// DeltaToCelsius converts a temperature difference in this unit to Celsius,
+// rounded to the nearest degree
+func (u TemperatureUnit) DeltaToCelsius(delta int) int {
+	if u != TemperatureUnitFahrenheit {
+		return delta
+	}
+	return int(math.Round(float64(delta) * 5 / 9))
+}
+
+// TemperatureSensor represents a temperature sensor that can feed the control input
+type TemperatureSensor string
+
+const (
+	SensorCore   TemperatureSensor = "core"
+	SensorMemory TemperatureSensor = "memory"
+)
+
+// IsValid returns whether the temperature sensor is known
+func (s TemperatureSensor) IsValid() bool {
+	switch s {
+	case SensorCore, SensorMemory:
+		return true
+	default:
+		return false
+	}
+}
+
+// FanReadFailureAction represents how the control loop reacts when no fan speed can be read
+type FanReadFailureAction string
+
+const (
+	// FanReadFailureProceed continues with the last known fan speeds
+	FanReadFailureProceed FanReadFailureAction = "proceed"
+	// FanReadFailureSkip skips the current tick without changing anything
+	FanReadFailureSkip FanReadFailureAction = "skip"
+	// FanReadFailureAuto hands fan control back to the driver
+	FanReadFailureAuto FanReadFailureAction = "auto"
+)
+
+// IsValid returns whether the fan read failure action is known
+func (a FanReadFailureAction) IsValid() bool {
+	switch a {
+	case FanReadFailureProceed, FanReadFailureSkip, FanReadFailureAuto:
+		return true
+	default:
+		return false
+	}
+}
+
+// MetricsSynchronous represents the SQLite synchronous mode
+type MetricsSynchronous string
+
+const (
+	// MetricsSynchronousOff leaves syncing to the OS, fastest but may lose or
+	// corrupt data on power loss
+	MetricsSynchronousOff MetricsSynchronous = "off"
+	// MetricsSynchronousNormal syncs at WAL checkpoints, may lose recent writes
+	MetricsSynchronousNormal MetricsSynchronous = "normal"
+	// MetricsSynchronousFull syncs every commit
+	MetricsSynchronousFull MetricsSynchronous = "full"
+)
+
+// IsValid returns whether the synchronous mode is known
+func (m MetricsSynchronous) IsValid() bool {
+	switch m {
+	case MetricsSynchronousOff, MetricsSynchronousNormal, MetricsSynchronousFull:
+		return true
+	default:
+		return false
+	}
+}
+
+// MetricsBackend names a metrics backend
+type MetricsBackend string
+
+const (
+	// MetricsBackendSQLite stores metrics in the SQLite database
+	MetricsBackendSQLite MetricsBackend = "sqlite"
+	// MetricsBackendStdout prints every snapshot as a JSON line, for debugging
+	MetricsBackendStdout MetricsBackend = "stdout"
+	// MetricsBackendTextfile writes the latest snapshot in the Prometheus
+	// text format for node_exporter's textfile collector
+	MetricsBackendTextfile MetricsBackend = "textfile"
+	// MetricsBackendInflux sends every snapshot in the InfluxDB line
+	// protocol to a Unix datagram socket
+	MetricsBackendInflux MetricsBackend = "influx"
+)
+
+// IsValid returns whether the metrics backend is known
+func (b MetricsBackend) IsValid() bool {
+	switch b {
+	case MetricsBackendSQLite, MetricsBackendStdout, MetricsBackendTextfile, MetricsBackendInflux:
+		return true
+	default:
+		return false
+	}
+}
+
+// MetricsJournalMode represents the SQLite journal mode
+type MetricsJournalMode string
+
+const (
+	// MetricsJournalWAL uses write-ahead logging, for local filesystems
+	MetricsJournalWAL MetricsJournalMode = "wal"
+	// MetricsJournalDelete uses a rollback journal without memory-mapped
+	// I/O, for network filesystems
+	MetricsJournalDelete MetricsJournalMode = "delete"
+)
+
+// IsValid returns whether the journal mode is known
+func (m MetricsJournalMode) IsValid() bool {
+	switch m {
+	case MetricsJournalWAL, MetricsJournalDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// InitialFanControl represents the fan control state at startup
+type InitialFanControl string
+
+const (
+	// InitialFanControlDetect keeps the state the card is in
+	InitialFanControlDetect InitialFanControl = "detect"
+	// InitialFanControlAuto hands the fans to the driver at startup
+	InitialFanControlAuto InitialFanControl = "auto"
+	// InitialFanControlManual takes manual control of the fans at startup
+	InitialFanControlManual InitialFanControl = "manual"
+)
+
+// IsValid returns whether the initial fan control state is known
+func (c InitialFanControl) IsValid() bool {
+	switch c {
+	case InitialFanControlDetect, InitialFanControlAuto, InitialFanControlManual:
+		return true
+	default:
+		return false
+	}
+}
+
+// CurvePoint maps a temperature in Celsius to a value, such as a fan speed
+// percentage
+type CurvePoint struct {
+	Temperature int `json:"temperature"`
+	Value       int `json:"value"`
+}
+
+// Fan strategies known to the config. Strategies registered with the
+// control package are selected by their own name.
+const (
+	// FanStrategyAuto uses steps when fan_steps is set and curve otherwise
+	FanStrategyAuto  = "auto"
+	FanStrategyCurve = "curve"
+	FanStrategySteps = "steps"
+)
+
+// PIDGains are the gains of the PID fan strategy, the integral and derivative
+// gains are per second
+type PIDGains struct {
+	Proportional float64 `json:"proportional"`
+	Integral     float64 `json:"integral"`
+	Derivative   float64 `json:"derivative"`
+}
+
+// TemperatureStatistic represents how the temperature window is summarized
+type TemperatureStatistic string
+
+const (
+	// StatisticMean uses the mean of the window
+	StatisticMean TemperatureStatistic = "mean"
+	// StatisticMax uses the hottest reading of the window
+	StatisticMax TemperatureStatistic = "max"
+	// StatisticP90 uses the 90th percentile of the window
+	StatisticP90 TemperatureStatistic = "p90"
+)
+
+// TemperatureSmoothing represents how temperature readings are smoothed
+type TemperatureSmoothing string
+
+const (
+	// SmoothingSMA uses a simple moving average over the window, summarized
+	// with the temperature statistic
+	SmoothingSMA TemperatureSmoothing = "sma"
+	// SmoothingEMA uses an exponential moving average weighted by ema_alpha
+	SmoothingEMA TemperatureSmoothing = "ema"
+)
+
+// IsValid returns whether the temperature smoothing is known
+func (s TemperatureSmoothing) IsValid() bool {
+	switch s {
+	case SmoothingSMA, SmoothingEMA:
+		return true
+	default:
+		return false
+	}
+}
+
+// TemperatureFilter represents how outlier temperature readings are handled
+type TemperatureFilter string
+
+const (
+	// TemperatureFilterNone uses every reading
+	TemperatureFilterNone TemperatureFilter = "none"
+	// TemperatureFilterMedian replaces readings deviating more than
+	// temperature_filter_delta from the average with the window median
+	TemperatureFilterMedian TemperatureFilter = "median"
+)
+
+// IsValid returns whether the temperature filter is known
+func (f TemperatureFilter) IsValid() bool {
+	switch f {
+	case TemperatureFilterNone, TemperatureFilterMedian:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValid returns whether the temperature statistic is known
+func (s TemperatureStatistic) IsValid() bool {
+	switch s {
+	case StatisticMean, StatisticMax, StatisticP90:
+		return true
+	default:
+		return false
+	}
+}
+
+// CoolingPriority represents which actuator cools the GPU first
+type CoolingPriority string
+
+const (
+	// CoolWithBoth controls fans and power independently
+	CoolWithBoth CoolingPriority = "both"
+	// CoolWithFans raises fans and never lowers power
+	CoolWithFans CoolingPriority = "fans"
+	// CoolWithPower lowers power before raising fans
+	CoolWithPower CoolingPriority = "power"
+)
+
+// IsValid returns whether the cooling priority is known
+func (p CoolingPriority) IsValid() bool {
+	switch p {
+	case CoolWithBoth, CoolWithFans, CoolWithPower:
+		return true
+	default:
+		return false
+	}
+}
+
+// GPUCoordination represents how the temperatures of other GPUs are used
+type GPUCoordination string
+
+const (
+	// GPUCoordinationNone only reads the controlled GPU
+	GPUCoordinationNone GPUCoordination = "none"
+	// GPUCoordinationObserve reads all GPUs and reports their aggregate
+	// without affecting control
+	GPUCoordinationObserve GPUCoordination = "observe"
+	// GPUCoordinationHottest controls on the hottest GPU in the system
+	GPUCoordinationHottest GPUCoordination = "hottest"
+)
+
+// TemperatureSource represents which sensor feeds the control input
+type TemperatureSource string
+
+const (
+	// TemperatureSourceCore reads the core sensor
+	TemperatureSourceCore TemperatureSource = "core"
+	// TemperatureSourceMemory reads the memory junction sensor
+	TemperatureSourceMemory TemperatureSource = "memory"
+	// TemperatureSourceMax uses the hotter of the core and memory sensors
+	TemperatureSourceMax TemperatureSource = "max"
+)
+
+// IsValid returns whether the temperature source is known
+func (s TemperatureSource) IsValid() bool {
+	switch s {
+	case TemperatureSourceCore, TemperatureSourceMemory, TemperatureSourceMax:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValid returns whether the GPU coordination mode is known
+func (c GPUCoordination) IsValid() bool {
+	switch c {
+	case GPUCoordinationNone, GPUCoordinationObserve, GPUCoordinationHottest:
+		return true
+	default:
+		return false
+	}
+}
+
+// FailsafeRecovery represents how control resumes after the failsafe engaged
+type FailsafeRecovery string
+
+const (
+	// FailsafeRecoveryManual stays in failsafe until SIGUSR1 or a restart
+	FailsafeRecoveryManual FailsafeRecovery = "manual"
+	// FailsafeRecoveryAuto resumes control once reads succeed again
+	FailsafeRecoveryAuto FailsafeRecovery = "auto"
+)
+
+// IsValid returns whether the failsafe recovery mode is known
+func (r FailsafeRecovery) IsValid() bool {
+	switch r {
+	case FailsafeRecoveryManual, FailsafeRecoveryAuto:
+		return true
+	default:
+		return false
+	}
+}
+
 // ValidationError represents a configuration validation error
 type ValidationError interface {
 	error
@@ -0,0 +1,38 @@
+package config
+
+import "testing"
+
+func TestTemperatureUnitConversion(t *testing.T) {
+	tests := []struct {
+		name        string
+		unit        TemperatureUnit
+		value       int
+		toCelsius   int
+		fromCelsius int
+		delta       int
+	}{
+		{name: "celsius", unit: TemperatureUnitCelsius, value: 80, toCelsius: 80, fromCelsius: 80, delta: 80},
+		{name: "freezing", unit: TemperatureUnitFahrenheit, value: 32, toCelsius: 0, fromCelsius: 90, delta: 18},
+		{name: "boiling", unit: TemperatureUnitFahrenheit, value: 212, toCelsius: 100, fromCelsius: 414, delta: 118},
+		{name: "rounds down", unit: TemperatureUnitFahrenheit, value: 176, toCelsius: 80, fromCelsius: 349, delta: 98},
+		// 177°F is 80.56°C, 1°C is 33.8°F
+		{name: "rounds up", unit: TemperatureUnitFahrenheit, value: 177, toCelsius: 81, fromCelsius: 351, delta: 98},
+		{name: "one degree", unit: TemperatureUnitFahrenheit, value: 1, toCelsius: -17, fromCelsius: 34, delta: 1},
+		{name: "zero", unit: TemperatureUnitFahrenheit, value: 0, toCelsius: -18, fromCelsius: 32, delta: 0},
+		{name: "negative", unit: TemperatureUnitFahrenheit, value: -40, toCelsius: -40, fromCelsius: -40, delta: -22},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.unit.ToCelsius(tt.value); got != tt.toCelsius {
+				t.Errorf("%s.ToCelsius(%d) = %d, want %d", tt.unit, tt.value, got, tt.toCelsius)
+			}
+			if got := tt.unit.FromCelsius(tt.value); got != tt.fromCelsius {
+				t.Errorf("%s.FromCelsius(%d) = %d, want %d", tt.unit, tt.value, got, tt.fromCelsius)
+			}
+			if got := tt.unit.DeltaToCelsius(tt.value); got != tt.delta {
+				t.Errorf("%s.DeltaToCelsius(%d) = %d, want %d", tt.unit, tt.value, got, tt.delta)
+			}
+		})
+	}
+}
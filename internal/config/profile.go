@@ -0,0 +1,203 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"github.com/spf13/viper"
+)
+
+// profileTimeLayout is the layout of the start and end time of a profile
+const profileTimeLayout = "15:04"
+
+// day is the length of the daily profile schedule
+const day = 24 * time.Hour
+
+// Profile overrides settings during a daily time range. A range ending before
+// it starts wraps past midnight.
+type Profile struct {
+	Name string
+	// Start and End are the time of day the profile applies from and until,
+	// as an offset from midnight
+	Start time.Duration
+	End   time.Duration
+	// FanSpeed overrides fanspeed, 0 if not overridden
+	FanSpeed int
+	// Temperature overrides temperature in Celsius, 0 if not overridden
+	Temperature int
+}
+
+// Contains returns whether the local time of day of now is in the profile's
+// range. The end is exclusive.
+func (p Profile) Contains(now time.Time) bool {
+	since := p.sinceStart(now)
+	return since < (p.End-p.Start+day)%day
+}
+
+// sinceStart returns how long ago the profile last started, before now
+func (p Profile) sinceStart(now time.Time) time.Duration {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return (now.Sub(midnight) - p.Start + day) % day
+}
+
+// activeProfile returns the profile applying at now. Of overlapping profiles
+// the one started last wins, so a short profile inside a longer one takes
+// over for its range. Ties go to the first profile by name.
+func activeProfile(profiles []Profile, now time.Time) (Profile, bool) {
+	var active Profile
+	found := false
+	for _, profile := range profiles {
+		if !profile.Contains(now) {
+			continue
+		}
+		if !found || profile.sinceStart(now) < active.sinceStart(now) {
+			active = profile
+			found = true
+		}
+	}
+	return active, found
+}
+
+// parseProfiles reads the profiles tables, sorted by name. Temperatures are
+// converted from temperature_unit to Celsius. Returns nil if no profile is
+// configured.
+func parseProfiles(v *viper.Viper) ([]Profile, error) {
+	errFactory := errors.New()
+
+	raw := v.GetStringMap("profiles")
+	if len(raw) == 0 {
+		return nil, nil
+	}
+
+	unit := temperatureUnit(v)
+	profiles := make([]Profile, 0, len(raw))
+	for name, value := range raw {
+		field := "profiles." + name
+
+		table, ok := value.(map[string]any)
+		if !ok {
+			return nil, errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field  string
+				Reason string
+			}{
+				Field:  field,
+				Reason: "must be a table",
+			})
+		}
+
+		profile := Profile{Name: name}
+		var hasStart, hasEnd bool
+		for key, setting := range table {
+			var err error
+			switch key {
+			case "start":
+				profile.Start, err = parseTimeOfDay(field, key, setting)
+				hasStart = true
+			case "end":
+				profile.End, err = parseTimeOfDay(field, key, setting)
+				hasEnd = true
+			case "fanspeed":
+				profile.FanSpeed, err = parseProfileInt(field, key, setting, 1, 100)
+			case "temperature":
+				profile.Temperature, err = parseProfileInt(field, key, setting, 1, 0)
+				profile.Temperature = unit.ToCelsius(profile.Temperature)
+				if err == nil && unit != TemperatureUnitCelsius && profile.Temperature <= MinTemperature {
+					err = errFactory.WithData(errors.ErrInvalidConfig, struct {
+						Field   string
+						Key     string
+						Value   int
+						Minimum int
+					}{
+						Field:   field,
+						Key:     key,
+						Value:   profile.Temperature,
+						Minimum: MinTemperature + 1,
+					})
+				}
+			default:
+				err = errFactory.WithData(errors.ErrInvalidConfig, struct {
+					Field string
+					Key   string
+				}{
+					Field: field,
+					Key:   key,
+				})
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if !hasStart || !hasEnd || profile.Start == profile.End {
+			return nil, errFactory.WithData(errors.ErrInvalidConfig, struct {
+				Field  string
+				Reason string
+			}{
+				Field:  field,
+				Reason: "start and end must be set and differ",
+			})
+		}
+
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+
+	return profiles, nil
+}
+
+// parseTimeOfDay reads a "15:04" time of a profile as an offset from midnight
+func parseTimeOfDay(field, key string, value any) (time.Duration, error) {
+	errFactory := errors.New()
+
+	s, _ := value.(string)
+	t, err := time.Parse(profileTimeLayout, strings.TrimSpace(s))
+	if err != nil {
+		return 0, errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Key   string
+			Value any
+		}{
+			Field: field,
+			Key:   key,
+			Value: value,
+		})
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// parseProfileInt reads an integer setting of a profile, at least minimum
+// and, unless maximum is 0, at most maximum
+func parseProfileInt(field, key string, value any, minimum, maximum int) (int, error) {
+	errFactory := errors.New()
+
+	var n int
+	valid := true
+	switch v := value.(type) {
+	case int:
+		n = v
+	case int64:
+		n = int(v)
+	default:
+		valid = false
+	}
+
+	if !valid || n < minimum || (maximum > 0 && n > maximum) {
+		return 0, errFactory.WithData(errors.ErrInvalidConfig, struct {
+			Field string
+			Key   string
+			Value any
+		}{
+			Field: field,
+			Key:   key,
+			Value: value,
+		})
+	}
+
+	return n, nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActiveProfile(t *testing.T) {
+	night := Profile{Name: "night", Start: 22 * time.Hour, End: 7 * time.Hour}
+	evening := Profile{Name: "evening", Start: 18 * time.Hour, End: 23 * time.Hour}
+	lateNight := Profile{Name: "late", Start: time.Hour, End: 3 * time.Hour}
+	work := Profile{Name: "work", Start: 9 * time.Hour, End: 17*time.Hour + 30*time.Minute}
+
+	at := func(hour, minute int) time.Time {
+		return time.Date(2026, time.March, 14, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name     string
+		profiles []Profile
+		now      time.Time
+		want     string
+	}{
+		{name: "none configured", now: at(12, 0), want: ""},
+		{name: "before the range", profiles: []Profile{work}, now: at(8, 59), want: ""},
+		{name: "at the start", profiles: []Profile{work}, now: at(9, 0), want: "work"},
+		{name: "inside the range", profiles: []Profile{work}, now: at(17, 29), want: "work"},
+		{name: "end is exclusive", profiles: []Profile{work}, now: at(17, 30), want: ""},
+		{name: "wrapping, before midnight", profiles: []Profile{night}, now: at(23, 30), want: "night"},
+		{name: "wrapping, at midnight", profiles: []Profile{night}, now: at(0, 0), want: "night"},
+		{name: "wrapping, after midnight", profiles: []Profile{night}, now: at(6, 59), want: "night"},
+		{name: "wrapping, end is exclusive", profiles: []Profile{night}, now: at(7, 0), want: ""},
+		{name: "wrapping, outside", profiles: []Profile{night}, now: at(12, 0), want: ""},
+		{
+			name:     "overlap goes to the profile started last",
+			profiles: []Profile{evening, night},
+			now:      at(22, 30),
+			want:     "night",
+		},
+		{
+			name:     "earlier profile before the overlap",
+			profiles: []Profile{evening, night},
+			now:      at(21, 0),
+			want:     "evening",
+		},
+		{
+			name:     "later profile after the overlap",
+			profiles: []Profile{evening, night},
+			now:      at(23, 0),
+			want:     "night",
+		},
+		{
+			name:     "profile inside a wrapping one after midnight",
+			profiles: []Profile{lateNight, night},
+			now:      at(2, 0),
+			want:     "late",
+		},
+		{
+			name:     "wrapping one resumes after the inner profile",
+			profiles: []Profile{lateNight, night},
+			now:      at(3, 0),
+			want:     "night",
+		},
+		{
+			name: "tie goes to the first profile",
+			profiles: []Profile{
+				{Name: "a", Start: 22 * time.Hour, End: 6 * time.Hour},
+				{Name: "b", Start: 22 * time.Hour, End: 5 * time.Hour},
+			},
+			now:  at(1, 0),
+			want: "a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, active := activeProfile(tt.profiles, tt.now)
+			if active != (tt.want != "") || got.Name != tt.want {
+				t.Errorf("activeProfile() at %s = %q (active %v), want %q",
+					tt.now.Format(profileTimeLayout), got.Name, active, tt.want)
+			}
+		})
+	}
+}
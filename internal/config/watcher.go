@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+const defaultWatchInterval = 2 * time.Second
+
+// fileWatcher implements Watcher by polling the config file for changes
+type fileWatcher struct {
+	loader   Loader
+	interval time.Duration
+}
+
+// NewWatcher creates a watcher that reloads the loader's config file when it
+// changes. A zero interval polls every two seconds.
+func NewWatcher(loader Loader, interval time.Duration) Watcher {
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	return &fileWatcher{loader: loader, interval: interval}
+}
+
+// Watch blocks until ctx is canceled, calling callback with every
+// successfully reloaded configuration. Reloads that fail to parse or validate
+// are logged and the running configuration is kept.
+func (w *fileWatcher) Watch(ctx context.Context, callback func(Provider)) error {
+	errFactory := errors.New()
+
+	path := w.loader.ConfigFile()
+	if path == "" {
+		return errFactory.New(errors.ErrMissingConfig)
+	}
+
+	last, _ := os.Stat(path)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			// Editors often replace the file, it may briefly not exist
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		cfg, err := w.loader.Reload(ctx)
+		if err != nil {
+			var domainErr errors.Error
+			if !errors.As(err, &domainErr) {
+				domainErr = errFactory.Wrap(errors.ErrReloadConfig, err)
+			}
+			logger.ErrorWithCode(domainErr).Str("file", path).Msg("Ignoring config file change")
+			continue
+		}
+
+		callback(cfg)
+	}
+}
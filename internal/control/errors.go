@@ -0,0 +1,12 @@
+package control
+
+import "codeberg.org/mutker/nvidiactl/internal/errors"
+
+const (
+	ErrUnknownPriority = errors.ErrorCode("control_unknown_priority")
+	ErrUnknownStrategy = errors.ErrorCode("control_unknown_fan_strategy")
+	ErrInvalidStrategy = errors.ErrorCode("control_invalid_fan_strategy")
+	ErrStrategyExists  = errors.ErrorCode("control_fan_strategy_exists")
+	ErrNoSteps         = errors.ErrorCode("control_no_fan_steps")
+	ErrInvalidGains    = errors.ErrorCode("control_invalid_pid_gains")
+)
@@ -0,0 +1,79 @@
+package control
+
+import "time"
+
+// Policy coordinates the independently calculated fan and power targets
+type Policy interface {
+	// Coordinate adjusts the proposed targets according to the cooling priority
+	Coordinate(in Input) Targets
+	// LowersPowerFirst reports whether power may be lowered before fans are at
+	// their maximum speed
+	LowersPowerFirst() bool
+}
+
+// FanStrategy calculates the fan speed target from the control temperature.
+// Strategies may keep state between ticks, Reset clears it, e.g. after a
+// system resume or a config reload.
+type FanStrategy interface {
+	Compute(in ControlInput) FanSpeed
+	Reset()
+}
+
+// Priority selects which actuator is used first when above target temperature
+type Priority string
+
+const (
+	// PriorityBoth controls fans and power independently
+	PriorityBoth Priority = "both"
+	// PriorityFans raises fans and never lowers power, favoring performance
+	PriorityFans Priority = "fans"
+	// PriorityPower lowers power before raising fans, favoring low noise
+	PriorityPower Priority = "power"
+)
+
+type (
+	// Input is the current GPU state together with the proposed targets
+	Input struct {
+		Temperature       int
+		TargetTemperature int
+		CurrentFanSpeed   int
+		FanSpeed          int
+		CurrentPowerLimit int
+		PowerLimit        int
+		MinPowerLimit     int
+	}
+
+	// ControlInput is what a fan strategy calculates the fan speed from
+	ControlInput struct {
+		// Temperature is the averaged control temperature
+		Temperature int
+		// MinTemperature is where fan control starts, MaxTemperature the
+		// target temperature
+		MinTemperature int
+		MaxTemperature int
+		// MinFanSpeed and MaxFanSpeed bound the result, with the fan floor
+		// and caps already applied
+		MinFanSpeed int
+		MaxFanSpeed int
+		Hysteresis  int
+		// FanStep is the multiple continuous strategies round to
+		FanStep     int
+		Performance bool
+		// Interval is the time between ticks
+		Interval time.Duration
+	}
+
+	// FanSpeed is the result of a fan strategy
+	FanSpeed struct {
+		Percent int
+		// CurvePosition is how far along its range the strategy is, from 0
+		// at the minimum to 1 at the maximum fan speed. Only logged.
+		CurvePosition float64
+	}
+
+	// Targets are the coordinated fan speed and power limit
+	Targets struct {
+		FanSpeed   int
+		PowerLimit int
+	}
+)
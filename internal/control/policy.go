@@ -0,0 +1,44 @@
+package control
+
+import "codeberg.org/mutker/nvidiactl/internal/errors"
+
+type policy struct {
+	priority Priority
+}
+
+// NewPolicy creates a cooling policy for the given priority
+func NewPolicy(priority Priority) (Policy, error) {
+	errFactory := errors.New()
+
+	switch priority {
+	case PriorityBoth, PriorityFans, PriorityPower:
+		return &policy{priority: priority}, nil
+	default:
+		return nil, errFactory.WithData(ErrUnknownPriority, priority)
+	}
+}
+
+func (p *policy) Coordinate(in Input) Targets {
+	targets := Targets{
+		FanSpeed:   in.FanSpeed,
+		PowerLimit: in.PowerLimit,
+	}
+
+	switch p.priority {
+	case PriorityFans:
+		// Power is only ever raised back, the fans do the cooling
+		targets.PowerLimit = max(in.PowerLimit, in.CurrentPowerLimit)
+	case PriorityPower:
+		// Hold the fans while there is still power headroom to give up
+		if in.Temperature > in.TargetTemperature && in.CurrentPowerLimit > in.MinPowerLimit {
+			targets.FanSpeed = min(in.FanSpeed, in.CurrentFanSpeed)
+		}
+	case PriorityBoth:
+	}
+
+	return targets
+}
+
+func (p *policy) LowersPowerFirst() bool {
+	return p.priority == PriorityPower
+}
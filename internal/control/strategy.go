@@ -0,0 +1,332 @@
+package control
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+// Built-in fan strategies
+const (
+	StrategyCurve = "curve"
+	StrategySteps = "steps"
+	StrategyFixed = "fixed"
+	StrategyPID   = "pid"
+)
+
+const (
+	performancePowFactor = 1.5
+	normalPowFactor      = 2.0
+)
+
+type (
+	// Step is a fan speed used from a temperature threshold on
+	Step struct {
+		Temperature int
+		FanSpeed    int
+	}
+
+	// PIDGains are the gains of the PID strategy, the integral and
+	// derivative gains are per second
+	PIDGains struct {
+		Proportional float64
+		Integral     float64
+		Derivative   float64
+	}
+
+	// StrategyConfig holds the settings of the built-in strategies, each
+	// only reads the fields it needs
+	StrategyConfig struct {
+		Steps []Step
+		// Curve replaces the power curve of the curve strategy with points
+		// it interpolates between, sorted by temperature
+		Curve      []Step
+		FixedSpeed int
+		Gains      PIDGains
+	}
+
+	// FanStrategyFactory creates a fan strategy from its settings
+	FanStrategyFactory func(cfg StrategyConfig) (FanStrategy, error)
+)
+
+var (
+	strategiesMu sync.RWMutex
+	strategies   = map[string]FanStrategyFactory{
+		StrategyCurve: newCurveStrategy,
+		StrategySteps: newStepsStrategy,
+		StrategyFixed: newFixedStrategy,
+		StrategyPID:   newPIDStrategy,
+	}
+)
+
+// RegisterFanStrategy makes a fan strategy selectable by name. Built-in
+// strategies can't be replaced.
+func RegisterFanStrategy(name string, factory FanStrategyFactory) error {
+	errFactory := errors.New()
+
+	strategiesMu.Lock()
+	defer strategiesMu.Unlock()
+
+	if _, exists := strategies[name]; exists || name == "" || factory == nil {
+		return errFactory.WithData(ErrStrategyExists, name)
+	}
+	strategies[name] = factory
+
+	return nil
+}
+
+// FanStrategies returns the names of all selectable fan strategies
+func FanStrategies() []string {
+	strategiesMu.RLock()
+	defer strategiesMu.RUnlock()
+
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewFanStrategy creates the fan strategy registered under name
+func NewFanStrategy(name string, cfg StrategyConfig) (FanStrategy, error) {
+	errFactory := errors.New()
+
+	strategiesMu.RLock()
+	factory, ok := strategies[name]
+	strategiesMu.RUnlock()
+
+	if !ok {
+		return nil, errFactory.WithData(ErrUnknownStrategy, struct {
+			Strategy  string
+			Available []string
+		}{
+			Strategy:  name,
+			Available: FanStrategies(),
+		})
+	}
+
+	strategy, err := factory(cfg)
+	if err != nil {
+		return nil, errFactory.Wrap(ErrInvalidStrategy, err)
+	}
+
+	return strategy, nil
+}
+
+// curveStrategy follows a power curve from the minimum fan speed at the
+// minimum temperature to the maximum at the target temperature. Performance
+// mode uses a steeper curve. With configured points it interpolates linearly
+// between them instead.
+type curveStrategy struct {
+	points []Step
+}
+
+func newCurveStrategy(cfg StrategyConfig) (FanStrategy, error) {
+	points := append([]Step(nil), cfg.Curve...)
+	sort.Slice(points, func(i, j int) bool { return points[i].Temperature < points[j].Temperature })
+
+	return curveStrategy{points: points}, nil
+}
+
+func (s curveStrategy) Compute(in ControlInput) FanSpeed {
+	if len(s.points) > 0 {
+		return s.interpolate(in)
+	}
+
+	if in.Temperature <= in.MinTemperature {
+		return FanSpeed{Percent: in.MinFanSpeed}
+	}
+	if in.Temperature >= in.MaxTemperature {
+		return FanSpeed{Percent: in.MaxFanSpeed, CurvePosition: 1}
+	}
+
+	position := float64(in.Temperature-in.MinTemperature) / float64(in.MaxTemperature-in.MinTemperature)
+
+	factor := normalPowFactor
+	if in.Performance {
+		factor = performancePowFactor
+	}
+
+	speed := int(float64(in.MaxFanSpeed-in.MinFanSpeed)*math.Pow(position, factor)) + in.MinFanSpeed
+
+	return FanSpeed{
+		Percent:       clamp(quantize(speed, in.FanStep), in.MinFanSpeed, in.MaxFanSpeed),
+		CurvePosition: position,
+	}
+}
+
+// interpolate returns the speed between the points surrounding the
+// temperature. Below the first and above the last point the curve is flat.
+func (s curveStrategy) interpolate(in ControlInput) FanSpeed {
+	first, last := s.points[0], s.points[len(s.points)-1]
+
+	speed := float64(first.FanSpeed)
+	switch {
+	case in.Temperature >= last.Temperature:
+		speed = float64(last.FanSpeed)
+	case in.Temperature > first.Temperature:
+		for i := 1; i < len(s.points); i++ {
+			lower, upper := s.points[i-1], s.points[i]
+			if in.Temperature > upper.Temperature {
+				continue
+			}
+			position := float64(in.Temperature-lower.Temperature) / float64(upper.Temperature-lower.Temperature)
+			speed = float64(lower.FanSpeed) + position*float64(upper.FanSpeed-lower.FanSpeed)
+			break
+		}
+	}
+
+	var position float64
+	if last.Temperature > first.Temperature {
+		position = float64(in.Temperature-first.Temperature) / float64(last.Temperature-first.Temperature)
+	}
+
+	return FanSpeed{
+		Percent:       clamp(quantize(int(math.Round(speed)), in.FanStep), in.MinFanSpeed, in.MaxFanSpeed),
+		CurvePosition: math.Max(0, math.Min(position, 1)),
+	}
+}
+
+func (curveStrategy) Reset() {}
+
+// stepsStrategy uses the speed of the highest step whose threshold the
+// temperature reached. Stepping down requires the temperature to fall
+// hysteresis degrees below the threshold of the current step, so it doesn't
+// flap at a boundary.
+type stepsStrategy struct {
+	steps []Step
+	index int
+}
+
+func newStepsStrategy(cfg StrategyConfig) (FanStrategy, error) {
+	errFactory := errors.New()
+
+	if len(cfg.Steps) == 0 {
+		return nil, errFactory.New(ErrNoSteps)
+	}
+
+	steps := append([]Step(nil), cfg.Steps...)
+	sort.Slice(steps, func(i, j int) bool { return steps[i].Temperature < steps[j].Temperature })
+
+	return &stepsStrategy{steps: steps, index: -1}, nil
+}
+
+func (s *stepsStrategy) Compute(in ControlInput) FanSpeed {
+	index := -1
+	for i, step := range s.steps {
+		if in.Temperature >= step.Temperature {
+			index = i
+		}
+	}
+
+	if index < s.index && s.index < len(s.steps) &&
+		in.Temperature > s.steps[s.index].Temperature-in.Hysteresis {
+		index = s.index
+	}
+	s.index = index
+
+	if index < 0 {
+		return FanSpeed{Percent: in.MinFanSpeed}
+	}
+
+	return FanSpeed{Percent: clamp(s.steps[index].FanSpeed, in.MinFanSpeed, in.MaxFanSpeed)}
+}
+
+func (s *stepsStrategy) Reset() {
+	s.index = -1
+}
+
+// fixedStrategy holds a constant fan speed
+type fixedStrategy struct {
+	speed int
+}
+
+func newFixedStrategy(cfg StrategyConfig) (FanStrategy, error) {
+	return fixedStrategy{speed: cfg.FixedSpeed}, nil
+}
+
+func (s fixedStrategy) Compute(in ControlInput) FanSpeed {
+	return FanSpeed{Percent: clamp(s.speed, in.MinFanSpeed, in.MaxFanSpeed)}
+}
+
+func (fixedStrategy) Reset() {}
+
+// pidStrategy holds the temperature at the target with the lowest fan speed
+// that manages it. The integral is only accumulated while the output isn't
+// saturated, so it doesn't wind up during long stretches below the target.
+type pidStrategy struct {
+	gains     PIDGains
+	integral  float64
+	lastError float64
+	primed    bool
+}
+
+func newPIDStrategy(cfg StrategyConfig) (FanStrategy, error) {
+	errFactory := errors.New()
+
+	gains := cfg.Gains
+	if gains.Proportional < 0 || gains.Integral < 0 || gains.Derivative < 0 {
+		return nil, errFactory.WithData(ErrInvalidGains, gains)
+	}
+
+	return &pidStrategy{gains: gains}, nil
+}
+
+func (s *pidStrategy) Compute(in ControlInput) FanSpeed {
+	seconds := in.Interval.Seconds()
+	errorValue := float64(in.Temperature - in.MaxTemperature)
+
+	derivative := 0.0
+	if s.primed && seconds > 0 {
+		derivative = (errorValue - s.lastError) / seconds
+	}
+	s.lastError = errorValue
+	s.primed = true
+
+	integral := s.integral + errorValue*seconds
+	output := float64(in.MinFanSpeed) +
+		s.gains.Proportional*errorValue +
+		s.gains.Integral*integral +
+		s.gains.Derivative*derivative
+
+	saturatedHigh := output >= float64(in.MaxFanSpeed) && errorValue > 0
+	saturatedLow := output <= float64(in.MinFanSpeed) && errorValue < 0
+	if !saturatedHigh && !saturatedLow {
+		s.integral = integral
+	}
+
+	speed := int(math.Round(output))
+
+	position := 0.0
+	if span := in.MaxFanSpeed - in.MinFanSpeed; span > 0 {
+		position = math.Max(0, math.Min(1, (output-float64(in.MinFanSpeed))/float64(span)))
+	}
+
+	return FanSpeed{
+		Percent:       clamp(quantize(speed, in.FanStep), in.MinFanSpeed, in.MaxFanSpeed),
+		CurvePosition: position,
+	}
+}
+
+func (s *pidStrategy) Reset() {
+	s.integral = 0
+	s.lastError = 0
+	s.primed = false
+}
+
+// quantize rounds value to the nearest multiple of step
+func quantize(value, step int) int {
+	if step <= 1 {
+		return value
+	}
+
+	return int(math.Round(float64(value)/float64(step))) * step
+}
+
+func clamp(value, minValue, maxValue int) int {
+	return max(minValue, min(value, maxValue))
+}
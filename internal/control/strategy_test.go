@@ -0,0 +1,282 @@
+package control
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+// input returns the control input at temperature for a 50-80°C range and
+// fans between 30% and 100%
+func input(temperature int) ControlInput {
+	return ControlInput{
+		Temperature:    temperature,
+		MinTemperature: 50,
+		MaxTemperature: 80,
+		MinFanSpeed:    30,
+		MaxFanSpeed:    100,
+		Hysteresis:     3,
+		Interval:       2 * time.Second,
+	}
+}
+
+func newStrategy(t *testing.T, name string, cfg StrategyConfig) FanStrategy {
+	t.Helper()
+
+	strategy, err := NewFanStrategy(name, cfg)
+	if err != nil {
+		t.Fatalf("NewFanStrategy(%q) unexpected error: %v", name, err)
+	}
+	return strategy
+}
+
+func TestCurveStrategy(t *testing.T) {
+	performance := input(65)
+	performance.Performance = true
+	stepped := input(65)
+	stepped.FanStep = 5
+	capped := input(90)
+	capped.MaxFanSpeed = 80
+
+	tests := []struct {
+		name     string
+		in       ControlInput
+		want     int
+		position float64
+	}{
+		{name: "below the minimum temperature", in: input(40), want: 30},
+		{name: "at the minimum temperature", in: input(50), want: 30},
+		{name: "halfway", in: input(65), want: 47, position: 0.5},
+		{name: "halfway in performance mode", in: performance, want: 54, position: 0.5},
+		{name: "halfway rounded to the fan step", in: stepped, want: 45, position: 0.5},
+		{name: "near the target", in: input(74), want: 74, position: 0.8},
+		{name: "at the target", in: input(80), want: 100, position: 1},
+		{name: "above the target", in: input(90), want: 100, position: 1},
+		{name: "above the target, capped", in: capped, want: 80, position: 1},
+	}
+
+	strategy := newStrategy(t, StrategyCurve, StrategyConfig{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strategy.Compute(tt.in)
+			if got.Percent != tt.want || got.CurvePosition != tt.position {
+				t.Errorf("Compute() at %d°C = %d%% at %v, want %d%% at %v",
+					tt.in.Temperature, got.Percent, got.CurvePosition, tt.want, tt.position)
+			}
+		})
+	}
+}
+
+func TestStepsStrategy(t *testing.T) {
+	strategy := newStrategy(t, StrategySteps, StrategyConfig{
+		// Out of order, the strategy sorts them
+		Steps: []Step{{Temperature: 80, FanSpeed: 100}, {Temperature: 60, FanSpeed: 40}, {Temperature: 70, FanSpeed: 60}},
+	})
+
+	// Each tick depends on the step the previous one ended at
+	ticks := []struct {
+		name        string
+		temperature int
+		want        int
+	}{
+		{name: "below the first step", temperature: 55, want: 30},
+		{name: "first step", temperature: 60, want: 40},
+		{name: "second step", temperature: 72, want: 60},
+		{name: "held within the hysteresis", temperature: 68, want: 60},
+		{name: "stepped down past the hysteresis", temperature: 67, want: 40},
+		{name: "up several steps at once", temperature: 85, want: 100},
+		{name: "down several steps at once", temperature: 55, want: 30},
+	}
+	for _, tick := range ticks {
+		if got := strategy.Compute(input(tick.temperature)).Percent; got != tick.want {
+			t.Errorf("%s: Compute() at %d°C = %d%%, want %d%%", tick.name, tick.temperature, got, tick.want)
+		}
+	}
+
+	strategy.Compute(input(72))
+	strategy.Reset()
+	if got := strategy.Compute(input(68)).Percent; got != 40 {
+		t.Errorf("Compute() at 68°C after Reset() = %d%%, want 40%% without the hysteresis of the old step", got)
+	}
+
+	capped := input(85)
+	capped.MaxFanSpeed = 90
+	if got := strategy.Compute(capped).Percent; got != 90 {
+		t.Errorf("Compute() at 85°C with fans capped at 90%% = %d%%, want 90%%", got)
+	}
+}
+
+func TestStepsStrategyRequiresSteps(t *testing.T) {
+	_, err := NewFanStrategy(StrategySteps, StrategyConfig{})
+
+	var domainErr errors.Error
+	if !errors.As(err, &domainErr) || domainErr.Code() != ErrInvalidStrategy {
+		t.Errorf("NewFanStrategy(steps) without steps error = %v, want %s", err, ErrInvalidStrategy)
+	}
+}
+
+func TestFixedStrategy(t *testing.T) {
+	tests := []struct {
+		name  string
+		speed int
+		want  int
+	}{
+		{name: "within the range", speed: 55, want: 55},
+		{name: "below the minimum", speed: 20, want: 30},
+		{name: "above the maximum", speed: 120, want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			strategy := newStrategy(t, StrategyFixed, StrategyConfig{FixedSpeed: tt.speed})
+			for _, temperature := range []int{40, 65, 90} {
+				if got := strategy.Compute(input(temperature)).Percent; got != tt.want {
+					t.Errorf("Compute() at %d°C = %d%%, want %d%%", temperature, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestPIDStrategy(t *testing.T) {
+	gains := PIDGains{Proportional: 2, Integral: 0.05}
+
+	t.Run("at the target", func(t *testing.T) {
+		strategy := newStrategy(t, StrategyPID, StrategyConfig{Gains: gains})
+		if got := strategy.Compute(input(80)).Percent; got != 30 {
+			t.Errorf("Compute() at the target = %d%%, want the minimum 30%%", got)
+		}
+	})
+
+	t.Run("integral accumulates above the target", func(t *testing.T) {
+		strategy := newStrategy(t, StrategyPID, StrategyConfig{Gains: gains})
+		// 30 + 2*5 + 0.05*(5*2s), then the integral doubles
+		if got := strategy.Compute(input(85)).Percent; got != 41 {
+			t.Errorf("first Compute() at 85°C = %d%%, want 41%%", got)
+		}
+		if got := strategy.Compute(input(85)).Percent; got != 41 {
+			t.Errorf("second Compute() at 85°C = %d%%, want 41%%", got)
+		}
+		if got := strategy.Compute(input(85)).Percent; got != 42 {
+			t.Errorf("third Compute() at 85°C = %d%%, want 42%%", got)
+		}
+	})
+
+	t.Run("no windup below the target", func(t *testing.T) {
+		strategy := newStrategy(t, StrategyPID, StrategyConfig{Gains: gains})
+		for i := 0; i < 10; i++ {
+			if got := strategy.Compute(input(70)).Percent; got != 30 {
+				t.Fatalf("Compute() below the target = %d%%, want the minimum 30%%", got)
+			}
+		}
+		// Without windup only the new error counts: 30 + 2*1 + 0.05*(1*2s)
+		if got := strategy.Compute(input(81)).Percent; got != 32 {
+			t.Errorf("Compute() at 81°C after a long stretch below = %d%%, want 32%%", got)
+		}
+	})
+
+	t.Run("reset clears the integral", func(t *testing.T) {
+		strategy := newStrategy(t, StrategyPID, StrategyConfig{Gains: gains})
+		for i := 0; i < 50; i++ {
+			strategy.Compute(input(90))
+		}
+		strategy.Reset()
+		if got := strategy.Compute(input(80)).Percent; got != 30 {
+			t.Errorf("Compute() at the target after Reset() = %d%%, want 30%%", got)
+		}
+	})
+
+	t.Run("saturates at the maximum", func(t *testing.T) {
+		strategy := newStrategy(t, StrategyPID, StrategyConfig{Gains: gains})
+		got := strategy.Compute(input(120))
+		if got.Percent != 100 || got.CurvePosition != 1 {
+			t.Errorf("Compute() far above the target = %d%% at %v, want 100%% at 1", got.Percent, got.CurvePosition)
+		}
+	})
+
+	t.Run("negative gains", func(t *testing.T) {
+		_, err := NewFanStrategy(StrategyPID, StrategyConfig{Gains: PIDGains{Proportional: -1}})
+
+		var domainErr errors.Error
+		if !errors.As(err, &domainErr) || domainErr.Code() != ErrInvalidStrategy {
+			t.Errorf("NewFanStrategy(pid) with a negative gain error = %v, want %s", err, ErrInvalidStrategy)
+		}
+	})
+}
+
+// constantStrategy is a registered test strategy returning the maximum
+type constantStrategy struct{}
+
+func (constantStrategy) Compute(in ControlInput) FanSpeed { return FanSpeed{Percent: in.MaxFanSpeed} }
+
+func (constantStrategy) Reset() {}
+
+func TestRegisterFanStrategy(t *testing.T) {
+	factory := func(StrategyConfig) (FanStrategy, error) { return constantStrategy{}, nil }
+
+	if err := RegisterFanStrategy("test-constant", factory); err != nil {
+		t.Fatalf("RegisterFanStrategy() unexpected error: %v", err)
+	}
+	if !slices.Contains(FanStrategies(), "test-constant") {
+		t.Errorf("FanStrategies() = %v, missing the registered strategy", FanStrategies())
+	}
+	if got := newStrategy(t, "test-constant", StrategyConfig{}).Compute(input(40)).Percent; got != 100 {
+		t.Errorf("registered strategy Compute() = %d%%, want 100%%", got)
+	}
+
+	for _, name := range []string{"test-constant", StrategyCurve, ""} {
+		var domainErr errors.Error
+		err := RegisterFanStrategy(name, factory)
+		if !errors.As(err, &domainErr) || domainErr.Code() != ErrStrategyExists {
+			t.Errorf("RegisterFanStrategy(%q) error = %v, want %s", name, err, ErrStrategyExists)
+		}
+	}
+
+	var domainErr errors.Error
+	if _, err := NewFanStrategy("unknown", StrategyConfig{}); !errors.As(err, &domainErr) ||
+		domainErr.Code() != ErrUnknownStrategy {
+		t.Errorf("NewFanStrategy(unknown) error = %v, want %s", err, ErrUnknownStrategy)
+	}
+}
+
+func TestCurveStrategyPoints(t *testing.T) {
+	strategy := newStrategy(t, StrategyCurve, StrategyConfig{
+		// Out of order, the strategy sorts them
+		Curve: []Step{{Temperature: 80, FanSpeed: 100}, {Temperature: 50, FanSpeed: 30}, {Temperature: 65, FanSpeed: 50}},
+	})
+
+	stepped := input(70)
+	stepped.FanStep = 10
+	floor := input(40)
+	floor.MinFanSpeed = 35
+
+	tests := []struct {
+		name     string
+		in       ControlInput
+		want     int
+		position float64
+	}{
+		{name: "below the first point", in: input(40), want: 30},
+		{name: "below the first point, above the floor", in: floor, want: 35},
+		{name: "at the first point", in: input(50), want: 30},
+		{name: "between points", in: input(56), want: 38, position: 0.2},
+		{name: "at a middle point", in: input(65), want: 50, position: 0.5},
+		// 50 + 5/15 of 50, rounded
+		{name: "past a middle point", in: input(70), want: 67, position: 2.0 / 3},
+		{name: "rounded to the fan step", in: stepped, want: 70, position: 2.0 / 3},
+		{name: "at the last point", in: input(80), want: 100, position: 1},
+		{name: "above the last point", in: input(95), want: 100, position: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := strategy.Compute(tt.in)
+			if got.Percent != tt.want || got.CurvePosition != tt.position {
+				t.Errorf("Compute() at %d°C = %d%% at %v, want %d%% at %v",
+					tt.in.Temperature, got.Percent, got.CurvePosition, tt.want, tt.position)
+			}
+		})
+	}
+}
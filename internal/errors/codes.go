@@ -14,6 +14,7 @@ const (
 	ErrBindFlags       ErrorCode = "bind_flags_failed"
 	ErrInvalidInterval ErrorCode = "invalid_interval"
 	ErrLoadConfig      ErrorCode = "load_configuration"
+	ErrReloadConfig    ErrorCode = "reload_configuration"
 
 	// Logging errors
 	ErrInvalidLogLevel ErrorCode = "invalid_log_level"
@@ -30,11 +31,15 @@ const (
 	// Application errors
 	ErrInitApp         ErrorCode = "init_app_failed"
 	ErrMainLoop        ErrorCode = "main_loop_failed"
-	ErrSetGPUState     ErrorCode = "get_gpu_state_failed"
+	ErrSetGPUState     ErrorCode = "set_gpu_state_failed"
 	ErrGetGPUState     ErrorCode = "get_gpu_state_failed"
 	ErrShutdownGPU     ErrorCode = "shutdown_gpu_failed"
 	ErrResetPowerLimit ErrorCode = "reset_power_limit_failed"
 	ErrEnableAutoFan   ErrorCode = "enable_auto_fan_failed"
+	ErrFailsafeEngaged ErrorCode = "failsafe_engaged"
+	ErrDropPrivileges  ErrorCode = "drop_privileges_failed"
+	ErrTooManySkips    ErrorCode = "too_many_skipped_ticks"
+	ErrAlreadyRunning  ErrorCode = "already_running"
 
 	// Operation errors
 	ErrOperationFailed  ErrorCode = "operation_failed"
@@ -42,9 +47,10 @@ const (
 	ErrInvalidOperation ErrorCode = "invalid_operation"
 
 	// Metrics errors
-	ErrInitMetrics    ErrorCode = "init_metrics_failed"
-	ErrCollectMetrics ErrorCode = "collect_metrics_failed"
-	ErrCloseMetrics   ErrorCode = "close_metrics_failed"
+	ErrInitMetrics     ErrorCode = "init_metrics_failed"
+	ErrCollectMetrics  ErrorCode = "collect_metrics_failed"
+	ErrCloseMetrics    ErrorCode = "close_metrics_failed"
+	ErrMetricsDisabled ErrorCode = "metrics_disabled"
 )
 
 // Common error messages
@@ -57,6 +63,7 @@ var errorMessages = map[ErrorCode]string{
 	ErrMissingConfig:     "Missing configuration",
 	ErrBindFlags:         "Failed to bind flags",
 	ErrLoadConfig:        "Failed to load configuration",
+	ErrReloadConfig:      "Failed to reload configuration, keeping current",
 	ErrInitFailed:        "Initialization failed",
 	ErrShutdownFailed:    "Shutdown failed",
 	ErrResourceBusy:      "Resource is busy",
@@ -66,15 +73,22 @@ var errorMessages = map[ErrorCode]string{
 	ErrTimeout:           "Operation timed out",
 	ErrInvalidOperation:  "Invalid operation",
 	ErrInvalidInterval:   "Invalid interval value",
+	ErrInvalidLogLevel:   "Invalid log level",
 	ErrInitMetrics:       "Failed to initialize metrics",
 	ErrCollectMetrics:    "Failed to collect metrics data",
 	ErrCloseMetrics:      "Failed to close metrics connection",
+	ErrMetricsDisabled:   "Metrics disabled after repeated failures",
 	ErrInitApp:           "Failed to initialize application",
 	ErrMainLoop:          "Error in main loop",
+	ErrSetGPUState:       "Failed to set GPU state",
 	ErrGetGPUState:       "Failed to get GPU state",
 	ErrShutdownGPU:       "Failed to shutdown GPU",
 	ErrResetPowerLimit:   "Failed to reset power limit",
 	ErrEnableAutoFan:     "Failed to enable auto fan control",
+	ErrFailsafeEngaged:   "Failsafe engaged after repeated control failures",
+	ErrDropPrivileges:    "Failed to drop privileges",
+	ErrTooManySkips:      "Too many consecutive ticks without applying control",
+	ErrAlreadyRunning:    "Another instance is already controlling the GPU",
 }
 
 // GetErrorMessage returns the message for a given error code
@@ -0,0 +1,63 @@
+package errors
+
+import "testing"
+
+func TestErrorCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		code ErrorCode
+	}{
+		{name: "ErrInternal", code: ErrInternal},
+		{name: "ErrInvalidArgument", code: ErrInvalidArgument},
+		{name: "ErrNotImplemented", code: ErrNotImplemented},
+		{name: "ErrUnavailable", code: ErrUnavailable},
+		{name: "ErrInvalidConfig", code: ErrInvalidConfig},
+		{name: "ErrMissingConfig", code: ErrMissingConfig},
+		{name: "ErrBindFlags", code: ErrBindFlags},
+		{name: "ErrInvalidInterval", code: ErrInvalidInterval},
+		{name: "ErrLoadConfig", code: ErrLoadConfig},
+		{name: "ErrReloadConfig", code: ErrReloadConfig},
+		{name: "ErrInvalidLogLevel", code: ErrInvalidLogLevel},
+		{name: "ErrInitFailed", code: ErrInitFailed},
+		{name: "ErrShutdownFailed", code: ErrShutdownFailed},
+		{name: "ErrResourceBusy", code: ErrResourceBusy},
+		{name: "ErrResourceNotFound", code: ErrResourceNotFound},
+		{name: "ErrResourceExhausted", code: ErrResourceExhausted},
+		{name: "ErrInitApp", code: ErrInitApp},
+		{name: "ErrMainLoop", code: ErrMainLoop},
+		{name: "ErrSetGPUState", code: ErrSetGPUState},
+		{name: "ErrGetGPUState", code: ErrGetGPUState},
+		{name: "ErrShutdownGPU", code: ErrShutdownGPU},
+		{name: "ErrResetPowerLimit", code: ErrResetPowerLimit},
+		{name: "ErrEnableAutoFan", code: ErrEnableAutoFan},
+		{name: "ErrFailsafeEngaged", code: ErrFailsafeEngaged},
+		{name: "ErrDropPrivileges", code: ErrDropPrivileges},
+		{name: "ErrTooManySkips", code: ErrTooManySkips},
+		{name: "ErrAlreadyRunning", code: ErrAlreadyRunning},
+		{name: "ErrOperationFailed", code: ErrOperationFailed},
+		{name: "ErrTimeout", code: ErrTimeout},
+		{name: "ErrInvalidOperation", code: ErrInvalidOperation},
+		{name: "ErrInitMetrics", code: ErrInitMetrics},
+		{name: "ErrCollectMetrics", code: ErrCollectMetrics},
+		{name: "ErrCloseMetrics", code: ErrCloseMetrics},
+		{name: "ErrMetricsDisabled", code: ErrMetricsDisabled},
+	}
+
+	seen := make(map[ErrorCode]string, len(tests))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if other, ok := seen[tt.code]; ok {
+				t.Errorf("%s has the same code %q as %s", tt.name, tt.code, other)
+			}
+			seen[tt.code] = tt.name
+
+			if _, ok := errorMessages[tt.code]; !ok {
+				t.Errorf("%s (%q) has no entry in errorMessages", tt.name, tt.code)
+			}
+		})
+	}
+
+	if len(errorMessages) != len(tests) {
+		t.Errorf("errorMessages has %d entries for %d codes", len(errorMessages), len(tests))
+	}
+}
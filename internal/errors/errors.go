@@ -10,6 +10,7 @@ var (
 	Is     = errors.Is
 	As     = errors.As
 	Unwrap = errors.Unwrap
+	Join   = errors.Join
 )
 
 // appError implements the Error interface
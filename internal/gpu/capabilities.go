@@ -0,0 +1,156 @@
+package gpu
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// Capabilities describes what the card lets nvidiactl read and control. It
+// is probed with read-only calls, so write capabilities reflect what the
+// driver reports as supported rather than a test write.
+type Capabilities struct {
+	Name string `json:"name"`
+	UUID string `json:"uuid"`
+
+	// FanCount is the number of fans the driver exposes
+	FanCount int `json:"fan_count"`
+	// FanSpeedRead is whether the fan speed can be read
+	FanSpeedRead bool `json:"fan_speed_read"`
+	// FanControlPolicy is whether the per-fan control policy can be read,
+	// showing when the driver takes back control of the fans
+	FanControlPolicy bool `json:"fan_control_policy"`
+	// FanControl is whether the fan control policy can be switched to
+	// manual and the driver reports a usable speed range, both required to
+	// set fan speeds
+	FanControl bool `json:"fan_control"`
+
+	// PowerUsageRead is whether the current power draw can be read
+	PowerUsageRead bool `json:"power_usage_read"`
+	// PowerLimitRead is whether the power limit and its constraints can be read
+	PowerLimitRead bool `json:"power_limit_read"`
+	// PowerLimitControl is whether power management is enabled, required to
+	// set the power limit
+	PowerLimitControl bool `json:"power_limit_control"`
+	// BoardPower is whether the total board power is reported
+	BoardPower bool `json:"board_power"`
+
+	// Sensors lists the readable temperature sensors
+	Sensors []TemperatureSensor `json:"sensors"`
+	// ThermalSensors lists the sensors of the NVML thermal settings, which
+	// temperature_sensor_index selects from
+	ThermalSensors []ThermalSensor `json:"thermal_sensors"`
+	// TemperatureThresholds is whether the slowdown threshold is reported
+	TemperatureThresholds bool `json:"temperature_thresholds"`
+	// PerformanceState is whether the P-state can be read
+	PerformanceState bool `json:"performance_state"`
+	// PersistenceMode is whether persistence mode is enabled, which keeps
+	// the driver loaded between nvidiactl runs
+	PersistenceMode bool `json:"persistence_mode"`
+	// Events is whether the card supports any of the NVML events nvidiactl
+	// reacts to
+	Events bool `json:"events"`
+}
+
+// ProbeCapabilities initializes NVML, probes the selected device and shuts
+// NVML down again. Use it when no controller is running.
+func ProbeCapabilities(selector string) (Capabilities, error) {
+	errFactory := errors.New()
+
+	wrapper := &nvmlWrapper{}
+	if err := wrapper.Initialize(); err != nil {
+		return Capabilities{}, errFactory.Wrap(ErrInitFailed, err)
+	}
+	defer func() {
+		if err := wrapper.Shutdown(); err != nil {
+			logger.Debug().Err(err).Msg("NVML shutdown failed")
+		}
+	}()
+
+	device, _, err := resolveDevice(wrapper, selector)
+	if err != nil {
+		return Capabilities{}, err
+	}
+
+	return probeCapabilities(device), nil
+}
+
+// GetCapabilities returns the capabilities probed at initialization
+func (c *controller) GetCapabilities() Capabilities {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.capabilities
+}
+
+// probeCapabilities checks each capability with a read-only NVML call
+func probeCapabilities(device nvml.Device) Capabilities {
+	var caps Capabilities
+
+	if name, ret := device.GetName(); IsNVMLSuccess(ret) {
+		caps.Name = name
+	}
+	if uuid, ret := device.GetUUID(); IsNVMLSuccess(ret) {
+		caps.UUID = uuid
+	}
+
+	if count, ret := device.GetNumFans(); IsNVMLSuccess(ret) {
+		caps.FanCount = count
+	}
+	if caps.FanCount > 0 {
+		_, ret := device.GetFanSpeed_v2(0)
+		caps.FanSpeedRead = IsNVMLSuccess(ret)
+
+		_, ret = device.GetFanControlPolicy_v2(0)
+		caps.FanControlPolicy = IsNVMLSuccess(ret)
+		minSpeed, maxSpeed, limitsRet := device.GetMinMaxFanSpeed()
+		caps.FanControl = IsNVMLSuccess(ret) && IsNVMLSuccess(limitsRet) && maxSpeed > minSpeed
+	}
+
+	_, ret := device.GetPowerUsage()
+	caps.PowerUsageRead = IsNVMLSuccess(ret)
+
+	_, _, ret = device.GetPowerManagementLimitConstraints()
+	caps.PowerLimitRead = IsNVMLSuccess(ret)
+
+	mode, ret := device.GetPowerManagementMode()
+	caps.PowerLimitControl = IsNVMLSuccess(ret) && mode == nvml.FEATURE_ENABLED
+
+	values := []nvml.FieldValue{{FieldId: nvml.FI_DEV_POWER_INSTANT, ScopeId: nvml.POWER_SCOPE_MODULE}}
+	//nolint:gosec // G115: NVML return codes are small positive integers
+	caps.BoardPower = IsNVMLSuccess(device.GetFieldValues(values)) &&
+		IsNVMLSuccess(nvml.Return(values[0].NvmlReturn))
+
+	caps.Sensors = []TemperatureSensor{}
+	if _, ret := device.GetTemperature(nvml.TEMPERATURE_GPU); IsNVMLSuccess(ret) {
+		caps.Sensors = append(caps.Sensors, SensorCore)
+	}
+	values = []nvml.FieldValue{{FieldId: nvml.FI_DEV_MEMORY_TEMP}}
+	//nolint:gosec // G115: NVML return codes are small positive integers
+	if IsNVMLSuccess(device.GetFieldValues(values)) && IsNVMLSuccess(nvml.Return(values[0].NvmlReturn)) {
+		caps.Sensors = append(caps.Sensors, SensorMemory)
+	}
+
+	caps.ThermalSensors, _ = readThermalSensors(device)
+	if caps.ThermalSensors == nil {
+		caps.ThermalSensors = []ThermalSensor{}
+	}
+
+	_, ret = device.GetTemperatureThreshold(nvml.TEMPERATURE_THRESHOLD_SLOWDOWN)
+	caps.TemperatureThresholds = IsNVMLSuccess(ret)
+
+	pstate, ret := device.GetPerformanceState()
+	caps.PerformanceState = IsNVMLSuccess(ret) && pstate != nvml.PSTATE_UNKNOWN
+
+	persistence, ret := device.GetPersistenceMode()
+	caps.PersistenceMode = IsNVMLSuccess(ret) && persistence == nvml.FEATURE_ENABLED
+
+	if supported, ret := device.GetSupportedEventTypes(); IsNVMLSuccess(ret) {
+		for eventType := range watchedEventTypes {
+			if supported&eventType != 0 {
+				caps.Events = true
+			}
+		}
+	}
+
+	return caps
+}
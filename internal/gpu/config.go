@@ -0,0 +1,144 @@
+package gpu
+
+import "time"
+
+// Option configures the GPU controller created by New
+type Option func(*options)
+
+// options holds internal controller options
+type options struct {
+	traceReturnCodes     bool
+	temperatureStatistic TemperatureStatistic
+	smoothing            TemperatureSmoothing
+	emaAlpha             float64
+	filter               TemperatureFilter
+	filterDelta          Temperature
+	sensorIndex          int
+	warmupReads          int
+	utilizationWindow    int
+	temperatureWindow    int
+	powerWindow          int
+	readOnly             bool
+	deviceSelector       string
+	initRetries          int
+	initBackoff          time.Duration
+	readRetries          int
+	readRetryDelay       time.Duration
+}
+
+// WithReturnCodeTracing records the raw NVML return code of every temperature,
+// fan and power operation, to be collected with DrainReturnCodes. This is a
+// debugging aid and disabled by default.
+func WithReturnCodeTracing(enabled bool) Option {
+	return func(o *options) {
+		o.traceReturnCodes = enabled
+	}
+}
+
+// WithTemperatureStatistic sets how UpdateTemperatureHistory summarizes the
+// temperature window. The default is the mean.
+func WithTemperatureStatistic(statistic TemperatureStatistic) Option {
+	return func(o *options) {
+		o.temperatureStatistic = statistic
+	}
+}
+
+// WithTemperatureSmoothing sets how UpdateTemperatureHistory smooths the
+// readings. With SmoothingEMA, each reading is weighted by alpha (0 to 1) and
+// the temperature statistic is not used. The default is SmoothingSMA.
+func WithTemperatureSmoothing(smoothing TemperatureSmoothing, alpha float64) Option {
+	return func(o *options) {
+		o.smoothing = smoothing
+		o.emaAlpha = alpha
+	}
+}
+
+// WithTemperatureFilter sets how UpdateTemperatureHistory handles spikes.
+// With FilterMedian, a reading more than delta away from the current average
+// is replaced with the median of the history. The default is FilterNone.
+func WithTemperatureFilter(filter TemperatureFilter, delta Temperature) Option {
+	return func(o *options) {
+		o.filter = filter
+		o.filterDelta = delta
+	}
+}
+
+// WithTemperatureSensorIndex reads the control temperature from the thermal
+// sensor at index instead of the core sensor. A sensor that can't be read at
+// initialization falls back to the core sensor. A negative index, the
+// default, uses the core sensor.
+func WithTemperatureSensorIndex(index int) Option {
+	return func(o *options) {
+		o.sensorIndex = index
+	}
+}
+
+// WithWarmupReads discards the given number of temperature reads during
+// Initialize, before any value reaches the history. The default is none.
+func WithWarmupReads(count int) Option {
+	return func(o *options) {
+		o.warmupReads = count
+	}
+}
+
+// WithUtilizationWindow sets the number of samples UpdateUtilizationHistory
+// averages. The default is the size of the temperature window.
+func WithUtilizationWindow(size int) Option {
+	return func(o *options) {
+		o.utilizationWindow = size
+	}
+}
+
+// WithTemperatureWindow sets the number of temperature readings
+// UpdateTemperatureHistory summarizes. The default is 5.
+func WithTemperatureWindow(size int) Option {
+	return func(o *options) {
+		o.temperatureWindow = size
+	}
+}
+
+// WithPowerWindow sets the number of power limits the power history averages.
+// The default is 5.
+func WithPowerWindow(size int) Option {
+	return func(o *options) {
+		o.powerWindow = size
+	}
+}
+
+// WithInitRetries retries initializing NVML and finding the device up to
+// retries times, waiting backoff before the first retry and doubling the
+// wait after each. Only the first Initialize call retries, later calls make
+// a single attempt. The default is no retries.
+func WithInitRetries(retries int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.initRetries = retries
+		o.initBackoff = backoff
+	}
+}
+
+// WithReadRetries retries temperature, fan speed and power reads failing
+// with a transient NVML error up to retries times, waiting delay between
+// attempts. Permanent errors such as ERROR_NOT_SUPPORTED are never retried.
+// The default is no retries.
+func WithReadRetries(retries int, delay time.Duration) Option {
+	return func(o *options) {
+		o.readRetries = retries
+		o.readRetryDelay = delay
+	}
+}
+
+// WithReadOnly makes the controller refuse every NVML call that changes the
+// device, returning ErrReadOnly from the write methods
+func WithReadOnly(enabled bool) Option {
+	return func(o *options) {
+		o.readOnly = enabled
+	}
+}
+
+// WithDevice selects the GPU to control by index or UUID. The default, an
+// empty selector, is the first GPU.
+func WithDevice(selector string) Option {
+	return func(o *options) {
+		o.deviceSelector = selector
+	}
+}
@@ -12,24 +12,51 @@ const (
 	ErrDeviceNotFound   = errors.ErrResourceNotFound
 	ErrShutdownFailed   = errors.ErrShutdownFailed
 	ErrDeviceInfoFailed = errors.ErrorCode("gpu_device_info_failed")
+	ErrReadOnly         = errors.ErrorCode("gpu_read_only")
 
 	// Temperature Errors
 	ErrTemperatureReadFailed = errors.ErrorCode("gpu_temperature_read_failed")
+	ErrUnknownSensor         = errors.ErrorCode("gpu_unknown_temperature_sensor")
 
 	// Fan Control Errors
-	ErrFanControlFailed   = errors.ErrorCode("gpu_fan_control_failed")
-	ErrFanCountFailed     = errors.ErrorCode("gpu_fan_count_failed")
-	ErrGetFanSpeedFailed  = errors.ErrorCode("gpu_fan_speed_failed")
-	ErrGetFanLimitsFailed = errors.ErrorCode("gpu_fan_limits_failed")
-	ErrSetFanSpeed        = errors.ErrorCode("gpu_set_fan_speed_failed")
-	ErrEnableAutoFan      = errors.ErrorCode("gpu_enable_auto_fan_failed")
-	ErrDisableAutoFan     = errors.ErrorCode("gpu_disable_auto_fan_failed")
+	ErrFanControlFailed      = errors.ErrorCode("gpu_fan_control_failed")
+	ErrFanCountFailed        = errors.ErrorCode("gpu_fan_count_failed")
+	ErrGetFanSpeedFailed     = errors.ErrorCode("gpu_fan_speed_failed")
+	ErrFanSpeedsUnreadable   = errors.ErrorCode("gpu_fan_speeds_unreadable")
+	ErrGetFanLimitsFailed    = errors.ErrorCode("gpu_fan_limits_failed")
+	ErrSetFanSpeed           = errors.ErrorCode("gpu_set_fan_speed_failed")
+	ErrEnableAutoFan         = errors.ErrorCode("gpu_enable_auto_fan_failed")
+	ErrDisableAutoFan        = errors.ErrorCode("gpu_disable_auto_fan_failed")
+	ErrFanResetFailed        = errors.ErrorCode("gpu_fan_reset_failed")
+	ErrFanControlUnavailable = errors.ErrorCode("gpu_fan_control_unavailable")
+	ErrFanPolicyFailed       = errors.ErrorCode("gpu_fan_policy_failed")
+	ErrFanPolicyUnsupported  = errors.ErrorCode("gpu_fan_policy_unsupported")
 
 	// Power Management Errors
 	ErrPowerManagementFailed = errors.ErrorCode("gpu_power_management_failed")
 	ErrPowerLimitFailed      = errors.ErrorCode("gpu_power_limit_failed")
 	ErrPowerLimitsFailed     = errors.ErrorCode("gpu_power_limits_failed")
 	ErrSetPowerLimit         = errors.ErrorCode("gpu_set_power_limit_failed")
+	ErrBoardPowerFailed      = errors.ErrorCode("gpu_board_power_failed")
+	ErrBoardPowerUnsupported = errors.ErrorCode("gpu_board_power_unsupported")
+
+	ErrEnforcedPowerLimitFailed      = errors.ErrorCode("gpu_enforced_power_limit_failed")
+	ErrEnforcedPowerLimitUnsupported = errors.ErrorCode("gpu_enforced_power_limit_unsupported")
+
+	ErrPowerUsageFailed      = errors.ErrorCode("gpu_power_usage_failed")
+	ErrPowerUsageUnsupported = errors.ErrorCode("gpu_power_usage_unsupported")
+
+	// Performance State Errors
+	ErrPerformanceStateFailed      = errors.ErrorCode("gpu_performance_state_failed")
+	ErrPerformanceStateUnsupported = errors.ErrorCode("gpu_performance_state_unsupported")
+
+	// Utilization Errors
+	ErrUtilizationFailed = errors.ErrorCode("gpu_utilization_failed")
+	ErrMemoryInfoFailed  = errors.ErrorCode("gpu_memory_info_failed")
+
+	// Event Errors
+	ErrEventsFailed      = errors.ErrorCode("gpu_events_failed")
+	ErrEventsUnsupported = errors.ErrorCode("gpu_events_unsupported")
 
 	// Device Discovery Errors
 	ErrDeviceCountFailed = errors.ErrorCode("gpu_device_count_failed")
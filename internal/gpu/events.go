@@ -0,0 +1,127 @@
+package gpu
+
+import (
+	"context"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+const (
+	// eventWaitTimeoutMs bounds how long a wait blocks, and with it how long
+	// the event goroutine takes to notice cancellation
+	eventWaitTimeoutMs = 500
+	eventBufferSize    = 8
+)
+
+// EventType identifies the kind of NVML event received
+type EventType string
+
+const (
+	// EventClockChange is sent when the clocks change, which includes
+	// thermal and power throttling
+	EventClockChange EventType = "clock_change"
+	// EventPerformanceStateChange is sent when the P-state changes
+	EventPerformanceStateChange EventType = "pstate_change"
+	// EventCriticalError is sent on a critical Xid error, Data holds the Xid
+	EventCriticalError EventType = "critical_error"
+)
+
+// Event is a single NVML device event
+type Event struct {
+	Type EventType
+	Data uint64
+}
+
+// watchedEventTypes maps the NVML event types nvidiactl reacts to
+var watchedEventTypes = map[uint64]EventType{
+	nvml.EventTypeClock:            EventClockChange,
+	nvml.EventTypePState:           EventPerformanceStateChange,
+	nvml.EventTypeXidCriticalError: EventCriticalError,
+}
+
+// WatchEvents registers for the device events supported by the card and
+// delivers them on the returned channel until ctx is canceled or waiting
+// fails, after which the channel is closed. Returns ErrEventsUnsupported if
+// the card supports none of them.
+func (c *controller) WatchEvents(ctx context.Context) (<-chan Event, error) {
+	errFactory := errors.New()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.initialized {
+		return nil, errFactory.New(ErrNotInitialized)
+	}
+
+	supported, ret := c.device.GetSupportedEventTypes()
+	c.tracer.record("get_supported_event_types", ret)
+	if ret == nvml.ERROR_NOT_SUPPORTED {
+		return nil, errFactory.New(ErrEventsUnsupported)
+	}
+	if !IsNVMLSuccess(ret) {
+		return nil, errFactory.Wrap(ErrEventsFailed, newNVMLError(ret))
+	}
+
+	var mask uint64
+	for eventType := range watchedEventTypes {
+		mask |= eventType & supported
+	}
+	if mask == 0 {
+		return nil, errFactory.New(ErrEventsUnsupported)
+	}
+
+	set, ret := nvml.EventSetCreate()
+	if !IsNVMLSuccess(ret) {
+		return nil, errFactory.Wrap(ErrEventsFailed, newNVMLError(ret))
+	}
+
+	ret = c.device.RegisterEvents(mask, set)
+	c.tracer.record("register_events", ret)
+	if !IsNVMLSuccess(ret) {
+		set.Free()
+		if ret == nvml.ERROR_NOT_SUPPORTED {
+			return nil, errFactory.New(ErrEventsUnsupported)
+		}
+		return nil, errFactory.Wrap(ErrEventsFailed, newNVMLError(ret))
+	}
+
+	logger.Debug().Uint64("event_mask", mask).Msg("Registered for NVML events")
+
+	events := make(chan Event, eventBufferSize)
+	c.eventWorkers.Add(1)
+	go c.waitForEvents(ctx, set, events)
+
+	return events, nil
+}
+
+// waitForEvents forwards events from the event set until ctx is canceled.
+// Events are dropped rather than blocking if the receiver falls behind.
+func (c *controller) waitForEvents(ctx context.Context, set nvml.EventSet, events chan<- Event) {
+	defer c.eventWorkers.Done()
+	defer close(events)
+	defer set.Free()
+
+	for ctx.Err() == nil {
+		data, ret := set.Wait(eventWaitTimeoutMs)
+		if ret == nvml.ERROR_TIMEOUT {
+			continue
+		}
+		if !IsNVMLSuccess(ret) {
+			// Closing the channel tells the receiver to fall back to polling
+			logger.Warn().Msgf("Failed to wait for NVML events, stopping: %s", nvml.ErrorString(ret))
+			return
+		}
+
+		eventType, ok := watchedEventTypes[data.EventType]
+		if !ok {
+			continue
+		}
+
+		select {
+		case events <- Event{Type: eventType, Data: data.EventData}:
+		default:
+			logger.Debug().Str("event", string(eventType)).Msg("Dropping NVML event, receiver busy")
+		}
+	}
+}
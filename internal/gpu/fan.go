@@ -1,6 +1,7 @@
 package gpu
 
 import (
+	"fmt"
 	"sync"
 
 	"codeberg.org/mutker/nvidiactl/internal/errors"
@@ -15,14 +16,22 @@ type fanController struct {
 	speeds     []FanSpeed
 	lastSpeeds []FanSpeed
 	autoMode   bool
+	tracer     *returnCodeTracer
+	readRetry  readRetry
 	mu         sync.RWMutex
+	// controllable is false when the driver reports no usable speed range
+	controllable bool
 }
 
-func newFanController(device nvml.Device) (FanController, error) {
+func newFanController(device nvml.Device, tracer *returnCodeTracer, retry readRetry) (FanController, error) {
 	errFactory := errors.New()
 	fc := &fanController{
-		device:   device,
-		autoMode: true,
+		device:    device,
+		autoMode:  true,
+		tracer:    tracer,
+		readRetry: retry,
+		// Assumed until the limits are read, so a degenerate range warns
+		controllable: true,
 	}
 
 	count, ret := device.GetNumFans()
@@ -44,6 +53,7 @@ func newFanController(device nvml.Device) (FanController, error) {
 		Max:     FanSpeed(maxSpeed),
 		Default: FanSpeed(minSpeed),
 	}
+	fc.updateControllable()
 
 	for i := 0; i < fc.count; i++ {
 		speed, ret := device.GetFanSpeed_v2(i)
@@ -56,6 +66,7 @@ func newFanController(device nvml.Device) (FanController, error) {
 
 	if fc.count > 0 {
 		fc.limits.Default = fc.speeds[0]
+		fc.autoMode = detectAutoMode(device)
 	}
 
 	return fc, nil
@@ -70,7 +81,13 @@ func (fc *fanController) GetSpeed(fanIndex int) (FanSpeed, error) {
 		return 0, errFactory.WithData(errors.ErrInvalidArgument, "fan index out of range")
 	}
 
-	speed, ret := fc.device.GetFanSpeed_v2(fanIndex)
+	var speed uint32
+	ret := fc.readRetry.do(fanOperation("get_fan_speed", fanIndex), func() nvml.Return {
+		var ret nvml.Return
+		speed, ret = fc.device.GetFanSpeed_v2(fanIndex)
+		return ret
+	})
+	fc.tracer.record(fanOperation("get_fan_speed", fanIndex), ret)
 	if !IsNVMLSuccess(ret) {
 		return 0, errFactory.Wrap(ErrGetFanSpeedFailed, newNVMLError(ret))
 	}
@@ -83,6 +100,10 @@ func (fc *fanController) SetSpeed(speed FanSpeed) error {
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
 
+	if !fc.controllable {
+		return errFactory.WithData(ErrFanControlUnavailable, fc.limits)
+	}
+
 	if speed < fc.limits.Min || speed > fc.limits.Max {
 		return errFactory.WithData(errors.ErrInvalidArgument, "fan speed out of range")
 	}
@@ -90,7 +111,9 @@ func (fc *fanController) SetSpeed(speed FanSpeed) error {
 	copy(fc.lastSpeeds, fc.speeds)
 
 	for i := 0; i < fc.count; i++ {
-		if ret := nvml.DeviceSetFanSpeed_v2(fc.device, i, int(speed)); !IsNVMLSuccess(ret) {
+		ret := nvml.DeviceSetFanSpeed_v2(fc.device, i, int(speed))
+		fc.tracer.record(fanOperation("set_fan_speed", i), ret)
+		if !IsNVMLSuccess(ret) {
 			return errFactory.Wrap(ErrSetFanSpeed, newNVMLError(ret))
 		}
 		fc.speeds[i] = speed
@@ -107,6 +130,78 @@ func (fc *fanController) GetSpeedLimits() FanSpeedLimits {
 	return fc.limits
 }
 
+// RefreshLimits re-reads the fan count and the minimum and maximum fan speed
+// and reports whether the limits changed. The default speed is kept, it is
+// the speed found at startup.
+func (fc *fanController) RefreshLimits() (bool, error) {
+	errFactory := errors.New()
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.refreshCount(); err != nil {
+		return false, err
+	}
+
+	minSpeed, maxSpeed, ret := fc.device.GetMinMaxFanSpeed()
+	fc.tracer.record("get_fan_speed_limits", ret)
+	if !IsNVMLSuccess(ret) {
+		return false, errFactory.Wrap(ErrGetFanLimitsFailed, newNVMLError(ret))
+	}
+
+	limits := FanSpeedLimits{
+		Min:     FanSpeed(minSpeed),
+		Max:     FanSpeed(maxSpeed),
+		Default: fc.limits.Default,
+	}
+	changed := limits != fc.limits
+	fc.limits = limits
+	fc.updateControllable()
+
+	return changed, nil
+}
+
+// refreshCount re-reads the number of fans, which a card reset into a
+// different state may change, and resizes the speed slices to match. New
+// fans start at the speed of the first fan. The caller must hold the lock.
+func (fc *fanController) refreshCount() error {
+	errFactory := errors.New()
+
+	count, ret := fc.device.GetNumFans()
+	fc.tracer.record("get_fan_count", ret)
+	if !IsNVMLSuccess(ret) {
+		return errFactory.Wrap(ErrFanCountFailed, newNVMLError(ret))
+	}
+	if count == fc.count {
+		return nil
+	}
+
+	logger.Warn().
+		Int("previous", fc.count).
+		Int("count", count).
+		Msg("Fan count changed")
+
+	fill := fc.limits.Default
+	if fc.count > 0 {
+		fill = fc.speeds[0]
+	}
+	fc.speeds = resizeSpeeds(fc.speeds, count, fill)
+	fc.lastSpeeds = resizeSpeeds(fc.lastSpeeds, count, fill)
+	fc.count = count
+	fc.updateControllable()
+
+	return nil
+}
+
+// resizeSpeeds returns speeds truncated or extended with fill to count fans
+func resizeSpeeds(speeds []FanSpeed, count int, fill FanSpeed) []FanSpeed {
+	resized := make([]FanSpeed, count)
+	n := copy(resized, speeds)
+	for i := n; i < count; i++ {
+		resized[i] = fill
+	}
+	return resized
+}
+
 func (fc *fanController) EnableAuto() error {
 	errFactory := errors.New()
 	fc.mu.Lock()
@@ -115,7 +210,9 @@ func (fc *fanController) EnableAuto() error {
 	copy(fc.lastSpeeds, fc.speeds)
 
 	for i := 0; i < fc.count; i++ {
-		if ret := nvml.DeviceSetDefaultFanSpeed_v2(fc.device, i); !IsNVMLSuccess(ret) {
+		ret := nvml.DeviceSetDefaultFanSpeed_v2(fc.device, i)
+		fc.tracer.record(fanOperation("set_default_fan_speed", i), ret)
+		if !IsNVMLSuccess(ret) {
 			return errFactory.Wrap(ErrFanControlFailed, newNVMLError(ret))
 		}
 	}
@@ -125,18 +222,56 @@ func (fc *fanController) EnableAuto() error {
 	return nil
 }
 
+// ResetToDefault restores the VBIOS fan control policy and default fan
+// speed, returning the fans to their behavior before nvidiactl. Unlike
+// EnableAuto, it also resets the control policy, where the card supports it.
+func (fc *fanController) ResetToDefault() error {
+	errFactory := errors.New()
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	copy(fc.lastSpeeds, fc.speeds)
+
+	for i := 0; i < fc.count; i++ {
+		ret := fc.device.SetFanControlPolicy(i, nvml.FAN_POLICY_TEMPERATURE_CONTINOUS_SW)
+		fc.tracer.record(fanOperation("set_fan_control_policy", i), ret)
+		if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_FUNCTION_NOT_FOUND {
+			logger.Debug().Int("fan", i).Msg("Fan control policy not supported, resetting fan speed only")
+		} else if !IsNVMLSuccess(ret) {
+			return errFactory.Wrap(ErrFanResetFailed, newNVMLError(ret))
+		}
+
+		ret = nvml.DeviceSetDefaultFanSpeed_v2(fc.device, i)
+		fc.tracer.record(fanOperation("set_default_fan_speed", i), ret)
+		if !IsNVMLSuccess(ret) {
+			return errFactory.Wrap(ErrFanResetFailed, newNVMLError(ret))
+		}
+	}
+
+	fc.autoMode = true
+
+	return nil
+}
+
 func (fc *fanController) DisableAuto() error {
 	errFactory := errors.New()
 	fc.mu.Lock()
 	defer fc.mu.Unlock()
 
+	if !fc.controllable {
+		return errFactory.WithData(ErrFanControlUnavailable, fc.limits)
+	}
+
 	for i := 0; i < fc.count; i++ {
 		currentSpeed, ret := fc.device.GetFanSpeed_v2(i)
+		fc.tracer.record(fanOperation("get_fan_speed", i), ret)
 		if !IsNVMLSuccess(ret) {
 			return errFactory.Wrap(ErrGetFanSpeedFailed, newNVMLError(ret))
 		}
 
-		if ret := nvml.DeviceSetFanSpeed_v2(fc.device, i, int(currentSpeed)); !IsNVMLSuccess(ret) {
+		ret = nvml.DeviceSetFanSpeed_v2(fc.device, i, int(currentSpeed))
+		fc.tracer.record(fanOperation("set_fan_speed", i), ret)
+		if !IsNVMLSuccess(ret) {
 			return errFactory.Wrap(ErrFanControlFailed, newNVMLError(ret))
 		}
 
@@ -148,12 +283,62 @@ func (fc *fanController) DisableAuto() error {
 	return nil
 }
 
+// GetControlPolicy reads the control policy of a fan from the device. It
+// tells whether the fan is still under manual control or was handed back to
+// the driver, e.g. by a card whose VBIOS reclaims control.
+func (fc *fanController) GetControlPolicy(fanIndex int) (FanControlPolicy, error) {
+	errFactory := errors.New()
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+
+	if fanIndex < 0 || fanIndex >= fc.count {
+		return 0, errFactory.WithData(errors.ErrInvalidArgument, "fan index out of range")
+	}
+
+	policy, ret := fc.device.GetFanControlPolicy_v2(fanIndex)
+	fc.tracer.record(fanOperation("get_fan_control_policy", fanIndex), ret)
+	if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_FUNCTION_NOT_FOUND {
+		return 0, errFactory.Wrap(ErrFanPolicyUnsupported, newNVMLError(ret))
+	}
+	if !IsNVMLSuccess(ret) {
+		return 0, errFactory.Wrap(ErrFanPolicyFailed, newNVMLError(ret))
+	}
+
+	return FanControlPolicy(policy), nil
+}
+
+// IsControllable returns whether fan speeds can be set. Cards without fans
+// and cards reporting an empty speed range, as seen on some virtualized or
+// passthrough setups, are left to the driver.
+func (fc *fanController) IsControllable() bool {
+	fc.mu.RLock()
+	defer fc.mu.RUnlock()
+	return fc.controllable
+}
+
 func (fc *fanController) IsAutoMode() bool {
 	fc.mu.RLock()
 	defer fc.mu.RUnlock()
 	return fc.autoMode
 }
 
+// RefreshAutoMode re-reads the fan count and the fan control policy from the
+// device
+func (fc *fanController) RefreshAutoMode() bool {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+
+	if err := fc.refreshCount(); err != nil {
+		logger.Debug().Err(err).Msg("Failed to re-read fan count")
+	}
+
+	if fc.count > 0 {
+		fc.autoMode = detectAutoMode(fc.device)
+	}
+
+	return fc.autoMode
+}
+
 func (fc *fanController) GetLastSpeeds() []FanSpeed {
 	fc.mu.RLock()
 	defer fc.mu.RUnlock()
@@ -163,7 +348,11 @@ func (fc *fanController) GetLastSpeeds() []FanSpeed {
 	return speeds
 }
 
-func (fc *fanController) GetCurrentSpeeds() []FanSpeed {
+// GetCurrentSpeeds reads the speed of every fan. Fans that can't be read keep
+// their last known speed. If no fan can be read at all, the last known speeds
+// are returned together with ErrFanSpeedsUnreadable.
+func (fc *fanController) GetCurrentSpeeds() ([]FanSpeed, error) {
+	errFactory := errors.New()
 	fc.mu.RLock()
 	defer fc.mu.RUnlock()
 
@@ -171,16 +360,66 @@ func (fc *fanController) GetCurrentSpeeds() []FanSpeed {
 	copy(speeds, fc.speeds)
 
 	// Update current speeds
+	failed := 0
+	var lastRet nvml.Return
 	for i := 0; i < fc.count; i++ {
 		speed, ret := fc.device.GetFanSpeed_v2(i)
+		fc.tracer.record(fanOperation("get_fan_speed", i), ret)
 		if ret != nvml.SUCCESS {
 			logger.Debug().Msgf("Failed to get fan %d speed: %s", i, nvml.ErrorString(ret))
+			failed++
+			lastRet = ret
 			continue
 		}
 		speeds[i] = FanSpeed(speed)
 	}
 
+	if fc.count > 0 && failed == fc.count {
+		logger.Warn().
+			Int("fan_count", fc.count).
+			Str("nvml_error", nvml.ErrorString(lastRet)).
+			Msg("All fan speed reads failed")
+		return speeds, errFactory.Wrap(ErrFanSpeedsUnreadable, newNVMLError(lastRet))
+	}
+
 	logger.Debug().Interface("fanSpeeds", speeds).Msg("Current fan speeds retrieved")
 
-	return speeds
+	return speeds, nil
+}
+
+// usableFanSpeedRange reports whether there are fans with a speed range to
+// control within
+func usableFanSpeedRange(count int, limits FanSpeedLimits) bool {
+	return count > 0 && limits.Max > limits.Min
+}
+
+// updateControllable re-evaluates whether the fans can be controlled,
+// warning once when they stop being controllable. The caller must hold the
+// lock.
+func (fc *fanController) updateControllable() {
+	controllable := usableFanSpeedRange(fc.count, fc.limits)
+	if fc.controllable && !controllable && fc.count > 0 {
+		logger.Warn().
+			Int("min", int(fc.limits.Min)).
+			Int("max", int(fc.limits.Max)).
+			Msg("Driver reports no usable fan speed range, fan control unavailable")
+	}
+	fc.controllable = controllable
+}
+
+// detectAutoMode reads the control policy of the first fan. Cards that don't
+// report a policy are assumed to be under driver control.
+func detectAutoMode(device nvml.Device) bool {
+	policy, ret := device.GetFanControlPolicy_v2(0)
+	if !IsNVMLSuccess(ret) {
+		logger.Debug().Msgf("Failed to get fan control policy: %s", nvml.ErrorString(ret))
+		return true
+	}
+
+	return policy != nvml.FAN_POLICY_MANUAL
+}
+
+// fanOperation names a per-fan NVML operation for return code tracing
+func fanOperation(operation string, fanIndex int) string {
+	return fmt.Sprintf("%s[%d]", operation, fanIndex)
 }
@@ -0,0 +1,52 @@
+package gpu
+
+import "testing"
+
+func TestUsableFanSpeedRange(t *testing.T) {
+	tests := []struct {
+		name   string
+		count  int
+		limits FanSpeedLimits
+		want   bool
+	}{
+		{name: "range", count: 2, limits: FanSpeedLimits{Min: 30, Max: 100}, want: true},
+		{name: "no fans", count: 0, limits: FanSpeedLimits{Min: 30, Max: 100}, want: false},
+		{name: "min equals max", count: 2, limits: FanSpeedLimits{Min: 100, Max: 100}, want: false},
+		{name: "min above max", count: 1, limits: FanSpeedLimits{Min: 60, Max: 40}, want: false},
+		{name: "zero range", count: 1, limits: FanSpeedLimits{}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := usableFanSpeedRange(tt.count, tt.limits); got != tt.want {
+				t.Errorf("usableFanSpeedRange(%d, %+v) = %v, want %v", tt.count, tt.limits, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateControllableFollowsLimits(t *testing.T) {
+	fc := &fanController{
+		count:        2,
+		limits:       FanSpeedLimits{Min: 30, Max: 100},
+		controllable: true,
+	}
+
+	fc.updateControllable()
+	if !fc.controllable {
+		t.Fatal("fans with a speed range aren't controllable")
+	}
+
+	fc.limits = FanSpeedLimits{Min: 100, Max: 100}
+	fc.updateControllable()
+	fc.updateControllable()
+	if fc.controllable {
+		t.Fatal("fans without a speed range are controllable")
+	}
+
+	fc.limits = FanSpeedLimits{Min: 30, Max: 100}
+	fc.updateControllable()
+	if !fc.controllable {
+		t.Error("fans aren't controllable again once the range is back")
+	}
+}
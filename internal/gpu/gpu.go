@@ -1,7 +1,9 @@
 package gpu
 
 import (
+	"math"
 	"sync"
+	"time"
 
 	"codeberg.org/mutker/nvidiactl/internal/errors"
 	"codeberg.org/mutker/nvidiactl/internal/logger"
@@ -9,25 +11,86 @@ import (
 )
 
 const (
-	defaultDeviceIndex    = 0
-	temperatureWindowSize = 5
+	defaultDeviceIndex = 0
+	// defaultTemperatureWindowSize is the number of temperature readings
+	// summarized when no window is configured
+	defaultTemperatureWindowSize = 5
+	// maxRejectedTemperatures is the number of consecutive readings rejected
+	// as spikes before the next is accepted as a real change
+	maxRejectedTemperatures = 2
 )
 
 type controller struct {
 	nvml            nvmlController
 	device          nvml.Device
+	allDevices      []nvml.Device // All GPUs, enumerated on first use
 	fanController   FanController
 	powerController PowerController
 	tempHistory     []Temperature
+	tempWindow      int
 	tempMu          sync.RWMutex // Separate mutex for temperature history
+	tempStatistic   TemperatureStatistic
+	tempSmoothing   TemperatureSmoothing
+	emaAlpha        float64
+	tempEMA         float64 // Exponential moving average, valid once tempHistory has readings
+	tempAverage     Temperature
+	tempFilter      TemperatureFilter
+	filterDelta     Temperature
+	tempRejected    int // Consecutive readings rejected as spikes
+	utilHistory     []Utilization
+	utilMu          sync.Mutex
+	utilWindow      int
+	powerWindow     int
+	sensorIndex     int  // Requested thermal sensor, negative for the core sensor
+	useSensorIndex  bool // Whether the requested sensor was readable
+	warmupReads     int
+	readOnly        bool // Refuse all NVML writes
+	deviceSelector  string
+	deviceIndex     int // Index of the controlled GPU, negative if unknown
+	initRetries     int // Retries left for the first Initialize call
+	initBackoff     time.Duration
+	readRetry       readRetry // Retries of transient NVML read errors
+	thresholds      TemperatureThresholds
+	capabilities    Capabilities
+	tracer          *returnCodeTracer
+	eventWorkers    sync.WaitGroup
 	initialized     bool
 	mu              sync.RWMutex
 }
 
-func New() (Controller, error) {
+func New(opts ...Option) (Controller, error) {
+	o := &options{
+		temperatureStatistic: StatisticMean,
+		smoothing:            SmoothingSMA,
+		filter:               FilterNone,
+		sensorIndex:          -1,
+		utilizationWindow:    defaultUtilizationWindowSize,
+		temperatureWindow:    defaultTemperatureWindowSize,
+		powerWindow:          defaultPowerWindowSize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
 	c := &controller{
-		nvml:        &nvmlWrapper{},
-		tempHistory: make([]Temperature, 0, temperatureWindowSize),
+		nvml:           &nvmlWrapper{},
+		tempHistory:    make([]Temperature, 0, max(o.temperatureWindow, 1)),
+		tempWindow:     max(o.temperatureWindow, 1),
+		powerWindow:    max(o.powerWindow, 1),
+		tempStatistic:  o.temperatureStatistic,
+		tempSmoothing:  o.smoothing,
+		emaAlpha:       o.emaAlpha,
+		tempFilter:     o.filter,
+		filterDelta:    o.filterDelta,
+		sensorIndex:    o.sensorIndex,
+		warmupReads:    o.warmupReads,
+		readOnly:       o.readOnly,
+		deviceSelector: o.deviceSelector,
+		initRetries:    max(o.initRetries, 0),
+		initBackoff:    o.initBackoff,
+		readRetry:      readRetry{retries: max(o.readRetries, 0), delay: o.readRetryDelay},
+		utilWindow:     max(o.utilizationWindow, 1),
+		tracer:         newReturnCodeTracer(o.traceReturnCodes),
 	}
 	return c, nil
 }
@@ -41,37 +104,79 @@ func (c *controller) Initialize() error {
 		return nil
 	}
 
+	// Retries are for a driver still loading at startup, later calls
+	// are retried by the caller
+	retries := c.initRetries
+	c.initRetries = 0
+
+	// Each phase logs its duration, to tell which one slows down startup
+	start := time.Now()
+	phaseStart := start
+
 	logger.Debug().Msg("Initializing NVML...")
-	if err := c.nvml.Initialize(); err != nil {
-		logger.Debug().Err(err).Msg("NVML initialization failed")
+	if err := retryWithBackoff("nvml_init", retries, c.initBackoff, c.nvml.Initialize); err != nil {
+		logger.Debug().Err(err).Dur("elapsed", time.Since(phaseStart)).Msg("NVML initialization failed")
 		return errFactory.Wrap(ErrInitFailed, err)
 	}
+	logger.Debug().Dur("elapsed", time.Since(phaseStart)).Msg("NVML initialized")
 
+	phaseStart = time.Now()
 	logger.Debug().Msg("Getting GPU device...")
-	device, err := c.nvml.GetDevice(defaultDeviceIndex)
+	var device nvml.Device
+	var index int
+	err := retryWithBackoff("get_device", retries, c.initBackoff, func() error {
+		var err error
+		device, index, err = resolveDevice(c.nvml, c.deviceSelector)
+		return err
+	})
 	if err != nil {
-		logger.Debug().Err(err).Msg("Failed to get GPU device")
-		return errFactory.Wrap(ErrDeviceNotFound, err)
+		logger.Debug().Err(err).Dur("elapsed", time.Since(phaseStart)).Msg("Failed to get GPU device")
+		// Released so a later Initialize starts over
+		if shutdownErr := c.nvml.Shutdown(); shutdownErr != nil {
+			logger.Debug().Err(shutdownErr).Msg("Failed to shut down NVML")
+		}
+		return err
+	}
+	logger.Debug().Int("index", index).Dur("elapsed", time.Since(phaseStart)).Msg("GPU device found")
+	c.deviceIndex = index
+	if c.readOnly {
+		logger.Debug().Msg("Read-only mode, NVML writes are refused")
+		device = readOnlyDevice{device}
 	}
 	c.device = device
 
+	phaseStart = time.Now()
 	logger.Debug().Msg("Initializing fan controller...")
-	fanCtrl, err := newFanController(device)
+	fanCtrl, err := newFanController(device, c.tracer, c.readRetry)
 	if err != nil {
-		logger.Debug().Err(err).Msg("Failed to initialize fan controller")
+		logger.Debug().Err(err).Dur("elapsed", time.Since(phaseStart)).Msg("Failed to initialize fan controller")
 		return errFactory.Wrap(ErrInitFailed, err)
 	}
 	c.fanController = fanCtrl
+	logger.Debug().Dur("elapsed", time.Since(phaseStart)).Msg("Fan controller initialized")
 
+	phaseStart = time.Now()
 	logger.Debug().Msg("Initializing power controller...")
-	powerCtrl, err := newPowerController(device)
+	powerCtrl, err := newPowerController(device, c.tracer, c.readRetry, c.powerWindow)
 	if err != nil {
-		logger.Debug().Err(err).Msg("Failed to initialize power controller")
+		logger.Debug().Err(err).Dur("elapsed", time.Since(phaseStart)).Msg("Failed to initialize power controller")
 		return errFactory.Wrap(ErrInitFailed, err)
 	}
 	c.powerController = powerCtrl
+	logger.Debug().Dur("elapsed", time.Since(phaseStart)).Msg("Power controller initialized")
+
+	c.thresholds = readTemperatureThresholds(device)
+	c.capabilities = probeCapabilities(device)
+	c.useSensorIndex = checkSensorIndex(device, c.sensorIndex)
+
+	// Some drivers return a stale or zero value on the first reads
+	for i := 0; i < c.warmupReads; i++ {
+		temp, err := c.readTemperature()
+		logger.Debug().Err(err).Int("temperature", int(temp)).Int("read", i+1).Msg("Discarded warmup temperature read")
+	}
 
 	c.initialized = true
+	logger.Debug().Dur("elapsed", time.Since(start)).Msg("GPU controller initialized")
 
 	return nil
 }
@@ -86,17 +191,22 @@ func (c *controller) Shutdown() error {
 		return nil
 	}
 
+	// Event sets must be freed before NVML shuts down
+	c.eventWorkers.Wait()
+
 	if err := c.nvml.Shutdown(); err != nil {
 		logger.Debug().Err(err).Msg("NVML shutdown failed")
 		return errFactory.Wrap(ErrShutdownFailed, err)
 	}
 
+	c.allDevices = nil
 	c.initialized = false
 
 	return nil
 }
 
-// GetTemperature returns the current GPU temperature
+// GetTemperature returns the current GPU temperature, from the configured
+// thermal sensor if one is set and readable
 func (c *controller) GetTemperature() (Temperature, error) {
 	errFactory := errors.New()
 	c.mu.RLock()
@@ -106,7 +216,37 @@ func (c *controller) GetTemperature() (Temperature, error) {
 		return 0, errFactory.New(ErrNotInitialized)
 	}
 
-	temp, ret := c.device.GetTemperature(nvml.TEMPERATURE_GPU)
+	return c.readTemperature()
+}
+
+// readTemperature reads the control temperature, the caller must hold the
+// lock
+func (c *controller) readTemperature() (Temperature, error) {
+	errFactory := errors.New()
+
+	if c.useSensorIndex {
+		var temp Temperature
+		ret := c.readRetry.do("get_temperature", func() nvml.Return {
+			var ret nvml.Return
+			temp, ret = readThermalSensor(c.device, c.sensorIndex)
+			return ret
+		})
+		c.tracer.record("get_temperature", ret)
+		if !IsNVMLSuccess(ret) {
+			err := newNVMLError(ret)
+			logger.Debug().Err(err).Int("sensor_index", c.sensorIndex).Msg("Failed to read temperature")
+			return 0, errFactory.Wrap(ErrTemperatureReadFailed, err)
+		}
+		return temp, nil
+	}
+
+	var temp uint32
+	ret := c.readRetry.do("get_temperature", func() nvml.Return {
+		var ret nvml.Return
+		temp, ret = c.device.GetTemperature(nvml.TEMPERATURE_GPU)
+		return ret
+	})
+	c.tracer.record("get_temperature", ret)
 	if !IsNVMLSuccess(ret) {
 		err := newNVMLError(ret)
 		logger.Debug().Err(err).Msg("Failed to read temperature")
@@ -116,7 +256,69 @@ func (c *controller) GetTemperature() (Temperature, error) {
 	return Temperature(temp), nil
 }
 
-// GetAverageTemperature returns the moving average of GPU temperature
+// GetPerformanceState returns the current performance state, 0 (P0) being
+// the highest performance and 15 (P15) the lowest
+func (c *controller) GetPerformanceState() (int, error) {
+	errFactory := errors.New()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.initialized {
+		return 0, errFactory.New(ErrNotInitialized)
+	}
+
+	pstate, ret := c.device.GetPerformanceState()
+	c.tracer.record("get_performance_state", ret)
+	if ret == nvml.ERROR_NOT_SUPPORTED || (IsNVMLSuccess(ret) && pstate == nvml.PSTATE_UNKNOWN) {
+		return 0, errFactory.New(ErrPerformanceStateUnsupported)
+	}
+	if !IsNVMLSuccess(ret) {
+		return 0, errFactory.Wrap(ErrPerformanceStateFailed, newNVMLError(ret))
+	}
+
+	return int(pstate), nil
+}
+
+// GetTemperatureBySensor returns the current temperature of the given sensor.
+// The memory sensor is read through the NVML field value API, which is not
+// available on all cards.
+func (c *controller) GetTemperatureBySensor(sensor TemperatureSensor) (Temperature, error) {
+	errFactory := errors.New()
+
+	switch sensor {
+	case SensorCore:
+		return c.GetTemperature()
+	case SensorMemory:
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+
+		if !c.initialized {
+			return 0, errFactory.New(ErrNotInitialized)
+		}
+
+		values := []nvml.FieldValue{{FieldId: nvml.FI_DEV_MEMORY_TEMP}}
+		if ret := c.device.GetFieldValues(values); !IsNVMLSuccess(ret) {
+			c.tracer.record("get_memory_temperature", ret)
+			return 0, errFactory.Wrap(ErrTemperatureReadFailed, newNVMLError(ret))
+		}
+
+		//nolint:gosec // G115: NVML return codes are small positive integers
+		ret := nvml.Return(values[0].NvmlReturn)
+		c.tracer.record("get_memory_temperature", ret)
+		if !IsNVMLSuccess(ret) {
+			err := newNVMLError(ret)
+			logger.Debug().Err(err).Str("sensor", string(sensor)).Msg("Failed to read temperature")
+			return 0, errFactory.Wrap(ErrTemperatureReadFailed, err)
+		}
+
+		return Temperature(fieldValueToInt64(values[0])), nil
+	default:
+		return 0, errFactory.WithData(ErrUnknownSensor, sensor)
+	}
+}
+
+// GetAverageTemperature returns the moving average of GPU temperature, the
+// exponential one with SmoothingEMA
 func (c *controller) GetAverageTemperature() Temperature {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -125,6 +327,12 @@ func (c *controller) GetAverageTemperature() Temperature {
 		return 0
 	}
 
+	if c.tempSmoothing == SmoothingEMA {
+		c.tempMu.RLock()
+		defer c.tempMu.RUnlock()
+		return Temperature(math.Round(c.tempEMA))
+	}
+
 	var sum Temperature
 	for _, temp := range c.tempHistory {
 		sum += temp
@@ -133,30 +341,112 @@ func (c *controller) GetAverageTemperature() Temperature {
 	return sum / Temperature(len(c.tempHistory))
 }
 
+// GetTemperatureThresholds returns the hardware thermal limits read at
+// initialization
+func (c *controller) GetTemperatureThresholds() TemperatureThresholds {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.thresholds
+}
+
+// readTemperatureThresholds reads the thermal limits, leaving unsupported
+// ones at 0
+func readTemperatureThresholds(device nvml.Device) TemperatureThresholds {
+	read := func(threshold nvml.TemperatureThresholds, name string) Temperature {
+		temp, ret := device.GetTemperatureThreshold(threshold)
+		if !IsNVMLSuccess(ret) {
+			logger.Debug().Str("threshold", name).Msgf("Failed to get temperature threshold: %s", nvml.ErrorString(ret))
+			return 0
+		}
+		return Temperature(temp)
+	}
+
+	return TemperatureThresholds{
+		Slowdown: read(nvml.TEMPERATURE_THRESHOLD_SLOWDOWN, "slowdown"),
+		Shutdown: read(nvml.TEMPERATURE_THRESHOLD_SHUTDOWN, "shutdown"),
+		GPUMax:   read(nvml.TEMPERATURE_THRESHOLD_GPU_MAX, "gpu_max"),
+	}
+}
+
 func (c *controller) UpdateTemperatureHistory(temp Temperature) Temperature {
 	logger.Debug().Int("temp", int(temp)).Msg("Starting temperature history update")
 
 	c.tempMu.Lock()
 	defer c.tempMu.Unlock()
 
+	temp = c.filterTemperature(temp)
+
+	// The first reading seeds the average
+	if len(c.tempHistory) == 0 {
+		c.tempEMA = float64(temp)
+	} else {
+		c.tempEMA = c.emaAlpha*float64(temp) + (1-c.emaAlpha)*c.tempEMA
+	}
+
 	c.tempHistory = append(c.tempHistory, temp)
-	if len(c.tempHistory) > temperatureWindowSize {
+	if len(c.tempHistory) > c.tempWindow {
 		c.tempHistory = c.tempHistory[1:]
 	}
 
-	var sum Temperature
-	for _, t := range c.tempHistory {
-		sum += t
+	var avg Temperature
+	if c.tempSmoothing == SmoothingEMA {
+		avg = Temperature(math.Round(c.tempEMA))
+	} else {
+		avg = summarizeTemperatures(c.tempHistory, c.tempStatistic)
 	}
-	avg := sum / Temperature(len(c.tempHistory))
+
+	c.tempAverage = avg
 
 	logger.Debug().
 		Int("avgTemperature", int(avg)).
+		Str("smoothing", string(c.tempSmoothing)).
+		Str("statistic", string(c.tempStatistic)).
 		Msg("Temperature history updated")
 
 	return avg
 }
 
+// filterTemperature replaces a reading deviating more than the filter delta
+// from the current average with the median of the history. A change lasting
+// longer than maxRejectedTemperatures readings is accepted. Must be called
+// with tempMu held.
+func (c *controller) filterTemperature(temp Temperature) Temperature {
+	if c.tempFilter != FilterMedian || len(c.tempHistory) == 0 {
+		return temp
+	}
+
+	deviation := temp - c.tempAverage
+	if deviation < 0 {
+		deviation = -deviation
+	}
+	if deviation <= c.filterDelta || c.tempRejected >= maxRejectedTemperatures {
+		c.tempRejected = 0
+		return temp
+	}
+
+	c.tempRejected++
+	filtered := percentile(c.tempHistory, 0.5)
+	logger.Debug().
+		Int("temperature", int(temp)).
+		Int("filtered", int(filtered)).
+		Int("average", int(c.tempAverage)).
+		Msg("Temperature spike rejected")
+
+	return filtered
+}
+
+// GetFilteredTemperature returns the last reading added to the temperature
+// history, 0 if there is none
+func (c *controller) GetFilteredTemperature() Temperature {
+	c.tempMu.RLock()
+	defer c.tempMu.RUnlock()
+
+	if len(c.tempHistory) == 0 {
+		return 0
+	}
+	return c.tempHistory[len(c.tempHistory)-1]
+}
+
 // GetFanControl returns the fan controller interface
 func (c *controller) GetFanControl() FanController {
 	c.mu.RLock()
@@ -164,16 +454,20 @@ func (c *controller) GetFanControl() FanController {
 	return c.fanController
 }
 
-// GetCurrentFanSpeeds returns the current speeds of all fans
-func (c *controller) GetCurrentFanSpeeds() []FanSpeed {
+// GetCurrentFanSpeeds returns the current speeds of all fans. If no fan
+// could be read, the last known speeds are returned with ErrFanSpeedsUnreadable.
+func (c *controller) GetCurrentFanSpeeds() ([]FanSpeed, error) {
 	if c.fanController == nil {
-		return nil
+		return nil, nil
 	}
 	return c.fanController.GetCurrentSpeeds()
 }
 
 func (c *controller) SetFanSpeed(speed FanSpeed) error {
 	errFactory := errors.New()
+	if c.readOnly {
+		return errFactory.New(ErrReadOnly)
+	}
 	if c.fanController == nil {
 		return errFactory.New(ErrNotInitialized)
 	}
@@ -200,6 +494,9 @@ func (c *controller) GetFanSpeedLimits() FanSpeedLimits {
 // EnableAutoFanControl enables automatic fan control
 func (c *controller) EnableAutoFanControl() error {
 	errFactory := errors.New()
+	if c.readOnly {
+		return errFactory.New(ErrReadOnly)
+	}
 	if !c.initialized {
 		return errFactory.New(ErrNotInitialized)
 	}
@@ -212,6 +509,9 @@ func (c *controller) EnableAutoFanControl() error {
 // DisableAutoFanControl disables automatic fan control
 func (c *controller) DisableAutoFanControl() error {
 	errFactory := errors.New()
+	if c.readOnly {
+		return errFactory.New(ErrReadOnly)
+	}
 	if !c.initialized {
 		return errFactory.New(ErrNotInitialized)
 	}
@@ -221,6 +521,116 @@ func (c *controller) DisableAutoFanControl() error {
 	return nil
 }
 
+// ResetFanControl restores the VBIOS fan control policy and default speeds
+func (c *controller) ResetFanControl() error {
+	errFactory := errors.New()
+	if c.readOnly {
+		return errFactory.New(ErrReadOnly)
+	}
+	if !c.initialized {
+		return errFactory.New(ErrNotInitialized)
+	}
+	if err := c.fanController.ResetToDefault(); err != nil {
+		return errFactory.Wrap(ErrFanResetFailed, err)
+	}
+	return nil
+}
+
+// IsReadOnly returns whether the controller refuses all writes
+func (c *controller) IsReadOnly() bool {
+	return c.readOnly
+}
+
+// IsAutoFanControl returns whether the fans are under driver control
+func (c *controller) IsAutoFanControl() bool {
+	if c.fanController == nil {
+		return true
+	}
+	return c.fanController.IsAutoMode()
+}
+
+// RefreshLimits re-reads the fan speed and power limits and logs changes.
+// Both are refreshed even if one of them fails.
+func (c *controller) RefreshLimits() error {
+	c.mu.RLock()
+	fanController, powerController := c.fanController, c.powerController
+	c.mu.RUnlock()
+
+	var errs []error
+
+	if fanController != nil {
+		previous := fanController.GetSpeedLimits()
+		if changed, err := fanController.RefreshLimits(); err != nil {
+			errs = append(errs, err)
+		} else if changed {
+			current := fanController.GetSpeedLimits()
+			logger.Info().
+				Int("previous_min", int(previous.Min)).
+				Int("previous_max", int(previous.Max)).
+				Int("min", int(current.Min)).
+				Int("max", int(current.Max)).
+				Msg("Fan speed limits changed")
+		}
+	}
+
+	if powerController != nil {
+		previous := powerController.GetLimits()
+		if changed, err := powerController.RefreshLimits(); err != nil {
+			errs = append(errs, err)
+		} else if changed {
+			current := powerController.GetLimits()
+			logger.Info().
+				Int("previous_min", int(previous.Min)).
+				Int("previous_max", int(previous.Max)).
+				Int("previous_default", int(previous.Default)).
+				Int("min", int(current.Min)).
+				Int("max", int(current.Max)).
+				Int("default", int(current.Default)).
+				Msg("Power limits changed")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// IsFanControllable returns whether fan speeds can be set
+func (c *controller) IsFanControllable() bool {
+	if c.fanController == nil {
+		return false
+	}
+	return c.fanController.IsControllable()
+}
+
+// GetFanControlPolicy reads the control policy of a fan from the device
+func (c *controller) GetFanControlPolicy(fanIndex int) (FanControlPolicy, error) {
+	errFactory := errors.New()
+	if c.fanController == nil {
+		return 0, errFactory.New(ErrNotInitialized)
+	}
+	return c.fanController.GetControlPolicy(fanIndex)
+}
+
+// ResetState clears the temperature and power histories and re-reads the fan
+// control policy
+func (c *controller) ResetState() {
+	c.tempMu.Lock()
+	c.tempHistory = c.tempHistory[:0]
+	c.tempRejected = 0
+	c.tempMu.Unlock()
+
+	c.utilMu.Lock()
+	c.utilHistory = c.utilHistory[:0]
+	c.utilMu.Unlock()
+
+	if c.powerController != nil {
+		c.powerController.ResetHistory()
+	}
+
+	if c.fanController != nil {
+		c.fanController.RefreshAutoMode()
+	}
+}
+
 // GetPowerControl returns the power controller interface
 func (c *controller) GetPowerControl() PowerController {
 	c.mu.RLock()
@@ -228,7 +638,8 @@ func (c *controller) GetPowerControl() PowerController {
 	return c.powerController
 }
 
-// GetCurrentPowerLimit returns the current power limit
+// GetCurrentPowerLimit returns the power limit read from the device, falling
+// back to the cached limit if the read fails
 func (c *controller) GetCurrentPowerLimit() PowerLimit {
 	if c.powerController == nil {
 		return 0
@@ -236,9 +647,48 @@ func (c *controller) GetCurrentPowerLimit() PowerLimit {
 	return c.powerController.GetCurrentLimit()
 }
 
+// GetCachedPowerLimit returns the power limit this controller last set, or
+// read at startup, without an NVML call. Use it in hot paths and for status
+// reporting; use GetCurrentPowerLimit when the limit may have been changed
+// outside nvidiactl.
+func (c *controller) GetCachedPowerLimit() PowerLimit {
+	if c.powerController == nil {
+		return 0
+	}
+	return c.powerController.GetCachedLimit()
+}
+
+// GetEnforcedPowerLimit returns the power limit the card enforces
+func (c *controller) GetEnforcedPowerLimit() (PowerLimit, error) {
+	errFactory := errors.New()
+	if c.powerController == nil {
+		return 0, errFactory.New(ErrNotInitialized)
+	}
+	return c.powerController.GetEnforcedLimit()
+}
+
+func (c *controller) GetPowerUsage() (PowerLimit, error) {
+	errFactory := errors.New()
+	if c.powerController == nil {
+		return 0, errFactory.New(ErrNotInitialized)
+	}
+	return c.powerController.GetUsage()
+}
+
+// GetLastPowerLimit returns the power limit that was in effect before the last change
+func (c *controller) GetLastPowerLimit() PowerLimit {
+	if c.powerController == nil {
+		return 0
+	}
+	return c.powerController.GetLastLimit()
+}
+
 // SetPowerLimit sets the power limit
 func (c *controller) SetPowerLimit(limit PowerLimit) error {
 	errFactory := errors.New()
+	if c.readOnly {
+		return errFactory.New(ErrReadOnly)
+	}
 	if c.powerController == nil {
 		return errFactory.New(ErrNotInitialized)
 	}
@@ -263,6 +713,35 @@ func (c *controller) UpdatePowerLimitHistory(limit PowerLimit) PowerLimit {
 	return c.powerController.UpdateHistory(limit)
 }
 
+// GetTotalBoardPower reads the module power scope of the instantaneous power
+// field. Cards without a separate board sensor return ErrBoardPowerUnsupported.
+func (c *controller) GetTotalBoardPower() (PowerLimit, error) {
+	errFactory := errors.New()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.initialized {
+		return 0, errFactory.New(ErrNotInitialized)
+	}
+
+	values := []nvml.FieldValue{{FieldId: nvml.FI_DEV_POWER_INSTANT, ScopeId: nvml.POWER_SCOPE_MODULE}}
+	ret := c.device.GetFieldValues(values)
+	if IsNVMLSuccess(ret) {
+		//nolint:gosec // G115: NVML return codes are small positive integers
+		ret = nvml.Return(values[0].NvmlReturn)
+	}
+	c.tracer.record("get_board_power", ret)
+
+	switch {
+	case ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_INVALID_ARGUMENT:
+		return 0, errFactory.New(ErrBoardPowerUnsupported)
+	case !IsNVMLSuccess(ret):
+		return 0, errFactory.Wrap(ErrBoardPowerFailed, newNVMLError(ret))
+	}
+
+	return PowerLimit(fieldValueToInt64(values[0]) / milliWattsToWatts), nil
+}
+
 // Name returns the GPU device name
 func (c *controller) Name() (string, error) {
 	errFactory := errors.New()
@@ -280,3 +759,9 @@ func (c *controller) Name() (string, error) {
 
 	return name, nil
 }
+
+// DrainReturnCodes returns the NVML return codes traced since the last call.
+// Returns nil unless return code tracing is enabled.
+func (c *controller) DrainReturnCodes() []ReturnCode {
+	return c.tracer.drain()
+}
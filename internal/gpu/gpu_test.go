@@ -0,0 +1,178 @@
+package gpu
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+)
+
+// fakeNVML hands out a single device
+type fakeNVML struct {
+	device nvml.Device
+}
+
+func (f *fakeNVML) Initialize() error            { return nil }
+func (f *fakeNVML) Shutdown() error              { return nil }
+func (f *fakeNVML) GetDeviceCount() (int, error) { return 1, nil }
+
+func (f *fakeNVML) GetDevice(int) (nvml.Device, error) { return f.device, nil }
+
+func (f *fakeNVML) GetDeviceByUUID(string) (nvml.Device, error) { return f.device, nil }
+
+// newMockDevice returns a device with two fans at 40% and the power range of
+// newMockPowerDevice, reporting everything else as unsupported
+func newMockDevice() *mock.Device {
+	device := newMockPowerDevice()
+	device.GetNumFansFunc = func() (int, nvml.Return) { return 2, nvml.SUCCESS }
+	device.GetMinMaxFanSpeedFunc = func() (int, int, nvml.Return) { return 30, 100, nvml.SUCCESS }
+	device.GetFanSpeed_v2Func = func(int) (uint32, nvml.Return) { return 40, nvml.SUCCESS }
+	device.GetFanControlPolicy_v2Func = func(int) (nvml.FanControlPolicy, nvml.Return) {
+		return 0, nvml.ERROR_NOT_SUPPORTED
+	}
+	device.GetTemperatureFunc = func(nvml.TemperatureSensors) (uint32, nvml.Return) { return 60, nvml.SUCCESS }
+	device.GetTemperatureThresholdFunc = func(nvml.TemperatureThresholds) (uint32, nvml.Return) {
+		return 0, nvml.ERROR_NOT_SUPPORTED
+	}
+	device.GetThermalSettingsFunc = func(uint32) (nvml.GpuThermalSettings, nvml.Return) {
+		return nvml.GpuThermalSettings{}, nvml.ERROR_NOT_SUPPORTED
+	}
+	device.GetNameFunc = func() (string, nvml.Return) { return "Mock GPU", nvml.SUCCESS }
+	device.GetUUIDFunc = func() (string, nvml.Return) { return "GPU-mock", nvml.SUCCESS }
+	device.GetIndexFunc = func() (int, nvml.Return) { return 0, nvml.SUCCESS }
+	device.GetPowerUsageFunc = func() (uint32, nvml.Return) { return 180_000, nvml.SUCCESS }
+	device.GetPowerManagementModeFunc = func() (nvml.EnableState, nvml.Return) {
+		return nvml.FEATURE_ENABLED, nvml.SUCCESS
+	}
+	device.GetFieldValuesFunc = func([]nvml.FieldValue) nvml.Return { return nvml.ERROR_NOT_SUPPORTED }
+	device.GetPerformanceStateFunc = func() (nvml.Pstates, nvml.Return) { return nvml.PSTATE_2, nvml.SUCCESS }
+	device.GetPersistenceModeFunc = func() (nvml.EnableState, nvml.Return) {
+		return nvml.FEATURE_ENABLED, nvml.SUCCESS
+	}
+	device.GetSupportedEventTypesFunc = func() (uint64, nvml.Return) { return 0, nvml.ERROR_NOT_SUPPORTED }
+	return device
+}
+
+// captureLogs sends the debug log as JSON to a file until the test ends and
+// returns a function reading the lines logged so far
+func captureLogs(t *testing.T) func() []map[string]any {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "log.json")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("creating the log file: %v", err)
+	}
+
+	stdout := os.Stdout
+	os.Stdout = file
+	logger.Init("debug", logger.FormatJSON, true)
+	os.Stdout = stdout
+	t.Cleanup(func() {
+		logger.Init("warning", logger.FormatConsole, true)
+		file.Close()
+	})
+
+	return func() []map[string]any {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("opening the log file: %v", err)
+		}
+		defer f.Close()
+
+		var lines []map[string]any
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var line map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+				t.Fatalf("log line %q isn't JSON: %v", scanner.Text(), err)
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+}
+
+func TestInitializeLogsPhaseDurations(t *testing.T) {
+	logs := captureLogs(t)
+
+	ctrl, err := New()
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	c := ctrl.(*controller)
+	c.nvml = &fakeNVML{device: newMockDevice()}
+	if err := c.Initialize(); err != nil {
+		t.Fatalf("Initialize() unexpected error: %v", err)
+	}
+
+	logged := make(map[string]map[string]any)
+	for _, line := range logs() {
+		if msg, ok := line["message"].(string); ok {
+			logged[msg] = line
+		}
+	}
+
+	tests := []struct {
+		phase string
+		msg   string
+	}{
+		{phase: "nvml", msg: "NVML initialized"},
+		{phase: "device", msg: "GPU device found"},
+		{phase: "fan controller", msg: "Fan controller initialized"},
+		{phase: "power controller", msg: "Power controller initialized"},
+		{phase: "total", msg: "GPU controller initialized"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.phase, func(t *testing.T) {
+			line, ok := logged[tt.msg]
+			if !ok {
+				t.Fatalf("%q wasn't logged", tt.msg)
+			}
+			elapsed, ok := line["elapsed"].(float64)
+			if !ok || elapsed < 0 {
+				t.Errorf("%q logged elapsed %v, want a duration", tt.msg, line["elapsed"])
+			}
+		})
+	}
+}
+
+func TestTemperatureSmoothing(t *testing.T) {
+	readings := []Temperature{60, 70, 80, 90}
+
+	tests := []struct {
+		name      string
+		smoothing TemperatureSmoothing
+		alpha     float64
+		want      []Temperature
+	}{
+		{name: "sma over the window", smoothing: SmoothingSMA, want: []Temperature{60, 65, 70, 80}},
+		{name: "ema halves", smoothing: SmoothingEMA, alpha: 0.5, want: []Temperature{60, 65, 73, 81}},
+		{name: "ema follows with alpha 1", smoothing: SmoothingEMA, alpha: 1, want: []Temperature{60, 70, 80, 90}},
+		{name: "ema ignores the window", smoothing: SmoothingEMA, alpha: 0.2, want: []Temperature{60, 62, 66, 70}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl, err := New(WithTemperatureSmoothing(tt.smoothing, tt.alpha), WithTemperatureWindow(3))
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			for i, temp := range readings {
+				if got := ctrl.UpdateTemperatureHistory(temp); got != tt.want[i] {
+					t.Errorf("UpdateTemperatureHistory(%d) after %v = %d, want %d", temp, readings[:i], got, tt.want[i])
+				}
+			}
+			if got := ctrl.GetAverageTemperature(); got != tt.want[len(tt.want)-1] {
+				t.Errorf("GetAverageTemperature() = %d, want %d", got, tt.want[len(tt.want)-1])
+			}
+		})
+	}
+}
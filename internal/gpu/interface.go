@@ -1,21 +1,63 @@
 package gpu
 
+import (
+	"context"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
 // Controller manages GPU operations and state
 type Controller interface {
 	// Core operations
 	Initialize() error
 	Shutdown() error
 
+	// IsReadOnly returns whether the controller refuses every write, as set
+	// with WithReadOnly
+	IsReadOnly() bool
+
+	// GetCapabilities returns what the card supports, probed at initialization
+	GetCapabilities() Capabilities
+
+	// Name returns the name of the controlled GPU
+	Name() (string, error)
+
 	// Temperature management
 	GetTemperature() (Temperature, error)
+	GetTemperatureBySensor(sensor TemperatureSensor) (Temperature, error)
 	GetAverageTemperature() Temperature
+	GetTemperatureThresholds() TemperatureThresholds
 	UpdateTemperatureHistory(Temperature) Temperature
+	// GetFilteredTemperature returns the last reading added to the
+	// temperature history, after spike rejection
+	GetFilteredTemperature() Temperature
+	// GetAllTemperatures reads the core temperature of every GPU in the
+	// system, including the controlled one. The others are only read.
+	GetAllTemperatures() ([]DeviceTemperature, error)
+
+	// Performance state
+	GetPerformanceState() (int, error)
+
+	// Utilization
+	GetUtilization() (Utilization, error)
+	UpdateUtilizationHistory(Utilization) Utilization
+	// GetMemoryInfo returns the used and total frame buffer memory
+	GetMemoryInfo() (MemoryInfo, error)
 
 	// Fan control
 	GetFanControl() FanController
 	EnableAutoFanControl() error
 	DisableAutoFanControl() error
-	GetCurrentFanSpeeds() []FanSpeed
+	ResetFanControl() error
+	IsAutoFanControl() bool
+	// IsFanControllable returns whether fan speeds can be set, false for
+	// cards without fans or with an empty speed range
+	IsFanControllable() bool
+	// GetFanControlPolicy reads the control policy of a fan from the device,
+	// independent of what nvidiactl last set
+	GetFanControlPolicy(fanIndex int) (FanControlPolicy, error)
+	GetCurrentFanSpeeds() ([]FanSpeed, error)
 	SetFanSpeed(speed FanSpeed) error
 	GetLastFanSpeeds() []FanSpeed
 	GetFanSpeedLimits() FanSpeedLimits
@@ -23,20 +65,53 @@ type Controller interface {
 	// Power management
 	GetPowerControl() PowerController
 	GetCurrentPowerLimit() PowerLimit
+	GetCachedPowerLimit() PowerLimit
+	GetLastPowerLimit() PowerLimit
 	SetPowerLimit(PowerLimit) error
 	GetPowerLimits() PowerLimits
 	UpdatePowerLimitHistory(PowerLimit) PowerLimit
+
+	// GetTotalBoardPower returns the power draw of the whole board in watts,
+	// including memory and VRM losses. Only some datacenter cards report it.
+	GetTotalBoardPower() (PowerLimit, error)
+
+	// GetEnforcedPowerLimit returns the power limit the card enforces, below
+	// the requested one when the card constrains power itself
+	GetEnforcedPowerLimit() (PowerLimit, error)
+
+	// GetPowerUsage returns the current power draw of the GPU in watts
+	GetPowerUsage() (PowerLimit, error)
+
+	// RefreshLimits re-reads the fan count and the fan speed and power
+	// limits, which are otherwise cached from initialization, logging any
+	// change
+	RefreshLimits() error
+
+	// ResetState clears the temperature and power histories and re-reads the
+	// fan count and control policy, e.g. after a system resume
+	ResetState()
+
+	// WatchEvents delivers NVML device events until ctx is canceled
+	WatchEvents(ctx context.Context) (<-chan Event, error)
+
+	// Diagnostics
+	DrainReturnCodes() []ReturnCode
 }
 
 // FanController manages fan operations
 type FanController interface {
 	GetSpeed(fanIndex int) (FanSpeed, error)
-	GetCurrentSpeeds() []FanSpeed
+	GetCurrentSpeeds() ([]FanSpeed, error)
 	GetSpeedLimits() FanSpeedLimits
+	RefreshLimits() (bool, error)
 	EnableAuto() error
 	DisableAuto() error
+	ResetToDefault() error
 	SetSpeed(speed FanSpeed) error
 	IsAutoMode() bool
+	IsControllable() bool
+	GetControlPolicy(fanIndex int) (FanControlPolicy, error)
+	RefreshAutoMode() bool
 	GetLastSpeeds() []FanSpeed
 }
 
@@ -45,10 +120,15 @@ type PowerController interface {
 	GetLimit() (PowerLimit, error)
 	SetLimit(limit PowerLimit) error
 	GetLimits() PowerLimits
+	RefreshLimits() (bool, error)
 	GetLastLimit() PowerLimit
 	GetCurrentLimit() PowerLimit
+	GetCachedLimit() PowerLimit
+	GetEnforcedLimit() (PowerLimit, error)
+	GetUsage() (PowerLimit, error)
 	ResetToDefault() error
 	UpdateHistory(limit PowerLimit) PowerLimit
+	ResetHistory()
 }
 
 // Domain types for type safety and validation
@@ -57,11 +137,100 @@ type (
 	FanSpeed    int
 	PowerLimit  int
 
+	// Utilization is the percentage of time the GPU was busy
+	Utilization int
+
+	// FanControlPolicy is the NVML fan control policy of a single fan
+	FanControlPolicy int
+
+	// TemperatureSensor identifies one of the thermal sensors exposed by NVML
+	TemperatureSensor string
+
+	// TemperatureStatistic selects how the temperature history is summarized
+	TemperatureStatistic string
+
+	// TemperatureSmoothing selects how temperature readings are smoothed
+	// into the control input
+	TemperatureSmoothing string
+
+	// TemperatureFilter selects how outlier temperature readings are handled
+	TemperatureFilter string
+
 	FanSpeedLimits struct {
 		Min, Max, Default FanSpeed
 	}
 
+	// MemoryInfo is the frame buffer memory usage in MiB
+	MemoryInfo struct {
+		Used, Total int
+	}
+
 	PowerLimits struct {
 		Min, Max, Default PowerLimit
 	}
+
+	// TemperatureThresholds are the hardware thermal limits, 0 if the card
+	// doesn't report them
+	TemperatureThresholds struct {
+		// Slowdown is where the hardware starts throttling clocks
+		Slowdown Temperature
+		// Shutdown is where the hardware shuts down to protect itself
+		Shutdown Temperature
+		// GPUMax is the maximum operating temperature of the GPU
+		GPUMax Temperature
+	}
+
+	// ReturnCode is the raw result of a single NVML operation
+	ReturnCode struct {
+		Timestamp time.Time
+		Operation string
+		Code      int
+		Message   string
+	}
+)
+
+// Supported temperature sensors
+const (
+	SensorCore   TemperatureSensor = "core"
+	SensorMemory TemperatureSensor = "memory"
+)
+
+// Fan control policies as reported by NVML
+const (
+	// FanPolicyAuto is temperature based control by the driver or VBIOS
+	FanPolicyAuto FanControlPolicy = FanControlPolicy(nvml.FAN_POLICY_TEMPERATURE_CONTINOUS_SW)
+	// FanPolicyManual is a fixed speed set through NVML
+	FanPolicyManual FanControlPolicy = FanControlPolicy(nvml.FAN_POLICY_MANUAL)
+)
+
+// String returns the policy name used in logs and the status socket
+func (p FanControlPolicy) String() string {
+	switch p {
+	case FanPolicyAuto:
+		return "auto"
+	case FanPolicyManual:
+		return "manual"
+	default:
+		return "unknown"
+	}
+}
+
+const (
+	StatisticMean TemperatureStatistic = "mean"
+	StatisticMax  TemperatureStatistic = "max"
+	StatisticP90  TemperatureStatistic = "p90"
+)
+
+const (
+	// SmoothingSMA summarizes the temperature window with the statistic
+	SmoothingSMA TemperatureSmoothing = "sma"
+	// SmoothingEMA uses an exponential moving average of all readings
+	SmoothingEMA TemperatureSmoothing = "ema"
+)
+
+const (
+	// FilterNone adds every reading to the history
+	FilterNone TemperatureFilter = "none"
+	// FilterMedian replaces spikes with the median of the history
+	FilterMedian TemperatureFilter = "median"
 )
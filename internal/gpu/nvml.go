@@ -1,6 +1,11 @@
 package gpu
 
 import (
+	"encoding/binary"
+	"math"
+	"sync"
+	"time"
+
 	"codeberg.org/mutker/nvidiactl/internal/errors"
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 )
@@ -91,3 +96,70 @@ func (w *nvmlWrapper) GetDeviceByUUID(uuid string) (nvml.Device, error) {
 
 	return device, nil
 }
+
+// fieldValueToInt64 decodes the raw value of an NVML field value according to its type
+func fieldValueToInt64(fv nvml.FieldValue) int64 {
+	//nolint:gosec // G115: value widths are dictated by the NVML value type
+	switch nvml.ValueType(fv.ValueType) {
+	case nvml.VALUE_TYPE_DOUBLE:
+		return int64(math.Float64frombits(binary.LittleEndian.Uint64(fv.Value[:])))
+	case nvml.VALUE_TYPE_UNSIGNED_INT:
+		return int64(binary.LittleEndian.Uint32(fv.Value[:4]))
+	case nvml.VALUE_TYPE_SIGNED_INT:
+		return int64(int32(binary.LittleEndian.Uint32(fv.Value[:4])))
+	case nvml.VALUE_TYPE_UNSIGNED_LONG, nvml.VALUE_TYPE_UNSIGNED_LONG_LONG:
+		return int64(binary.LittleEndian.Uint64(fv.Value[:]))
+	default:
+		return int64(binary.LittleEndian.Uint64(fv.Value[:]))
+	}
+}
+
+// maxTracedReturnCodes bounds the trace buffer if it isn't drained
+const maxTracedReturnCodes = 1024
+
+// returnCodeTracer collects raw NVML return codes for debugging
+type returnCodeTracer struct {
+	enabled bool
+	records []ReturnCode
+	mu      sync.Mutex
+}
+
+func newReturnCodeTracer(enabled bool) *returnCodeTracer {
+	return &returnCodeTracer{enabled: enabled}
+}
+
+// record stores the return code of an NVML operation if tracing is enabled
+func (t *returnCodeTracer) record(operation string, ret nvml.Return) {
+	if t == nil || !t.enabled {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.records) >= maxTracedReturnCodes {
+		t.records = t.records[1:]
+	}
+
+	t.records = append(t.records, ReturnCode{
+		Timestamp: time.Now(),
+		Operation: operation,
+		Code:      int(ret),
+		Message:   nvml.ErrorString(ret),
+	})
+}
+
+// drain returns and clears the recorded return codes
+func (t *returnCodeTracer) drain() []ReturnCode {
+	if t == nil || !t.enabled {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	records := t.records
+	t.records = nil
+
+	return records
+}
@@ -0,0 +1,125 @@
+package gpu
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+type (
+	// DeviceTemperature is the core temperature of one GPU in the system
+	DeviceTemperature struct {
+		Index       int         `json:"index"`
+		Name        string      `json:"name"`
+		Temperature Temperature `json:"temperature"`
+		// Controlled is set for the GPU nvidiactl controls
+		Controlled bool `json:"controlled"`
+	}
+
+	// TemperatureAggregate summarizes the temperatures of all GPUs
+	TemperatureAggregate struct {
+		Count int         `json:"count"`
+		Min   Temperature `json:"min"`
+		Max   Temperature `json:"max"`
+		Mean  float64     `json:"mean"`
+		// Hottest is the index of the hottest GPU
+		Hottest int `json:"hottest"`
+		// Spread is the difference between the hottest and coolest GPU
+		Spread Temperature `json:"spread"`
+	}
+)
+
+// AggregateTemperatures summarizes the temperatures of several GPUs. An
+// empty slice gives a zero aggregate.
+func AggregateTemperatures(temperatures []DeviceTemperature) TemperatureAggregate {
+	if len(temperatures) == 0 {
+		return TemperatureAggregate{}
+	}
+
+	aggregate := TemperatureAggregate{
+		Count:   len(temperatures),
+		Min:     temperatures[0].Temperature,
+		Max:     temperatures[0].Temperature,
+		Hottest: temperatures[0].Index,
+	}
+
+	var sum float64
+	for _, t := range temperatures {
+		sum += float64(t.Temperature)
+		aggregate.Min = min(aggregate.Min, t.Temperature)
+		if t.Temperature > aggregate.Max {
+			aggregate.Max = t.Temperature
+			aggregate.Hottest = t.Index
+		}
+	}
+	aggregate.Mean = sum / float64(len(temperatures))
+	aggregate.Spread = aggregate.Max - aggregate.Min
+
+	return aggregate
+}
+
+// GetAllTemperatures reads the core temperature of every GPU in the system.
+// GPUs other than the controlled one are only read, never changed. GPUs that
+// can't be read are left out.
+func (c *controller) GetAllTemperatures() ([]DeviceTemperature, error) {
+	errFactory := errors.New()
+
+	devices, err := c.devices()
+	if err != nil {
+		return nil, err
+	}
+
+	temperatures := make([]DeviceTemperature, 0, len(devices))
+	for index, device := range devices {
+		temp, ret := device.GetTemperature(nvml.TEMPERATURE_GPU)
+		if !IsNVMLSuccess(ret) {
+			logger.Debug().Int("gpu", index).Msgf("Failed to get temperature: %s", nvml.ErrorString(ret))
+			continue
+		}
+
+		name, _ := device.GetName()
+		temperatures = append(temperatures, DeviceTemperature{
+			Index:       index,
+			Name:        name,
+			Temperature: Temperature(temp),
+			Controlled:  index == c.deviceIndex,
+		})
+	}
+
+	if len(temperatures) == 0 {
+		return nil, errFactory.New(ErrTemperatureReadFailed)
+	}
+
+	return temperatures, nil
+}
+
+// devices returns the handles of all GPUs, enumerated on first use
+func (c *controller) devices() ([]nvml.Device, error) {
+	errFactory := errors.New()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.initialized {
+		return nil, errFactory.New(ErrNotInitialized)
+	}
+	if c.allDevices != nil {
+		return c.allDevices, nil
+	}
+
+	count, err := c.nvml.GetDeviceCount()
+	if err != nil {
+		return nil, errFactory.Wrap(ErrDeviceNotFound, err)
+	}
+
+	devices := make([]nvml.Device, 0, count)
+	for index := 0; index < count; index++ {
+		device, err := c.nvml.GetDevice(index)
+		if err != nil {
+			return nil, errFactory.Wrap(ErrDeviceNotFound, err)
+		}
+		devices = append(devices, device)
+	}
+	c.allDevices = devices
+
+	return devices, nil
+}
@@ -10,8 +10,10 @@ import (
 )
 
 const (
-	milliWattsToWatts    = 1000
-	powerLimitWindowSize = 5
+	milliWattsToWatts = 1000
+	// defaultPowerWindowSize is the number of power limits averaged when no
+	// window is configured
+	defaultPowerWindowSize = 5
 )
 
 type powerController struct {
@@ -20,14 +22,22 @@ type powerController struct {
 	currentLimit PowerLimit
 	lastLimit    PowerLimit
 	powerHistory []PowerLimit
+	window       int
+	tracer       *returnCodeTracer
+	readRetry    readRetry
 	mu           sync.RWMutex
 }
 
-func newPowerController(device nvml.Device) (PowerController, error) {
+func newPowerController(
+	device nvml.Device, tracer *returnCodeTracer, retry readRetry, window int,
+) (PowerController, error) {
 	errFactory := errors.New()
 	pc := &powerController{
 		device:       device,
-		powerHistory: make([]PowerLimit, 0, powerLimitWindowSize),
+		powerHistory: make([]PowerLimit, 0, window),
+		window:       window,
+		tracer:       tracer,
+		readRetry:    retry,
 	}
 
 	minLimit, maxLimit, ret := device.GetPowerManagementLimitConstraints()
@@ -63,7 +73,13 @@ func (pc *powerController) GetLimit() (PowerLimit, error) {
 	pc.mu.RLock()
 	defer pc.mu.RUnlock()
 
-	limit, ret := pc.device.GetPowerManagementLimit()
+	var limit uint32
+	ret := pc.readRetry.do("get_power_limit", func() nvml.Return {
+		var ret nvml.Return
+		limit, ret = pc.device.GetPowerManagementLimit()
+		return ret
+	})
+	pc.tracer.record("get_power_limit", ret)
 	if !IsNVMLSuccess(ret) {
 		return 0, errFactory.Wrap(ErrPowerLimitFailed, newNVMLError(ret))
 	}
@@ -82,6 +98,7 @@ func (pc *powerController) SetLimit(limit PowerLimit) error {
 
 	limitInMilliWatts := wattsToMilliWatts(limit)
 	ret := pc.device.SetPowerManagementLimit(limitInMilliWatts)
+	pc.tracer.record("set_power_limit", ret)
 	if !IsNVMLSuccess(ret) {
 		return errFactory.Wrap(ErrSetPowerLimit, newNVMLError(ret))
 	}
@@ -98,6 +115,36 @@ func (pc *powerController) GetLimits() PowerLimits {
 	return pc.limits
 }
 
+// RefreshLimits re-reads the power limit constraints and the default limit
+// and reports whether they changed
+func (pc *powerController) RefreshLimits() (bool, error) {
+	errFactory := errors.New()
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	minLimit, maxLimit, ret := pc.device.GetPowerManagementLimitConstraints()
+	pc.tracer.record("get_power_limit_constraints", ret)
+	if !IsNVMLSuccess(ret) {
+		return false, errFactory.Wrap(ErrPowerLimitsFailed, newNVMLError(ret))
+	}
+
+	defaultLimit, ret := pc.device.GetPowerManagementDefaultLimit()
+	pc.tracer.record("get_default_power_limit", ret)
+	if !IsNVMLSuccess(ret) {
+		return false, errFactory.Wrap(ErrPowerLimitsFailed, newNVMLError(ret))
+	}
+
+	limits := PowerLimits{
+		Min:     PowerLimit(minLimit / milliWattsToWatts),
+		Max:     PowerLimit(maxLimit / milliWattsToWatts),
+		Default: PowerLimit(defaultLimit / milliWattsToWatts),
+	}
+	changed := limits != pc.limits
+	pc.limits = limits
+
+	return changed, nil
+}
+
 func (pc *powerController) GetLastLimit() PowerLimit {
 	pc.mu.RLock()
 	defer pc.mu.RUnlock()
@@ -108,7 +155,13 @@ func (pc *powerController) GetCurrentLimit() PowerLimit {
 	pc.mu.RLock()
 	defer pc.mu.RUnlock()
 
-	limit, ret := pc.device.GetPowerManagementLimit()
+	var limit uint32
+	ret := pc.readRetry.do("get_power_limit", func() nvml.Return {
+		var ret nvml.Return
+		limit, ret = pc.device.GetPowerManagementLimit()
+		return ret
+	})
+	pc.tracer.record("get_power_limit", ret)
 	if !IsNVMLSuccess(ret) {
 		logger.Debug().Msgf("Failed to get power limit: %s", nvml.ErrorString(ret))
 		return pc.currentLimit
@@ -120,6 +173,61 @@ func (pc *powerController) GetCurrentLimit() PowerLimit {
 	return currentLimit
 }
 
+// GetEnforcedLimit reads the power limit the card actually enforces, which
+// may be below the requested limit when the card constrains power itself
+func (pc *powerController) GetEnforcedLimit() (PowerLimit, error) {
+	errFactory := errors.New()
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	var limit uint32
+	ret := pc.readRetry.do("get_enforced_power_limit", func() nvml.Return {
+		var ret nvml.Return
+		limit, ret = pc.device.GetEnforcedPowerLimit()
+		return ret
+	})
+	pc.tracer.record("get_enforced_power_limit", ret)
+	if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_FUNCTION_NOT_FOUND {
+		return 0, errFactory.Wrap(ErrEnforcedPowerLimitUnsupported, newNVMLError(ret))
+	}
+	if !IsNVMLSuccess(ret) {
+		return 0, errFactory.Wrap(ErrEnforcedPowerLimitFailed, newNVMLError(ret))
+	}
+
+	return PowerLimit(limit / milliWattsToWatts), nil
+}
+
+// GetUsage reads the current power draw from the device
+func (pc *powerController) GetUsage() (PowerLimit, error) {
+	errFactory := errors.New()
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+
+	var usage uint32
+	ret := pc.readRetry.do("get_power_usage", func() nvml.Return {
+		var ret nvml.Return
+		usage, ret = pc.device.GetPowerUsage()
+		return ret
+	})
+	pc.tracer.record("get_power_usage", ret)
+	if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_FUNCTION_NOT_FOUND {
+		return 0, errFactory.Wrap(ErrPowerUsageUnsupported, newNVMLError(ret))
+	}
+	if !IsNVMLSuccess(ret) {
+		return 0, errFactory.Wrap(ErrPowerUsageFailed, newNVMLError(ret))
+	}
+
+	return PowerLimit(usage / milliWattsToWatts), nil
+}
+
+// GetCachedLimit returns the limit last set or read at startup without
+// querying the device
+func (pc *powerController) GetCachedLimit() PowerLimit {
+	pc.mu.RLock()
+	defer pc.mu.RUnlock()
+	return pc.currentLimit
+}
+
 func (pc *powerController) ResetToDefault() error {
 	return pc.SetLimit(pc.limits.Default)
 }
@@ -129,7 +237,7 @@ func (pc *powerController) UpdateHistory(limit PowerLimit) PowerLimit {
 	defer pc.mu.Unlock()
 
 	pc.powerHistory = append(pc.powerHistory, limit)
-	if len(pc.powerHistory) > powerLimitWindowSize {
+	if len(pc.powerHistory) > pc.window {
 		pc.powerHistory = pc.powerHistory[1:]
 	}
 
@@ -141,6 +249,13 @@ func (pc *powerController) UpdateHistory(limit PowerLimit) PowerLimit {
 	return sum / PowerLimit(len(pc.powerHistory))
 }
 
+func (pc *powerController) ResetHistory() {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.powerHistory = pc.powerHistory[:0]
+}
+
 func wattsToMilliWatts(watts PowerLimit) uint32 {
 	if watts <= 0 {
 		return 0
@@ -0,0 +1,80 @@
+package gpu
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+)
+
+// newMockPowerDevice returns a device with a 100-300W range currently
+// limited to 250W, remembering the limit set
+func newMockPowerDevice() *mock.Device {
+	limit := uint32(250_000)
+	return &mock.Device{
+		GetPowerManagementLimitConstraintsFunc: func() (uint32, uint32, nvml.Return) {
+			return 100_000, 300_000, nvml.SUCCESS
+		},
+		GetPowerManagementDefaultLimitFunc: func() (uint32, nvml.Return) {
+			return 250_000, nvml.SUCCESS
+		},
+		GetPowerManagementLimitFunc: func() (uint32, nvml.Return) {
+			return limit, nvml.SUCCESS
+		},
+		SetPowerManagementLimitFunc: func(v uint32) nvml.Return {
+			limit = v
+			return nvml.SUCCESS
+		},
+	}
+}
+
+func TestCachedPowerLimitFollowsSetLimit(t *testing.T) {
+	device := newMockPowerDevice()
+	pc, err := newPowerController(device, newReturnCodeTracer(false), readRetry{}, defaultPowerWindowSize)
+	if err != nil {
+		t.Fatalf("newPowerController() unexpected error: %v", err)
+	}
+	reads := len(device.GetPowerManagementLimitCalls())
+
+	if got := pc.GetCachedLimit(); got != 250 {
+		t.Errorf("GetCachedLimit() at startup = %dW, want 250W", got)
+	}
+
+	if err := pc.SetLimit(200); err != nil {
+		t.Fatalf("SetLimit(200) unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if got := pc.GetCachedLimit(); got != 200 {
+			t.Errorf("GetCachedLimit() after SetLimit(200) = %dW, want 200W", got)
+		}
+	}
+	if got := len(device.GetPowerManagementLimitCalls()) - reads; got != 0 {
+		t.Errorf("GetCachedLimit() read the limit from NVML %d times, want 0", got)
+	}
+
+	// The authoritative read still goes to the device
+	limit, err := pc.GetLimit()
+	if err != nil || limit != 200 {
+		t.Errorf("GetLimit() = %dW, %v, want 200W", limit, err)
+	}
+	if got := len(device.GetPowerManagementLimitCalls()) - reads; got != 1 {
+		t.Errorf("GetLimit() read the limit from NVML %d times, want 1", got)
+	}
+}
+
+func TestCachedPowerLimitKeptOnFailedSetLimit(t *testing.T) {
+	device := newMockPowerDevice()
+	device.SetPowerManagementLimitFunc = func(uint32) nvml.Return { return nvml.ERROR_NO_PERMISSION }
+
+	pc, err := newPowerController(device, newReturnCodeTracer(false), readRetry{}, defaultPowerWindowSize)
+	if err != nil {
+		t.Fatalf("newPowerController() unexpected error: %v", err)
+	}
+
+	if err := pc.SetLimit(200); err == nil {
+		t.Fatal("SetLimit(200) succeeded without permission")
+	}
+	if got := pc.GetCachedLimit(); got != 250 {
+		t.Errorf("GetCachedLimit() after a failed SetLimit = %dW, want the unchanged 250W", got)
+	}
+}
@@ -0,0 +1,34 @@
+package gpu
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// readOnlyDevice refuses every NVML call that changes the device, so a
+// read-only controller can't write through any path, including the fan and
+// power controllers returned by GetFanControl and GetPowerControl
+type readOnlyDevice struct {
+	nvml.Device
+}
+
+func (readOnlyDevice) refuse(operation string) nvml.Return {
+	logger.Warn().Str("operation", operation).Msg("Refused NVML write in read-only mode")
+	return nvml.ERROR_NO_PERMISSION
+}
+
+func (d readOnlyDevice) SetFanSpeed_v2(int, int) nvml.Return {
+	return d.refuse("set_fan_speed")
+}
+
+func (d readOnlyDevice) SetDefaultFanSpeed_v2(int) nvml.Return {
+	return d.refuse("set_default_fan_speed")
+}
+
+func (d readOnlyDevice) SetFanControlPolicy(int, nvml.FanControlPolicy) nvml.Return {
+	return d.refuse("set_fan_control_policy")
+}
+
+func (d readOnlyDevice) SetPowerManagementLimit(uint32) nvml.Return {
+	return d.refuse("set_power_limit")
+}
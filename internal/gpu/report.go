@@ -0,0 +1,73 @@
+package gpu
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// SystemInfo describes the driver and the selected device, as included in
+// bug reports. Values that can't be read are left empty.
+type SystemInfo struct {
+	DriverVersion     string `json:"driver_version"`
+	NVMLVersion       string `json:"nvml_version"`
+	CUDADriverVersion int    `json:"cuda_driver_version"`
+	DeviceCount       int    `json:"device_count"`
+
+	Capabilities          Capabilities          `json:"capabilities"`
+	FanSpeedLimits        FanSpeedLimits        `json:"fan_speed_limits"`
+	PowerLimits           PowerLimits           `json:"power_limits"`
+	TemperatureThresholds TemperatureThresholds `json:"temperature_thresholds"`
+}
+
+// ProbeSystem initializes NVML, reads the driver versions and probes the
+// selected device with read-only calls, then shuts NVML down again. Use it
+// when no controller is running.
+func ProbeSystem(selector string) (SystemInfo, error) {
+	errFactory := errors.New()
+
+	wrapper := &nvmlWrapper{}
+	if err := wrapper.Initialize(); err != nil {
+		return SystemInfo{}, errFactory.Wrap(ErrInitFailed, err)
+	}
+	defer func() {
+		if err := wrapper.Shutdown(); err != nil {
+			logger.Debug().Err(err).Msg("NVML shutdown failed")
+		}
+	}()
+
+	var info SystemInfo
+	if version, ret := nvml.SystemGetDriverVersion(); IsNVMLSuccess(ret) {
+		info.DriverVersion = version
+	}
+	if version, ret := nvml.SystemGetNVMLVersion(); IsNVMLSuccess(ret) {
+		info.NVMLVersion = version
+	}
+	if version, ret := nvml.SystemGetCudaDriverVersion(); IsNVMLSuccess(ret) {
+		info.CUDADriverVersion = version
+	}
+	if count, err := wrapper.GetDeviceCount(); err == nil {
+		info.DeviceCount = count
+	}
+
+	device, _, err := resolveDevice(wrapper, selector)
+	if err != nil {
+		return info, err
+	}
+
+	info.Capabilities = probeCapabilities(device)
+	info.TemperatureThresholds = readTemperatureThresholds(device)
+
+	if minSpeed, maxSpeed, ret := device.GetMinMaxFanSpeed(); IsNVMLSuccess(ret) {
+		info.FanSpeedLimits = FanSpeedLimits{Min: FanSpeed(minSpeed), Max: FanSpeed(maxSpeed)}
+	}
+	if minLimit, maxLimit, ret := device.GetPowerManagementLimitConstraints(); IsNVMLSuccess(ret) {
+		info.PowerLimits.Min = PowerLimit(minLimit / milliWattsToWatts)
+		info.PowerLimits.Max = PowerLimit(maxLimit / milliWattsToWatts)
+	}
+	if defaultLimit, ret := device.GetPowerManagementDefaultLimit(); IsNVMLSuccess(ret) {
+		info.PowerLimits.Default = PowerLimit(defaultLimit / milliWattsToWatts)
+	}
+
+	return info, nil
+}
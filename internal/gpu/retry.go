@@ -0,0 +1,83 @@
+package gpu
+
+import (
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// retryWithBackoff runs fn, retrying it up to retries times while it fails.
+// The first retry waits backoff, each later one twice as long as the
+// previous. Failed attempts are logged at warn level with the NVML return
+// code when there is one.
+func retryWithBackoff(step string, retries int, backoff time.Duration, fn func() error) error {
+	err := fn()
+	for attempt := 1; err != nil && attempt <= retries; attempt++ {
+		log := logger.Warn().
+			Err(err).
+			Str("step", step).
+			Int("attempt", attempt).
+			Int("retries", retries).
+			Dur("backoff", backoff)
+		if ret, ok := nvmlReturn(err); ok {
+			log = log.Int("nvml_return", int(ret))
+		}
+		log.Msg("GPU initialization step failed, retrying")
+
+		time.Sleep(backoff)
+		backoff *= 2
+		err = fn()
+	}
+
+	return err
+}
+
+// nvmlReturn extracts the NVML return code from an error chain
+func nvmlReturn(err error) (nvml.Return, bool) {
+	var nvmlErr *nvmlError
+	if errors.As(err, &nvmlErr) {
+		return nvmlErr.ret, true
+	}
+	return nvml.SUCCESS, false
+}
+
+// transientReturns are the NVML errors worth retrying, returned while the
+// GPU is briefly busy. Any other error, like ERROR_NOT_SUPPORTED, is
+// permanent and returned at once.
+var transientReturns = map[nvml.Return]bool{
+	nvml.ERROR_UNKNOWN: true,
+	nvml.ERROR_TIMEOUT: true,
+	nvml.ERROR_IN_USE:  true,
+}
+
+// readRetry retries NVML reads failing with a transient error
+type readRetry struct {
+	retries int
+	delay   time.Duration
+}
+
+// do calls fn until it succeeds, fails permanently or the retries are used
+// up, and returns its last return code. Retries are logged at debug level to
+// show how flaky the driver is.
+func (r readRetry) do(op string, fn func() nvml.Return) nvml.Return {
+	ret := fn()
+
+	retried := 0
+	for retried < r.retries && transientReturns[ret] {
+		retried++
+		time.Sleep(r.delay)
+		ret = fn()
+	}
+
+	if retried > 0 {
+		logger.Debug().
+			Str("operation", op).
+			Int("retries", retried).
+			Str("result", nvml.ErrorString(ret)).
+			Msg("Retried transient NVML error")
+	}
+
+	return ret
+}
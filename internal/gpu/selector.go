@@ -0,0 +1,103 @@
+package gpu
+
+import (
+	"strconv"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// resolveDevice returns the device a selector names along with its index.
+// An empty selector is the first GPU, an integer is the index NVML reports
+// and anything else is taken as a UUID, which stays the same when indices
+// shuffle between boots.
+func resolveDevice(w nvmlController, selector string) (nvml.Device, int, error) {
+	errFactory := errors.New()
+
+	notFound := func(err error) error {
+		return errFactory.WithData(ErrDeviceNotFound, struct {
+			Selector string
+			Error    string
+		}{
+			Selector: selector,
+			Error:    err.Error(),
+		})
+	}
+
+	if selector == "" {
+		device, err := w.GetDevice(defaultDeviceIndex)
+		if err != nil {
+			return nil, 0, errFactory.Wrap(ErrDeviceNotFound, err)
+		}
+		return device, defaultDeviceIndex, nil
+	}
+
+	if index, err := strconv.Atoi(selector); err == nil {
+		device, err := w.GetDevice(index)
+		if err != nil {
+			return nil, 0, notFound(err)
+		}
+		return device, index, nil
+	}
+
+	device, err := w.GetDeviceByUUID(selector)
+	if err != nil {
+		return nil, 0, notFound(err)
+	}
+	index, ret := device.GetIndex()
+	if !IsNVMLSuccess(ret) {
+		index = -1
+	}
+
+	return device, index, nil
+}
+
+// DeviceInfo identifies a GPU for the device setting
+type DeviceInfo struct {
+	Index int    `json:"index"`
+	Name  string `json:"name"`
+	UUID  string `json:"uuid"`
+}
+
+// ListDevices initializes NVML, returns the index, name and UUID of every
+// GPU and shuts NVML down again. Use it when no controller is running.
+func ListDevices() ([]DeviceInfo, error) {
+	errFactory := errors.New()
+
+	wrapper := &nvmlWrapper{}
+	if err := wrapper.Initialize(); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := wrapper.Shutdown(); err != nil {
+			logger.Debug().Err(err).Msg("NVML shutdown failed")
+		}
+	}()
+
+	count, err := wrapper.GetDeviceCount()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]DeviceInfo, 0, count)
+	for index := 0; index < count; index++ {
+		device, err := wrapper.GetDevice(index)
+		if err != nil {
+			return nil, err
+		}
+
+		name, ret := device.GetName()
+		if !IsNVMLSuccess(ret) {
+			return nil, errFactory.Wrap(ErrDeviceInfoFailed, newNVMLError(ret))
+		}
+		uuid, ret := device.GetUUID()
+		if !IsNVMLSuccess(ret) {
+			return nil, errFactory.Wrap(ErrDeviceUUIDFailed, newNVMLError(ret))
+		}
+
+		devices = append(devices, DeviceInfo{Index: index, Name: name, UUID: uuid})
+	}
+
+	return devices, nil
+}
@@ -0,0 +1,35 @@
+package gpu
+
+import (
+	"math"
+	"slices"
+)
+
+// summarizeTemperatures reduces a non-empty temperature window to a single
+// value. Unknown statistics fall back to the mean.
+func summarizeTemperatures(history []Temperature, statistic TemperatureStatistic) Temperature {
+	switch statistic {
+	case StatisticMax:
+		return slices.Max(history)
+	case StatisticP90:
+		return percentile(history, 0.9)
+	case StatisticMean:
+		fallthrough
+	default:
+		var sum Temperature
+		for _, t := range history {
+			sum += t
+		}
+		return sum / Temperature(len(history))
+	}
+}
+
+// percentile returns the nearest-rank percentile p (0-1) of the values
+func percentile(values []Temperature, p float64) Temperature {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+
+	rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
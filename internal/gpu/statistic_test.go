@@ -0,0 +1,40 @@
+package gpu
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSummarizeTemperatures(t *testing.T) {
+	spike := []Temperature{60, 62, 61, 70, 65}
+	ten := []Temperature{64, 61, 69, 60, 67, 62, 68, 63, 66, 65}
+
+	tests := []struct {
+		name      string
+		history   []Temperature
+		statistic TemperatureStatistic
+		want      Temperature
+	}{
+		{name: "mean truncates", history: spike, statistic: StatisticMean, want: 63},
+		{name: "max", history: spike, statistic: StatisticMax, want: 70},
+		{name: "p90 of five is the maximum", history: spike, statistic: StatisticP90, want: 70},
+		{name: "p90 of ten", history: ten, statistic: StatisticP90, want: 68},
+		{name: "mean of ten", history: ten, statistic: StatisticMean, want: 64},
+		{name: "max of ten", history: ten, statistic: StatisticMax, want: 69},
+		{name: "single reading mean", history: []Temperature{55}, statistic: StatisticMean, want: 55},
+		{name: "single reading p90", history: []Temperature{55}, statistic: StatisticP90, want: 55},
+		{name: "unknown falls back to the mean", history: spike, statistic: "median", want: 63},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := slices.Clone(tt.history)
+			if got := summarizeTemperatures(history, tt.statistic); got != tt.want {
+				t.Errorf("summarizeTemperatures(%v, %s) = %d, want %d", tt.history, tt.statistic, got, tt.want)
+			}
+			if !slices.Equal(history, tt.history) {
+				t.Errorf("summarizeTemperatures(%v, %s) reordered the window to %v", tt.history, tt.statistic, history)
+			}
+		})
+	}
+}
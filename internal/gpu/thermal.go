@@ -0,0 +1,86 @@
+package gpu
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// ThermalSensor is one of the sensors reported by the NVML thermal settings,
+// selected for the control input by its index
+type ThermalSensor struct {
+	Index int `json:"index"`
+	// Target is what the sensor measures, e.g. gpu, memory or board
+	Target      string      `json:"target"`
+	Temperature Temperature `json:"temperature"`
+}
+
+// thermalTargets names the NVML thermal targets
+var thermalTargets = map[nvml.ThermalTarget]string{
+	nvml.THERMAL_TARGET_NONE:         "none",
+	nvml.THERMAL_TARGET_GPU:          "gpu",
+	nvml.THERMAL_TARGET_MEMORY:       "memory",
+	nvml.THERMAL_TARGET_POWER_SUPPLY: "power_supply",
+	nvml.THERMAL_TARGET_BOARD:        "board",
+	nvml.THERMAL_TARGET_VCD_BOARD:    "vcd_board",
+	nvml.THERMAL_TARGET_VCD_INLET:    "vcd_inlet",
+	nvml.THERMAL_TARGET_VCD_OUTLET:   "vcd_outlet",
+}
+
+// readThermalSensors reads all sensors of the NVML thermal settings, which
+// only some cards report
+func readThermalSensors(device nvml.Device) ([]ThermalSensor, nvml.Return) {
+	//nolint:gosec // G115: THERMAL_TARGET_ALL is a small positive constant
+	settings, ret := device.GetThermalSettings(uint32(nvml.THERMAL_TARGET_ALL))
+	if !IsNVMLSuccess(ret) {
+		return nil, ret
+	}
+
+	count := min(int(settings.Count), len(settings.Sensor))
+	sensors := make([]ThermalSensor, 0, count)
+	for i := 0; i < count; i++ {
+		sensor := settings.Sensor[i]
+		target, ok := thermalTargets[nvml.ThermalTarget(sensor.Target)]
+		if !ok {
+			target = "unknown"
+		}
+		sensors = append(sensors, ThermalSensor{
+			Index:       i,
+			Target:      target,
+			Temperature: Temperature(sensor.CurrentTemp),
+		})
+	}
+
+	return sensors, ret
+}
+
+// readThermalSensor reads the temperature of the sensor at index
+func readThermalSensor(device nvml.Device, index int) (Temperature, nvml.Return) {
+	sensors, ret := readThermalSensors(device)
+	if !IsNVMLSuccess(ret) {
+		return 0, ret
+	}
+	if index < 0 || index >= len(sensors) {
+		return 0, nvml.ERROR_INVALID_ARGUMENT
+	}
+
+	return sensors[index].Temperature, ret
+}
+
+// checkSensorIndex returns whether the sensor at index can be read, warning
+// that the core sensor is used instead if not
+func checkSensorIndex(device nvml.Device, index int) bool {
+	if index < 0 {
+		return false
+	}
+
+	if _, ret := readThermalSensor(device, index); !IsNVMLSuccess(ret) {
+		logger.Warn().
+			Int("temperature_sensor_index", index).
+			Str("error", nvml.ErrorString(ret)).
+			Msg("Temperature sensor not readable, using the core sensor instead")
+		return false
+	}
+
+	logger.Debug().Int("temperature_sensor_index", index).Msg("Reading temperature from thermal sensor")
+	return true
+}
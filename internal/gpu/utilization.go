@@ -0,0 +1,78 @@
+package gpu
+
+import (
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// defaultUtilizationWindowSize is the number of samples averaged when no
+// window is configured, matching the temperature window
+const defaultUtilizationWindowSize = defaultTemperatureWindowSize
+
+// GetUtilization returns the percentage of time the GPU was busy over the
+// driver's last sample period
+func (c *controller) GetUtilization() (Utilization, error) {
+	errFactory := errors.New()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.initialized {
+		return 0, errFactory.New(ErrNotInitialized)
+	}
+
+	rates, ret := c.device.GetUtilizationRates()
+	c.tracer.record("get_utilization", ret)
+	if !IsNVMLSuccess(ret) {
+		return 0, errFactory.Wrap(ErrUtilizationFailed, newNVMLError(ret))
+	}
+
+	return Utilization(rates.Gpu), nil
+}
+
+// UpdateUtilizationHistory adds a sample to the utilization window and
+// returns the mean of the window. Utilization swings between idle and full
+// load from one sample to the next, decisions should use the mean.
+func (c *controller) UpdateUtilizationHistory(utilization Utilization) Utilization {
+	c.utilMu.Lock()
+	defer c.utilMu.Unlock()
+
+	c.utilHistory = append(c.utilHistory, utilization)
+	if len(c.utilHistory) > c.utilWindow {
+		c.utilHistory = c.utilHistory[len(c.utilHistory)-c.utilWindow:]
+	}
+
+	var sum Utilization
+	for _, u := range c.utilHistory {
+		sum += u
+	}
+	avg := sum / Utilization(len(c.utilHistory))
+
+	logger.Debug().
+		Int("utilization", int(utilization)).
+		Int("avgUtilization", int(avg)).
+		Msg("Utilization history updated")
+
+	return avg
+}
+
+// GetMemoryInfo returns the frame buffer memory in use and in total
+func (c *controller) GetMemoryInfo() (MemoryInfo, error) {
+	errFactory := errors.New()
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if !c.initialized {
+		return MemoryInfo{}, errFactory.New(ErrNotInitialized)
+	}
+
+	memory, ret := c.device.GetMemoryInfo()
+	c.tracer.record("get_memory_info", ret)
+	if !IsNVMLSuccess(ret) {
+		return MemoryInfo{}, errFactory.Wrap(ErrMemoryInfoFailed, newNVMLError(ret))
+	}
+
+	return MemoryInfo{
+		Used:  int(memory.Used >> 20),
+		Total: int(memory.Total >> 20),
+	}, nil
+}
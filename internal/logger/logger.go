@@ -20,6 +20,14 @@ var logLevelMap = map[string]LogLevel{
 	"error":   ErrorLevel,
 }
 
+// Output formats
+const (
+	// FormatConsole writes human readable lines
+	FormatConsole = "console"
+	// FormatJSON writes one JSON object per line, for log shipping
+	FormatJSON = "json"
+)
+
 const (
 	DebugLevel LogLevel = iota
 	InfoLevel
@@ -40,21 +48,31 @@ func (e *LogEvent) Send() {
 	e.Event.Send()
 }
 
-// Init initializes the logger based on the given configuration
-func Init(logLevel string, isService bool) {
-	output := zerolog.ConsoleWriter{
-		Out:        os.Stdout,
-		TimeFormat: time.RFC3339,
-	}
+// Init initializes the logger based on the given configuration. Unknown
+// formats fall back to the console format. As a service, the timestamp is
+// left out, the journal already records it.
+func Init(logLevel, format string, isService bool) {
+	if format == FormatJSON {
+		context := zerolog.New(os.Stdout).With()
+		if !isService {
+			context = context.Timestamp()
+		}
+		log = context.Logger()
+	} else {
+		output := zerolog.ConsoleWriter{
+			Out:        os.Stdout,
+			TimeFormat: time.RFC3339,
+		}
 
-	if isService {
-		output.TimeFormat = ""
-		output.FormatTimestamp = func(_ interface{}) string {
-			return ""
+		if isService {
+			output.TimeFormat = ""
+			output.FormatTimestamp = func(_ interface{}) string {
+				return ""
+			}
 		}
-	}
 
-	log = zerolog.New(output).With().Timestamp().Logger()
+		log = zerolog.New(output).With().Timestamp().Logger()
+	}
 
 	// Set log level from string
 	if level, ok := logLevelMap[logLevel]; ok {
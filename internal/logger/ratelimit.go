@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+// warnLimit tracks when a rate limited warning last fired
+type warnLimit struct {
+	last       time.Time
+	suppressed int
+}
+
+var (
+	warnLimits   = make(map[errors.ErrorCode]*warnLimit)
+	warnLimitsMu sync.Mutex
+)
+
+// WarnEvery logs a warning for code at most once every interval. Warnings in
+// between are suppressed: the returned event discards its fields and
+// message. Each code is limited on its own, and the next warning that fires
+// carries the number of suppressed ones.
+func WarnEvery(code errors.ErrorCode, interval time.Duration) *LogEvent {
+	warnLimitsMu.Lock()
+	defer warnLimitsMu.Unlock()
+
+	limit, ok := warnLimits[code]
+	if !ok {
+		limit = &warnLimit{}
+		warnLimits[code] = limit
+	}
+
+	now := time.Now()
+	if !limit.last.IsZero() && now.Sub(limit.last) < interval {
+		limit.suppressed++
+		return &LogEvent{nil}
+	}
+
+	event := log.Warn().Str("error_code", string(code))
+	if limit.suppressed > 0 {
+		event = event.Int("suppressed", limit.suppressed)
+	}
+	limit.last = now
+	limit.suppressed = 0
+
+	return &LogEvent{event}
+}
@@ -1,25 +1,66 @@
 package metrics
 
-import "codeberg.org/mutker/nvidiactl/internal/errors"
+import (
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
 
 const (
 	// File system permissions and paths
 	defaultDirPerm  = 0o755
 	defaultFilePerm = 0o644
 	defaultDBPath   = "/var/lib/nvidiactl/metrics.db"
+
+	// SQLite synchronous modes, trading durability for write throughput
+	SynchronousOff    = "off"
+	SynchronousNormal = "normal"
+	SynchronousFull   = "full"
+
+	// SQLite journal modes. WAL is faster but needs shared memory and
+	// locking that network filesystems don't reliably provide.
+	JournalModeWAL    = "wal"
+	JournalModeDelete = "delete"
+
+	// Metrics backends. Several can be enabled at once.
+	BackendSQLite   = "sqlite"
+	BackendStdout   = "stdout"
+	BackendTextfile = "textfile"
+	BackendInflux   = "influx"
 )
 
 type Config struct {
 	DBPath          string
 	SchemaVersion   int
 	BackupOnMigrate bool
-	Enabled         bool
+	// DestructiveMigrations drops the samples of an older schema version
+	// that can't be migrated instead of failing
+	DestructiveMigrations bool
+	Enabled               bool
+	// Synchronous is the SQLite synchronous mode, NORMAL when empty
+	Synchronous string
+	// JournalMode is the SQLite journal mode, WAL when empty
+	JournalMode string
+	// Backends lists the backends every snapshot is recorded to, SQLite
+	// only when empty
+	Backends []string
+	// TextfilePath is the file the textfile backend writes to
+	TextfilePath string
+	// InfluxSocket is the Unix datagram socket the influx backend sends to
+	InfluxSocket string
+	// MaxSize is the database size in bytes above which the oldest samples
+	// are evicted, unlimited if 0
+	MaxSize int64
+	// Retention is how long samples are kept, forever if 0
+	Retention time.Duration
 }
 
 func DefaultConfig() Config {
 	return Config{
-		DBPath:  defaultDBPath,
-		Enabled: false, // Disabled by default
+		DBPath:      defaultDBPath,
+		Enabled:     false, // Disabled by default
+		Synchronous: SynchronousNormal,
+		JournalMode: JournalModeWAL,
 	}
 }
 
@@ -30,9 +71,112 @@ func (c Config) Validate() error {
 	if c.Enabled && c.DBPath == "" {
 		return errFactory.New(ErrInvalidDBPath)
 	}
+
+	switch c.Synchronous {
+	case "", SynchronousOff, SynchronousNormal, SynchronousFull:
+	default:
+		return errFactory.WithData(ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "synchronous",
+			Value: c.Synchronous,
+		})
+	}
+
+	switch c.JournalMode {
+	case "", JournalModeWAL, JournalModeDelete:
+	default:
+		return errFactory.WithData(ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "journal_mode",
+			Value: c.JournalMode,
+		})
+	}
+
+	if c.MaxSize < 0 {
+		return errFactory.WithData(ErrInvalidConfig, struct {
+			Field string
+			Value int64
+		}{
+			Field: "max_size",
+			Value: c.MaxSize,
+		})
+	}
+
+	if c.Retention < 0 {
+		return errFactory.WithData(ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "retention",
+			Value: c.Retention.String(),
+		})
+	}
+
+	for _, backend := range c.Backends {
+		switch backend {
+		case BackendSQLite, BackendStdout:
+		case BackendTextfile:
+			if c.TextfilePath == "" {
+				return errFactory.WithData(ErrInvalidConfig, struct {
+					Field string
+					Value string
+				}{
+					Field: "textfile_path",
+					Value: c.TextfilePath,
+				})
+			}
+		case BackendInflux:
+			if c.InfluxSocket == "" {
+				return errFactory.WithData(ErrInvalidConfig, struct {
+					Field string
+					Value string
+				}{
+					Field: "influx_socket",
+					Value: c.InfluxSocket,
+				})
+			}
+		default:
+			return errFactory.WithData(ErrInvalidConfig, struct {
+				Field string
+				Value string
+			}{
+				Field: "backends",
+				Value: backend,
+			})
+		}
+	}
+
 	return nil
 }
 
+// backends returns the configured backends, defaulting to SQLite
+func (c Config) backends() []string {
+	if len(c.Backends) == 0 {
+		return []string{BackendSQLite}
+	}
+	return c.Backends
+}
+
+// journalMode returns the journal mode, defaulting to WAL
+func (c Config) journalMode() string {
+	if c.JournalMode == "" {
+		return JournalModeWAL
+	}
+	return c.JournalMode
+}
+
+// synchronousMode returns the synchronous mode, defaulting to NORMAL
+func (c Config) synchronousMode() string {
+	if c.Synchronous == "" {
+		return SynchronousNormal
+	}
+	return c.Synchronous
+}
+
 func boolToInt(b bool) int {
 	if b {
 		return 1
@@ -24,6 +24,8 @@ const (
 	// Collection Errors
 	ErrMetricsCollection = errors.ErrorCode("metrics_metrics_collection_failed")
 	ErrInvalidMetrics    = errors.ErrorCode("metrics_invalid_metrics")
+	ErrBackendFailed     = errors.ErrorCode("metrics_backend_failed")
+	ErrQueryUnsupported  = errors.ErrorCode("metrics_query_unsupported")
 
 	// Operation Errors
 	ErrOperationTimeout = errors.ErrTimeout
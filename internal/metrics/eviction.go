@@ -0,0 +1,200 @@
+package metrics
+
+import (
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+const (
+	// sizeCheckInterval is how many samples are recorded between checks of
+	// the database size. Eviction only needs to keep up with slow growth.
+	sizeCheckInterval = 300
+
+	// evictionBatch is how many of the oldest samples are deleted at a time
+	evictionBatch = 1000
+
+	// pruneInterval is the time between deletions of samples older than the
+	// retention
+	pruneInterval = time.Hour
+)
+
+const (
+	evictMetricsSQL = `
+    DELETE FROM metrics WHERE timestamp IN (
+        SELECT timestamp FROM metrics ORDER BY timestamp LIMIT ?
+    )`
+
+	pruneMetricsSQL = `DELETE FROM metrics WHERE timestamp < ?`
+
+	evictReturnCodesSQL = `
+    DELETE FROM nvml_return_codes
+    WHERE timestamp < (SELECT COALESCE(MIN(timestamp), 0) FROM metrics)`
+)
+
+// checkSize evicts the oldest samples once every sizeCheckInterval samples
+// if the database grew beyond maxSize
+func (r *repository) checkSize() error {
+	if r.maxSize <= 0 {
+		return nil
+	}
+
+	r.sinceSizeCheck++
+	if r.sinceSizeCheck < sizeCheckInterval {
+		return nil
+	}
+	r.sinceSizeCheck = 0
+
+	return r.evict()
+}
+
+// evict deletes the oldest samples in batches until the database is back
+// under maxSize, then returns the freed pages to the filesystem. The size
+// counts used pages only, so pages freed but not yet returned don't cause
+// further eviction.
+func (r *repository) evict() error {
+	errFactory := errors.New()
+
+	size, err := r.usedSize()
+	if err != nil {
+		return err
+	}
+	if size <= r.maxSize {
+		return nil
+	}
+
+	initialSize := size
+	var evicted int64
+	for size > r.maxSize {
+		result, err := r.db.Exec(evictMetricsSQL, evictionBatch)
+		if err != nil {
+			return errFactory.WithData(ErrStorageAccess, struct {
+				Phase string
+				Error string
+			}{
+				Phase: "evict_metrics",
+				Error: err.Error(),
+			})
+		}
+		deleted, err := result.RowsAffected()
+		if err != nil {
+			return errFactory.Wrap(ErrStorageAccess, err)
+		}
+		if deleted == 0 {
+			break
+		}
+		evicted += deleted
+
+		if size, err = r.usedSize(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.db.Exec(evictReturnCodesSQL); err != nil {
+		return errFactory.WithData(ErrStorageAccess, struct {
+			Phase string
+			Error string
+		}{
+			Phase: "evict_return_codes",
+			Error: err.Error(),
+		})
+	}
+
+	// Only has an effect on databases created with incremental auto vacuum,
+	// elsewhere the free pages are reused by later samples
+	if _, err := r.db.Exec("PRAGMA incremental_vacuum"); err != nil {
+		logger.Debug().Err(err).Msg("Failed to vacuum metrics database")
+	}
+
+	logger.Info().
+		Int64("evicted", evicted).
+		Int64("size", initialSize).
+		Int64("new_size", size).
+		Int64("max_size", r.maxSize).
+		Msg("Metrics database exceeded its maximum size, evicted the oldest samples")
+
+	return nil
+}
+
+// checkRetention deletes the samples older than the retention with the
+// first sample and then once every pruneInterval
+func (r *repository) checkRetention(now time.Time) error {
+	if r.retention <= 0 {
+		return nil
+	}
+	if !r.lastPrune.IsZero() && now.Sub(r.lastPrune) < pruneInterval {
+		return nil
+	}
+	r.lastPrune = now
+
+	return r.prune(now.Add(-r.retention))
+}
+
+// prune deletes the samples and return codes recorded before cutoff
+func (r *repository) prune(cutoff time.Time) error {
+	errFactory := errors.New()
+
+	result, err := r.db.Exec(pruneMetricsSQL, cutoff.Unix())
+	if err != nil {
+		return errFactory.WithData(ErrStorageAccess, struct {
+			Phase string
+			Error string
+		}{
+			Phase: "prune_metrics",
+			Error: err.Error(),
+		})
+	}
+	pruned, err := result.RowsAffected()
+	if err != nil {
+		return errFactory.Wrap(ErrStorageAccess, err)
+	}
+	if pruned == 0 {
+		return nil
+	}
+
+	if _, err := r.db.Exec(evictReturnCodesSQL); err != nil {
+		return errFactory.WithData(ErrStorageAccess, struct {
+			Phase string
+			Error string
+		}{
+			Phase: "prune_return_codes",
+			Error: err.Error(),
+		})
+	}
+
+	if _, err := r.db.Exec("PRAGMA incremental_vacuum"); err != nil {
+		logger.Debug().Err(err).Msg("Failed to vacuum metrics database")
+	}
+
+	logger.Info().
+		Int64("pruned", pruned).
+		Time("cutoff", cutoff).
+		Msg("Deleted metrics samples older than the retention")
+
+	return nil
+}
+
+// usedSize returns the size of the pages in use, in bytes
+func (r *repository) usedSize() (int64, error) {
+	errFactory := errors.New()
+
+	var pageCount, freePages, pageSize int64
+	for pragma, value := range map[string]*int64{
+		"page_count":     &pageCount,
+		"freelist_count": &freePages,
+		"page_size":      &pageSize,
+	} {
+		if err := r.db.QueryRow("PRAGMA " + pragma).Scan(value); err != nil {
+			return 0, errFactory.WithData(ErrStorageAccess, struct {
+				Phase string
+				Error string
+			}{
+				Phase: "read_" + pragma,
+				Error: err.Error(),
+			})
+		}
+	}
+
+	return (pageCount - freePages) * pageSize, nil
+}
@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"path/filepath"
+	"syscall"
+)
+
+// Filesystem magic numbers from statfs(2) of filesystems that don't provide
+// the shared memory and locking WAL mode relies on
+var networkFilesystems = map[int64]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x65735546: "fuse",
+	0x00c36400: "ceph",
+	0x5346414f: "afs",
+	0x47504653: "gpfs",
+	0x0bd00bd0: "lustre",
+	0x01021997: "9p",
+}
+
+// networkFilesystem returns the name of the network filesystem the database
+// directory is on, or an empty string for local or undetectable filesystems
+func networkFilesystem(dbPath string) string {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(filepath.Dir(dbPath), &st); err != nil {
+		return ""
+	}
+
+	//nolint:unconvert // Type is int32 on some architectures
+	return networkFilesystems[int64(st.Type)]
+}
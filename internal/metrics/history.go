@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+const selectHistorySQL = `
+    SELECT
+        timestamp,
+        fan_speed_current, fan_speed_target,
+        temp_current, temp_average,
+        power_current, power_target, power_average,
+        auto_fan_control, performance_mode
+    FROM metrics
+    WHERE timestamp >= ? AND timestamp <= ?
+    ORDER BY timestamp`
+
+// ReadHistory reads the snapshots recorded between from and to from a
+// metrics database, oldest first. A zero from or to leaves that end open. The
+// database is opened read-only, so it is safe to use while the daemon is
+// writing to it.
+func ReadHistory(dbPath string, from, to time.Time) ([]MetricsSnapshot, error) {
+	errFactory := errors.New()
+
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	start, end := int64(0), int64(math.MaxInt64)
+	if !from.IsZero() {
+		start = from.Unix()
+	}
+	if !to.IsZero() {
+		end = to.Unix()
+	}
+
+	rows, err := db.Query(selectHistorySQL, start, end)
+	if err != nil {
+		return nil, errFactory.WithData(ErrStorageAccess, struct {
+			Phase string
+			Error string
+		}{
+			Phase: "query_history",
+			Error: err.Error(),
+		})
+	}
+	defer rows.Close()
+
+	var snapshots []MetricsSnapshot
+	for rows.Next() {
+		var (
+			timestamp       int64
+			snapshot        MetricsSnapshot
+			autoFanControl  int
+			performanceMode int
+		)
+		if err := rows.Scan(
+			&timestamp,
+			&snapshot.FanSpeed.Current, &snapshot.FanSpeed.Target,
+			&snapshot.Temperature.Current, &snapshot.Temperature.Average,
+			&snapshot.PowerLimit.Current, &snapshot.PowerLimit.Target, &snapshot.PowerLimit.Average,
+			&autoFanControl, &performanceMode,
+		); err != nil {
+			return nil, errFactory.Wrap(ErrStorageAccess, err)
+		}
+
+		snapshot.Timestamp = time.Unix(timestamp, 0)
+		snapshot.PowerLimit.Board = -1
+		snapshot.PowerLimit.Enforced = -1
+		snapshot.PowerLimit.Draw = -1
+		snapshot.SystemState.AutoFanControl = autoFanControl == 1
+		snapshot.SystemState.PerformanceMode = performanceMode == 1
+		snapshot.SystemState.PerformanceState = -1
+		snapshot.Load = LoadMetrics{Utilization: -1, MemoryUsed: -1, MemoryTotal: -1}
+
+		snapshots = append(snapshots, snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errFactory.Wrap(ErrStorageAccess, err)
+	}
+
+	return snapshots, nil
+}
+
+// DatabaseStats describes the contents of a metrics database
+type DatabaseStats struct {
+	Path          string    `json:"path"`
+	SizeBytes     int64     `json:"size_bytes"`
+	SchemaVersion int       `json:"schema_version"`
+	Samples       int64     `json:"samples"`
+	First         time.Time `json:"first,omitempty"`
+	Last          time.Time `json:"last,omitempty"`
+}
+
+// ReadStats reads the size, schema version and sample range of a metrics
+// database, opened read-only
+func ReadStats(dbPath string) (DatabaseStats, error) {
+	errFactory := errors.New()
+
+	db, err := openReadOnly(dbPath)
+	if err != nil {
+		return DatabaseStats{}, err
+	}
+	defer db.Close()
+
+	stats := DatabaseStats{Path: dbPath}
+	if info, err := os.Stat(dbPath); err == nil {
+		stats.SizeBytes = info.Size()
+	}
+
+	if stats.SchemaVersion, err = GetSchemaVersion(db); err != nil {
+		return stats, err
+	}
+
+	var first, last sql.NullInt64
+	if err := db.QueryRow("SELECT COUNT(*), MIN(timestamp), MAX(timestamp) FROM metrics").
+		Scan(&stats.Samples, &first, &last); err != nil {
+		return stats, errFactory.Wrap(ErrStorageAccess, err)
+	}
+	if first.Valid {
+		stats.First = time.Unix(first.Int64, 0)
+		stats.Last = time.Unix(last.Int64, 0)
+	}
+
+	return stats, nil
+}
+
+// openReadOnly opens an existing metrics database read-only
+func openReadOnly(dbPath string) (*sql.DB, error) {
+	errFactory := errors.New()
+
+	if dbPath == "" {
+		return nil, errFactory.New(ErrInvalidDBPath)
+	}
+
+	// sql.Open would create a missing file despite the read-only mode
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, errFactory.WithData(ErrStorageAccess, struct {
+			Path  string
+			Error string
+		}{
+			Path:  dbPath,
+			Error: err.Error(),
+		})
+	}
+
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", dbPath))
+	if err != nil {
+		return nil, errFactory.Wrap(ErrStorageAccess, err)
+	}
+
+	return db, nil
+}
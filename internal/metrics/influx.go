@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"sync"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+// influxMeasurement is the measurement name of every line
+const influxMeasurement = "nvidiactl"
+
+// influxField is a field of the line protocol message
+type influxField struct {
+	name  string
+	value func(s *MetricsSnapshot) (string, bool)
+}
+
+func intField(v int) string   { return strconv.Itoa(v) + "i" }
+func boolField(v bool) string { return strconv.FormatBool(v) }
+
+// influxFields are the fields of the line protocol message. Values the card
+// doesn't report are left out.
+var influxFields = []influxField{
+	{"temperature", func(s *MetricsSnapshot) (string, bool) { return intField(s.Temperature.Current), true }},
+	{"temperature_average", func(s *MetricsSnapshot) (string, bool) { return intField(s.Temperature.Average), true }},
+	{"temperature_raw", func(s *MetricsSnapshot) (string, bool) { return intField(s.Temperature.Raw), true }},
+	{"fan_speed", func(s *MetricsSnapshot) (string, bool) { return intField(s.FanSpeed.Current), true }},
+	{"fan_speed_target", func(s *MetricsSnapshot) (string, bool) { return intField(s.FanSpeed.Target), true }},
+	{"power_limit", func(s *MetricsSnapshot) (string, bool) { return intField(s.PowerLimit.Current), true }},
+	{"power_limit_target", func(s *MetricsSnapshot) (string, bool) { return intField(s.PowerLimit.Target), true }},
+	{"power_limit_average", func(s *MetricsSnapshot) (string, bool) { return intField(s.PowerLimit.Average), true }},
+	{"power_limit_enforced", func(s *MetricsSnapshot) (string, bool) {
+		return intField(s.PowerLimit.Enforced), s.PowerLimit.Enforced >= 0
+	}},
+	{"power_draw", func(s *MetricsSnapshot) (string, bool) {
+		return intField(s.PowerLimit.Draw), s.PowerLimit.Draw >= 0
+	}},
+	{"board_power", func(s *MetricsSnapshot) (string, bool) {
+		return intField(s.PowerLimit.Board), s.PowerLimit.Board >= 0
+	}},
+	{"performance_state", func(s *MetricsSnapshot) (string, bool) {
+		return intField(s.SystemState.PerformanceState), s.SystemState.PerformanceState >= 0
+	}},
+	{"utilization", func(s *MetricsSnapshot) (string, bool) {
+		return intField(s.Load.Utilization), s.Load.Utilization >= 0
+	}},
+	{"memory_used", func(s *MetricsSnapshot) (string, bool) {
+		return intField(s.Load.MemoryUsed), s.Load.MemoryUsed >= 0
+	}},
+	{"memory_total", func(s *MetricsSnapshot) (string, bool) {
+		return intField(s.Load.MemoryTotal), s.Load.MemoryTotal >= 0
+	}},
+	{"auto_fan_control", func(s *MetricsSnapshot) (string, bool) { return boolField(s.SystemState.AutoFanControl), true }},
+	{"performance_mode", func(s *MetricsSnapshot) (string, bool) { return boolField(s.SystemState.PerformanceMode), true }},
+}
+
+// influxCollector sends every snapshot as an InfluxDB line protocol message
+// to a Unix datagram socket, such as telegraf's socket_listener. Snapshots
+// sent while nothing listens are lost; the socket is dialed again on the
+// next one.
+type influxCollector struct {
+	writeOnly
+	path string
+	conn net.Conn
+	mu   sync.Mutex
+}
+
+func newInfluxCollector(path string) (MetricsCollector, error) {
+	errFactory := errors.New()
+
+	if path == "" {
+		return nil, errFactory.WithData(ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "influx_socket",
+			Value: path,
+		})
+	}
+
+	return &influxCollector{path: path}, nil
+}
+
+func (c *influxCollector) Record(_ context.Context, snapshot *MetricsSnapshot) error {
+	errFactory := errors.New()
+
+	if snapshot == nil {
+		return errFactory.New(ErrInvalidMetrics)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		conn, err := net.Dial("unixgram", c.path)
+		if err != nil {
+			return errFactory.WithData(ErrMetricsCollection, struct {
+				Path  string
+				Error string
+			}{
+				Path:  c.path,
+				Error: err.Error(),
+			})
+		}
+		c.conn = conn
+	}
+
+	if _, err := c.conn.Write(formatLineProtocol(snapshot)); err != nil {
+		c.conn.Close()
+		c.conn = nil
+		return errFactory.WithData(ErrMetricsCollection, struct {
+			Path  string
+			Error string
+		}{
+			Path:  c.path,
+			Error: err.Error(),
+		})
+	}
+
+	return nil
+}
+
+func (c *influxCollector) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// formatLineProtocol renders a snapshot as a single line of the InfluxDB
+// line protocol with a nanosecond timestamp
+func formatLineProtocol(snapshot *MetricsSnapshot) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(influxMeasurement)
+
+	separator := byte(' ')
+	for _, f := range influxFields {
+		value, ok := f.value(snapshot)
+		if !ok {
+			continue
+		}
+		buf.WriteByte(separator)
+		buf.WriteString(f.name)
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		separator = ','
+	}
+
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatInt(snapshot.Timestamp.UnixNano(), 10))
+	buf.WriteByte('\n')
+
+	return buf.Bytes()
+}
@@ -8,42 +8,83 @@ import (
 // MetricsCollector defines the core domain interface
 type MetricsCollector interface {
 	Record(ctx context.Context, snapshot *MetricsSnapshot) error
+	// Query returns the snapshots recorded between from and to, oldest
+	// first. Backends that don't store snapshots return ErrQueryUnsupported.
+	Query(ctx context.Context, from, to time.Time) ([]*MetricsSnapshot, error)
 	Close() error
 }
 
 // Repository defines the interface for metrics data storage
 type MetricsRepository interface {
+	// Record stores a snapshot, replacing one recorded in the same second
 	Record(snapshot *MetricsSnapshot) error
+	// Query returns the snapshots recorded between from and to, oldest
+	// first. A zero from or to leaves that end open.
+	Query(ctx context.Context, from, to time.Time) ([]*MetricsSnapshot, error)
 	Close() error
 }
 
 // MetricsSnapshot represents domain entities
 type MetricsSnapshot struct {
-	Timestamp   time.Time
-	FanSpeed    FanMetrics
-	Temperature TempMetrics
-	PowerLimit  PowerMetrics
-	SystemState StateMetrics
+	Timestamp   time.Time    `json:"timestamp"`
+	FanSpeed    FanMetrics   `json:"fan_speed"`
+	Temperature TempMetrics  `json:"temperature"`
+	PowerLimit  PowerMetrics `json:"power_limit"`
+	SystemState StateMetrics `json:"system_state"`
+	Load        LoadMetrics  `json:"load"`
+	// ReturnCodes holds raw NVML results, only populated when NVML debug
+	// recording is enabled
+	ReturnCodes []ReturnCodeMetrics `json:"return_codes,omitempty"`
 }
 
 // Domain value objects
 type FanMetrics struct {
-	Current int
-	Target  int
+	Current int `json:"current"`
+	Target  int `json:"target"`
 }
 
 type TempMetrics struct {
-	Current int
-	Average int
+	Current int `json:"current"`
+	Average int `json:"average"`
+	// Raw is the reading before the configured offset was applied
+	Raw int `json:"raw"`
 }
 
 type PowerMetrics struct {
-	Current int
-	Target  int
-	Average int
+	Current int `json:"current"`
+	Target  int `json:"target"`
+	Average int `json:"average"`
+	// Board is the total board power draw in watts, or -1 when the card
+	// doesn't report it
+	Board int `json:"board"`
+	// Enforced is the power limit the card enforces, or -1 when unknown.
+	// It isn't stored in the database.
+	Enforced int `json:"enforced"`
+	// Draw is the current power draw of the GPU in watts, or -1 when
+	// unknown
+	Draw int `json:"draw"`
 }
 
 type StateMetrics struct {
-	AutoFanControl  bool
-	PerformanceMode bool
+	AutoFanControl  bool `json:"auto_fan_control"`
+	PerformanceMode bool `json:"performance_mode"`
+	// PerformanceState is the P-state (0-15), or -1 when unknown
+	PerformanceState int `json:"pstate"`
+}
+
+// LoadMetrics is how busy the GPU is, each value -1 when unknown
+type LoadMetrics struct {
+	// Utilization is the GPU utilization in percent
+	Utilization int `json:"utilization"`
+	// MemoryUsed and MemoryTotal are the frame buffer memory in MiB
+	MemoryUsed  int `json:"memory_used"`
+	MemoryTotal int `json:"memory_total"`
+}
+
+// ReturnCodeMetrics is the raw result of a single NVML operation
+type ReturnCodeMetrics struct {
+	Timestamp time.Time `json:"timestamp"`
+	Operation string    `json:"operation"`
+	Code      int       `json:"code"`
+	Message   string    `json:"message"`
 }
@@ -2,6 +2,8 @@ package metrics
 
 import (
 	"context"
+	"os"
+	"time"
 
 	"codeberg.org/mutker/nvidiactl/internal/errors"
 	"codeberg.org/mutker/nvidiactl/internal/logger"
@@ -28,22 +30,59 @@ func NewService(cfg Config) (MetricsCollector, error) {
 		return &noopMetricsCollector{}, nil
 	}
 
-	// Remove reference to undefined removeOldDatabase
-	repo, err := NewRepository(cfg)
-	if err != nil {
-		logger.Debug().Err(err).Msg("Failed to create metrics repository")
-		return nil, err
+	collectors := make([]namedCollector, 0, len(cfg.backends()))
+	for _, backend := range cfg.backends() {
+		collector, err := newBackend(backend, cfg)
+		if err != nil {
+			for _, c := range collectors {
+				if closeErr := c.Close(); closeErr != nil {
+					logger.Debug().Err(closeErr).Str("backend", c.name).Msg("Failed to close metrics backend")
+				}
+			}
+			return nil, err
+		}
+		collectors = append(collectors, namedCollector{name: backend, MetricsCollector: collector})
 	}
 
 	logger.Debug().
-		Str("db_path", cfg.DBPath).
+		Strs("backends", cfg.backends()).
 		Bool("enabled", cfg.Enabled).
 		Msg("Metrics service initialized successfully")
 
-	return &service{
-		repo: repo,
-		cfg:  cfg,
-	}, nil
+	if len(collectors) == 1 {
+		return collectors[0].MetricsCollector, nil
+	}
+
+	return &multiCollector{collectors: collectors}, nil
+}
+
+// newBackend creates the collector of a single backend
+func newBackend(backend string, cfg Config) (MetricsCollector, error) {
+	errFactory := errors.New()
+
+	switch backend {
+	case BackendSQLite:
+		repo, err := NewRepository(cfg)
+		if err != nil {
+			logger.Debug().Err(err).Msg("Failed to create metrics repository")
+			return nil, err
+		}
+
+		logger.Debug().Str("db_path", cfg.DBPath).Msg("SQLite metrics backend initialized")
+
+		return &service{
+			repo: repo,
+			cfg:  cfg,
+		}, nil
+	case BackendStdout:
+		return newStreamCollector(os.Stdout), nil
+	case BackendTextfile:
+		return newTextfileCollector(cfg.TextfilePath)
+	case BackendInflux:
+		return newInfluxCollector(cfg.InfluxSocket)
+	default:
+		return nil, errFactory.WithData(ErrInvalidConfig, backend)
+	}
 }
 
 func (s *service) Record(ctx context.Context, snapshot *MetricsSnapshot) error {
@@ -65,6 +104,16 @@ func (s *service) Record(ctx context.Context, snapshot *MetricsSnapshot) error {
 	return nil
 }
 
+func (s *service) Query(ctx context.Context, from, to time.Time) ([]*MetricsSnapshot, error) {
+	errFactory := errors.New()
+
+	snapshots, err := s.repo.Query(ctx, from, to)
+	if err != nil {
+		return nil, errFactory.Wrap(ErrStorageAccess, err)
+	}
+	return snapshots, nil
+}
+
 func (s *service) Close() error {
 	errFactory := errors.New()
 
@@ -79,6 +128,18 @@ func (*noopMetricsCollector) Record(_ context.Context, _ *MetricsSnapshot) error
 	return nil
 }
 
+func (*noopMetricsCollector) Query(_ context.Context, _, _ time.Time) ([]*MetricsSnapshot, error) {
+	return nil, nil
+}
+
 func (*noopMetricsCollector) Close() error {
 	return nil
 }
+
+// writeOnly implements Query for backends that only pass snapshots on
+type writeOnly struct{}
+
+func (writeOnly) Query(_ context.Context, _, _ time.Time) ([]*MetricsSnapshot, error) {
+	errFactory := errors.New()
+	return nil, errFactory.New(ErrQueryUnsupported)
+}
@@ -5,13 +5,21 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"codeberg.org/mutker/nvidiactl/internal/errors"
 	"codeberg.org/mutker/nvidiactl/internal/logger"
 )
 
-const backupDir = "/var/lib/nvidiactl/backups"
+// backupDir is where databases are backed up before a migration, a variable
+// so tests can back up to a temporary directory
+var backupDir = "/var/lib/nvidiactl/backups"
+
+// migratedTables are the tables whose rows are carried over to a new schema
+// version. schema_versions keeps a row per applied version instead.
+var migratedTables = []string{"metrics", "nvml_return_codes"}
 
 func backupDatabase(db *sql.DB, version int) (string, error) {
 	errFactory := errors.New()
@@ -56,10 +64,11 @@ func backupDatabase(db *sql.DB, version int) (string, error) {
 	return backupPath, nil
 }
 
-// ValidateAndUpdateSchema checks the schema version and recreates it if needed.
-// If a schema exists but the version doesn't match, it creates a backup
-// before recreating the schema.
-func ValidateAndUpdateSchema(db *sql.DB) error {
+// ValidateAndUpdateSchema checks the schema version and migrates it if
+// needed. If a schema exists but the version doesn't match, it creates a
+// backup and copies the rows into the current schema. Only if that fails and
+// destructive is set are the old tables dropped instead.
+func ValidateAndUpdateSchema(db *sql.DB, destructive bool) error {
 	errFactory := errors.New()
 
 	version, err := GetSchemaVersion(db)
@@ -73,25 +82,54 @@ func ValidateAndUpdateSchema(db *sql.DB) error {
 		Bool("init_db", version == 0).
 		Msg("Current schema version")
 
-	// New database or version mismatch
-	if version == 0 || version != SchemaVersion {
-		// If existing schema, backup first
-		if version != 0 {
-			backupPath, err := backupDatabase(db, version)
-			if err != nil {
-				return errFactory.WithData(ErrSchemaMigrationFailed, struct {
-					Phase string
-					Error string
-					Path  string
-				}{
-					Phase: "backup",
-					Error: err.Error(),
-					Path:  backupPath,
-				})
-			}
+	// A missing version may be an interrupted initialization rather than a
+	// new database, don't throw away a metrics table that is still current
+	if version == 0 {
+		reconciled, err := reconcileSchemaVersion(db)
+		if err != nil {
+			return errFactory.Wrap(ErrSchemaValidationFailed, err)
+		}
+		if reconciled {
+			return nil
 		}
+	}
+
+	// New database, or one whose tables were already backed up above
+	if version == 0 {
+		if err := dropTables(db); err != nil {
+			return err
+		}
+		return InitSchema(db)
+	}
+
+	if version != SchemaVersion {
+		backupPath, err := backupDatabase(db, version)
+		if err != nil {
+			return errFactory.WithData(ErrSchemaMigrationFailed, struct {
+				Phase string
+				Error string
+				Path  string
+			}{
+				Phase: "backup",
+				Error: err.Error(),
+				Path:  backupPath,
+			})
+		}
+
+		err = migrateSchema(db, version)
+		if err == nil {
+			return nil
+		}
+		if !destructive {
+			return err
+		}
+
+		logger.Warn().
+			Err(err).
+			Int("version", version).
+			Str("backup", backupPath).
+			Msg("Failed to migrate metrics, recreating the schema without them")
 
-		// Drop existing tables and create new schema
 		if err := dropTables(db); err != nil {
 			return err
 		}
@@ -104,6 +142,178 @@ func ValidateAndUpdateSchema(db *sql.DB) error {
 	return nil
 }
 
+// reconcileSchemaVersion handles a database without a recorded schema
+// version. If the metrics table has the current columns, the missing tables
+// and the version are created and true is returned. An outdated metrics
+// table is backed up before it is dropped, so its data is never lost
+// silently. The columns only identify the version while no two versions
+// share them; a version changing what a column holds must be told apart
+// here before it is stamped.
+func reconcileSchemaVersion(db *sql.DB) (bool, error) {
+	errFactory := errors.New()
+
+	exists, err := TableExists(db, "metrics")
+	if err != nil || !exists {
+		return false, err
+	}
+
+	columns, err := tableColumns(db, "metrics")
+	if err != nil {
+		return false, err
+	}
+
+	if !slices.Equal(columns, metricsColumns) {
+		logger.Warn().
+			Strs("columns", columns).
+			Strs("expected", metricsColumns).
+			Msg("Metrics table without schema version doesn't match the current schema, recreating it")
+
+		if _, err := backupDatabase(db, 0); err != nil {
+			return false, errFactory.WithData(ErrSchemaMigrationFailed, struct {
+				Phase string
+				Error string
+			}{
+				Phase: "backup",
+				Error: err.Error(),
+			})
+		}
+		return false, nil
+	}
+
+	logger.Warn().
+		Int("version", SchemaVersion).
+		Msg("Schema version missing but metrics table is current, recording version")
+
+	// Only creates what is missing, existing tables are left untouched
+	if err := InitSchema(db); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// migrateSchema moves the tables of an older schema version aside, creates
+// the current schema and copies the columns both have in common, in a single
+// transaction. Columns the old tables lack are left NULL; if the current
+// schema requires them the copy fails and the database is left unchanged.
+func migrateSchema(db *sql.DB, version int) error {
+	errFactory := errors.New()
+
+	migrationFailed := func(phase, table string, err error) error {
+		return errFactory.WithData(ErrSchemaMigrationFailed, struct {
+			Phase   string
+			Table   string
+			Version int
+			Error   string
+		}{
+			Phase:   phase,
+			Table:   table,
+			Version: version,
+			Error:   err.Error(),
+		})
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return errFactory.Wrap(ErrSchemaMigrationFailed, err)
+	}
+
+	// Track transaction state
+	committed := false
+	defer func() {
+		if !committed {
+			if err := tx.Rollback(); err != nil {
+				if !errors.Is(err, sql.ErrTxDone) {
+					logger.Debug().Err(err).Msg("Failed to rollback migration")
+				}
+			}
+		}
+	}()
+
+	// The old index would keep its name and stop the new one from being created
+	if _, err := tx.Exec("DROP INDEX IF EXISTS idx_nvml_return_codes_timestamp"); err != nil {
+		return migrationFailed("drop_index", "nvml_return_codes", err)
+	}
+
+	oldTables := make(map[string]string, len(migratedTables))
+	for _, table := range migratedTables {
+		columns, err := tableColumns(tx, table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+
+		oldTable := fmt.Sprintf("%s_v%d", table, version)
+		if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s RENAME TO %s", table, oldTable)); err != nil {
+			return migrationFailed("rename_table", table, err)
+		}
+		oldTables[table] = oldTable
+	}
+
+	if err := createSchema(tx); err != nil {
+		return err
+	}
+
+	var copied int64
+	for _, table := range migratedTables {
+		oldTable, ok := oldTables[table]
+		if !ok {
+			continue
+		}
+
+		oldColumns, err := tableColumns(tx, oldTable)
+		if err != nil {
+			return err
+		}
+		newColumns, err := tableColumns(tx, table)
+		if err != nil {
+			return err
+		}
+
+		var common []string
+		for _, column := range newColumns {
+			if slices.Contains(oldColumns, column) {
+				common = append(common, column)
+			}
+		}
+
+		if len(common) > 0 {
+			columns := strings.Join(common, ", ")
+			result, err := tx.Exec(fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s",
+				table, columns, columns, oldTable))
+			if err != nil {
+				return migrationFailed("copy_rows", table, err)
+			}
+			rows, err := result.RowsAffected()
+			if err != nil {
+				return errFactory.Wrap(ErrSchemaMigrationFailed, err)
+			}
+			copied += rows
+		}
+
+		if _, err := tx.Exec("DROP TABLE " + oldTable); err != nil {
+			return migrationFailed("drop_table", oldTable, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return migrationFailed("commit_changes", "", err)
+	}
+	committed = true
+
+	logger.Info().
+		Int("from_version", version).
+		Int("version", SchemaVersion).
+		Int64("rows", copied).
+		Msg("Metrics database migrated")
+
+	return nil
+}
+
+// dropTables drops the tables holding samples. schema_versions is kept, so
+// the versions applied before stay recorded.
 func dropTables(db *sql.DB) error {
 	errFactory := errors.New()
 
@@ -125,8 +335,7 @@ func dropTables(db *sql.DB) error {
 		}
 	}()
 
-	tables := []string{"metrics", "schema_versions"}
-	for _, table := range tables {
+	for _, table := range migratedTables {
 		if _, err := tx.Exec("DROP TABLE IF EXISTS " + table); err != nil {
 			return errFactory.WithData(ErrSchemaMigrationFailed, struct {
 				Phase string
@@ -0,0 +1,275 @@
+package metrics
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// newTestDB opens a new database in a temporary directory
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "metrics.db"))
+	if err != nil {
+		t.Fatalf("sql.Open() unexpected error: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// insertSample inserts a sample with only the columns every schema version
+// requires
+func insertSample(t *testing.T, db *sql.DB, timestamp int64) {
+	t.Helper()
+
+	if _, err := db.Exec(`INSERT INTO metrics (
+        timestamp, fan_speed_current, fan_speed_target, temp_current, temp_average,
+        power_current, power_target, power_average, auto_fan_control, performance_mode
+    ) VALUES (?, 30, 30, 60, 60, 200, 200, 200, 0, 0)`, timestamp); err != nil {
+		t.Fatalf("inserting a sample: %v", err)
+	}
+}
+
+// countRows returns the number of rows in a table
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+		t.Fatalf("counting the rows of %s: %v", table, err)
+	}
+	return count
+}
+
+func TestValidateKeepsCurrentTableWithoutVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		// lose leaves the schema the way an interrupted initialization
+		// did
+		lose string
+	}{
+		{name: "versions table missing", lose: "DROP TABLE schema_versions"},
+		{name: "version not recorded", lose: "DELETE FROM schema_versions"},
+		{name: "return codes table missing", lose: "DROP TABLE schema_versions; DROP TABLE nvml_return_codes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDB(t)
+			if err := InitSchema(db); err != nil {
+				t.Fatalf("InitSchema() unexpected error: %v", err)
+			}
+			insertSample(t, db, 1760000000)
+			insertSample(t, db, 1760000001)
+			if _, err := db.Exec(tt.lose); err != nil {
+				t.Fatalf("breaking the schema: %v", err)
+			}
+
+			if err := ValidateAndUpdateSchema(db, false); err != nil {
+				t.Fatalf("ValidateAndUpdateSchema() unexpected error: %v", err)
+			}
+
+			if got := countRows(t, db, "metrics"); got != 2 {
+				t.Errorf("metrics has %d rows, want both samples kept", got)
+			}
+			version, err := GetSchemaVersion(db)
+			if err != nil {
+				t.Fatalf("GetSchemaVersion() unexpected error: %v", err)
+			}
+			if version != SchemaVersion {
+				t.Errorf("schema version = %d, want %d recorded", version, SchemaVersion)
+			}
+			if exists, err := TableExists(db, "nvml_return_codes"); err != nil || !exists {
+				t.Errorf("nvml_return_codes exists = %v, %v, want it created", exists, err)
+			}
+		})
+	}
+}
+
+// v1MetricsSQL is a metrics table of an older version, without the columns
+// added since
+const v1MetricsSQL = `
+    CREATE TABLE metrics (
+        timestamp         INTEGER PRIMARY KEY,
+        fan_speed_current INTEGER NOT NULL,
+        fan_speed_target  INTEGER NOT NULL,
+        temp_current      INTEGER NOT NULL,
+        temp_average      INTEGER NOT NULL,
+        power_current     INTEGER NOT NULL,
+        power_target      INTEGER NOT NULL,
+        power_average     INTEGER NOT NULL,
+        auto_fan_control  INTEGER NOT NULL,
+        performance_mode  INTEGER NOT NULL
+    )`
+
+// unmigratableMetricsSQL lacks temp_average, which the current schema
+// requires, so its rows can't be copied
+const unmigratableMetricsSQL = `
+    CREATE TABLE metrics (
+        timestamp         INTEGER PRIMARY KEY,
+        fan_speed_current INTEGER NOT NULL,
+        fan_speed_target  INTEGER NOT NULL,
+        temp_current      INTEGER NOT NULL,
+        power_current     INTEGER NOT NULL,
+        power_target      INTEGER NOT NULL,
+        power_average     INTEGER NOT NULL,
+        auto_fan_control  INTEGER NOT NULL,
+        performance_mode  INTEGER NOT NULL
+    )`
+
+// newVersionedDB returns a database at schema version 1 with a metrics table
+// created by metricsSQL and rows samples
+func newVersionedDB(t *testing.T, metricsSQL string, rows int) *sql.DB {
+	t.Helper()
+
+	db := newTestDB(t)
+	for _, stmt := range []string{
+		"CREATE TABLE schema_versions (version INTEGER PRIMARY KEY, applied_at TEXT NOT NULL)",
+		"INSERT INTO schema_versions (version, applied_at) VALUES (1, datetime('now'))",
+		metricsSQL,
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Fatalf("creating the version 1 schema: %v", err)
+		}
+	}
+
+	// Every column but the timestamp holds the same reading
+	columns, err := tableColumns(db, "metrics")
+	if err != nil {
+		t.Fatalf("tableColumns() unexpected error: %v", err)
+	}
+	insert := fmt.Sprintf("INSERT INTO metrics (%s) VALUES (?%s)",
+		strings.Join(columns, ", "), strings.Repeat(", 0", len(columns)-1))
+	for i := 0; i < rows; i++ {
+		if _, err := db.Exec(insert, 1760000000+i); err != nil {
+			t.Fatalf("inserting a sample: %v", err)
+		}
+	}
+
+	return db
+}
+
+func TestMigrateSchemaKeepsRows(t *testing.T) {
+	db := newVersionedDB(t, v1MetricsSQL, 3)
+
+	if err := migrateSchema(db, 1); err != nil {
+		t.Fatalf("migrateSchema() unexpected error: %v", err)
+	}
+
+	if got := countRows(t, db, "metrics"); got != 3 {
+		t.Errorf("metrics has %d rows after the migration, want 3", got)
+	}
+	columns, err := tableColumns(db, "metrics")
+	if err != nil {
+		t.Fatalf("tableColumns() unexpected error: %v", err)
+	}
+	if len(columns) != len(metricsColumns) {
+		t.Errorf("metrics columns = %v, want the current %v", columns, metricsColumns)
+	}
+
+	// Columns the old version lacked are left empty
+	var powerDraw sql.NullInt64
+	if err := db.QueryRow("SELECT power_draw FROM metrics LIMIT 1").Scan(&powerDraw); err != nil {
+		t.Fatalf("reading a migrated sample: %v", err)
+	}
+	if powerDraw.Valid {
+		t.Errorf("power_draw of a migrated sample = %d, want NULL", powerDraw.Int64)
+	}
+	if exists, _ := TableExists(db, "metrics_v1"); exists {
+		t.Error("the version 1 table was left behind")
+	}
+}
+
+func TestMigrateSchemaRollsBackFailedCopy(t *testing.T) {
+	db := newVersionedDB(t, unmigratableMetricsSQL, 2)
+
+	if err := migrateSchema(db, 1); err == nil {
+		t.Fatal("migrateSchema() copied rows lacking a required column")
+	}
+
+	columns, err := tableColumns(db, "metrics")
+	if err != nil {
+		t.Fatalf("tableColumns() unexpected error: %v", err)
+	}
+	if slices.Contains(columns, "temp_average") {
+		t.Errorf("metrics columns after a failed migration = %v, want the old table", columns)
+	}
+	if got := countRows(t, db, "metrics"); got != 2 {
+		t.Errorf("metrics has %d rows after a failed migration, want the old 2", got)
+	}
+	if version, _ := GetSchemaVersion(db); version != 1 {
+		t.Errorf("schema version after a failed migration = %d, want 1", version)
+	}
+}
+
+func TestMigrateSchemaRecordsEveryVersion(t *testing.T) {
+	db := newVersionedDB(t, v1MetricsSQL, 1)
+
+	if err := migrateSchema(db, 1); err != nil {
+		t.Fatalf("migrateSchema() unexpected error: %v", err)
+	}
+
+	rows, err := db.Query("SELECT version, applied_at FROM schema_versions ORDER BY version")
+	if err != nil {
+		t.Fatalf("reading schema_versions: %v", err)
+	}
+	defer rows.Close()
+
+	var versions []int
+	for rows.Next() {
+		var version int
+		var appliedAt string
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			t.Fatalf("reading schema_versions: %v", err)
+		}
+		if appliedAt == "" {
+			t.Errorf("version %d has no applied_at", version)
+		}
+		versions = append(versions, version)
+	}
+	if !slices.Equal(versions, []int{1, SchemaVersion}) {
+		t.Errorf("schema versions = %v, want [1 %d]", versions, SchemaVersion)
+	}
+}
+
+func TestValidateDestructiveMigrations(t *testing.T) {
+	tests := []struct {
+		name        string
+		destructive bool
+		wantErr     bool
+		wantVersion int
+		wantRows    int
+	}{
+		{name: "kept on failure", destructive: false, wantErr: true, wantVersion: 1, wantRows: 2},
+		{name: "dropped on failure", destructive: true, wantVersion: SchemaVersion, wantRows: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backupDir = t.TempDir()
+			defer func(dir string) { backupDir = dir }(backupDir)
+
+			db := newVersionedDB(t, unmigratableMetricsSQL, 2)
+
+			err := ValidateAndUpdateSchema(db, tt.destructive)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateAndUpdateSchema() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if version, _ := GetSchemaVersion(db); version != tt.wantVersion {
+				t.Errorf("schema version = %d, want %d", version, tt.wantVersion)
+			}
+			if got := countRows(t, db, "metrics"); got != tt.wantRows {
+				t.Errorf("metrics has %d rows, want %d", got, tt.wantRows)
+			}
+
+			backups, _ := filepath.Glob(filepath.Join(backupDir, "metrics_v1_*.db"))
+			if len(backups) != 1 {
+				t.Errorf("backups = %v, want one of version 1 before migrating", backups)
+			}
+		})
+	}
+}
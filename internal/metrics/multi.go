@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+// namedCollector is a backend collector with the name it was configured by
+type namedCollector struct {
+	MetricsCollector
+	name string
+}
+
+// multiCollector records every snapshot to several backends. A failing
+// backend doesn't keep the snapshot from the others.
+type multiCollector struct {
+	collectors []namedCollector
+}
+
+func (m *multiCollector) Record(ctx context.Context, snapshot *MetricsSnapshot) error {
+	errFactory := errors.New()
+
+	var errs []error
+	for _, c := range m.collectors {
+		if err := c.Record(ctx, snapshot); err != nil {
+			errs = append(errs, errFactory.WithData(ErrBackendFailed, struct {
+				Backend string
+				Error   string
+			}{
+				Backend: c.name,
+				Error:   err.Error(),
+			}))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errFactory.Wrap(ErrMetricsCollection, errors.Join(errs...))
+	}
+
+	return nil
+}
+
+// Query reads from the first backend that stores snapshots
+func (m *multiCollector) Query(ctx context.Context, from, to time.Time) ([]*MetricsSnapshot, error) {
+	errFactory := errors.New()
+
+	for _, c := range m.collectors {
+		snapshots, err := c.Query(ctx, from, to)
+		var domainErr errors.Error
+		if errors.As(err, &domainErr) && domainErr.Code() == ErrQueryUnsupported {
+			continue
+		}
+		return snapshots, err
+	}
+
+	return nil, errFactory.New(ErrQueryUnsupported)
+}
+
+func (m *multiCollector) Close() error {
+	errFactory := errors.New()
+
+	var errs []error
+	for _, c := range m.collectors {
+		if err := c.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errFactory.Wrap(ErrServiceShutdown, errors.Join(errs...))
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+// fakeCollector is a backend keeping every snapshot it's given, failing
+// each record if err is set
+type fakeCollector struct {
+	snapshots []*MetricsSnapshot
+	err       error
+	closed    bool
+}
+
+func (f *fakeCollector) Record(_ context.Context, snapshot *MetricsSnapshot) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.snapshots = append(f.snapshots, snapshot)
+	return nil
+}
+
+func (f *fakeCollector) Query(context.Context, time.Time, time.Time) ([]*MetricsSnapshot, error) {
+	return f.snapshots, nil
+}
+
+func (f *fakeCollector) Close() error {
+	f.closed = true
+	return f.err
+}
+
+func TestMultiCollectorRecordsToEveryBackend(t *testing.T) {
+	errFactory := errors.New()
+
+	tests := []struct {
+		name       string
+		firstErr   error
+		secondErr  error
+		wantFirst  int
+		wantSecond int
+		wantErr    bool
+	}{
+		{name: "both record", wantFirst: 3, wantSecond: 3},
+		{name: "first fails", firstErr: errFactory.New(ErrMetricsCollection), wantFirst: 0, wantSecond: 3, wantErr: true},
+		{name: "second fails", secondErr: errFactory.New(ErrMetricsCollection), wantFirst: 3, wantSecond: 0, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := &fakeCollector{err: tt.firstErr}
+			second := &fakeCollector{err: tt.secondErr}
+			m := &multiCollector{collectors: []namedCollector{
+				{MetricsCollector: first, name: "first"},
+				{MetricsCollector: second, name: "second"},
+			}}
+
+			for i := 0; i < 3; i++ {
+				err := m.Record(context.Background(), &MetricsSnapshot{Timestamp: time.Unix(int64(i), 0)})
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Record() error = %v, wantErr %v", err, tt.wantErr)
+				}
+			}
+
+			if len(first.snapshots) != tt.wantFirst {
+				t.Errorf("first backend got %d snapshots, want %d", len(first.snapshots), tt.wantFirst)
+			}
+			if len(second.snapshots) != tt.wantSecond {
+				t.Errorf("second backend got %d snapshots, want %d", len(second.snapshots), tt.wantSecond)
+			}
+
+			m.Close()
+			if !first.closed || !second.closed {
+				t.Errorf("Close() closed first %v and second %v, want both", first.closed, second.closed)
+			}
+		})
+	}
+}
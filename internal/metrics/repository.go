@@ -1,9 +1,14 @@
 package metrics
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"codeberg.org/mutker/nvidiactl/internal/errors"
 	"codeberg.org/mutker/nvidiactl/internal/logger"
@@ -11,8 +16,18 @@ import (
 )
 
 type repository struct {
-	db         *sql.DB
-	insertStmt *sql.Stmt
+	db                   *sql.DB
+	insertStmt           *sql.Stmt
+	insertReturnCodeStmt *sql.Stmt
+	journalMode          string
+	maxSize              int64
+	retention            time.Duration
+	// lastPrune is the timestamp of the sample that last triggered pruning
+	// samples older than retention
+	lastPrune time.Time
+	// sinceSizeCheck counts the samples recorded since the size was last
+	// checked against maxSize
+	sinceSizeCheck int
 }
 
 func NewRepository(cfg Config) (MetricsRepository, error) {
@@ -35,8 +50,17 @@ func NewRepository(cfg Config) (MetricsRepository, error) {
 		})
 	}
 
+	journalMode := cfg.journalMode()
+	if fs := networkFilesystem(cfg.DBPath); fs != "" && journalMode == JournalModeWAL {
+		logger.Warn().
+			Str("path", cfg.DBPath).
+			Str("filesystem", fs).
+			Msg("Metrics database is on a network filesystem, WAL mode may corrupt it; consider metrics_journal_mode = \"delete\"")
+	}
+
 	// Open database with specific pragmas for better performance and safety
-	dsn := cfg.DBPath + "?_journal=WAL&_auto_vacuum=2;"
+	dsn := fmt.Sprintf("%s?_journal=%s&_auto_vacuum=2&_synchronous=%s",
+		cfg.DBPath, strings.ToUpper(journalMode), cfg.synchronousMode())
 	db, err := sql.Open("sqlite3", dsn)
 	if err != nil {
 		return nil, errFactory.WithData(ErrStorageInit, struct {
@@ -48,8 +72,24 @@ func NewRepository(cfg Config) (MetricsRepository, error) {
 		})
 	}
 
-	// Validate if schema is current, with backup if needed
-	if err := ValidateAndUpdateSchema(db); err != nil {
+	if journalMode == JournalModeDelete {
+		// Memory-mapped I/O is unsafe on network filesystems as well. The
+		// pragma is per connection, so keep to a single one.
+		db.SetMaxOpenConns(1)
+		if _, err := db.Exec("PRAGMA mmap_size = 0"); err != nil {
+			db.Close()
+			return nil, errFactory.WithData(ErrStorageInit, struct {
+				Phase string
+				Error string
+			}{
+				Phase: "disable_mmap",
+				Error: err.Error(),
+			})
+		}
+	}
+
+	// Validate if schema is current, migrating it with a backup if needed
+	if err := ValidateAndUpdateSchema(db, cfg.DestructiveMigrations); err != nil {
 		db.Close()
 		return nil, errFactory.WithData(ErrStorageInit, struct {
 			Phase string
@@ -73,14 +113,33 @@ func NewRepository(cfg Config) (MetricsRepository, error) {
 		})
 	}
 
+	returnCodeStmt, err := db.Prepare(GetInsertReturnCodeSQL())
+	if err != nil {
+		stmt.Close()
+		db.Close()
+		return nil, errFactory.WithData(ErrStorageInit, struct {
+			Phase string
+			Error string
+		}{
+			Phase: "prepare_statement",
+			Error: err.Error(),
+		})
+	}
+
 	logger.Info().
 		Str("path", cfg.DBPath).
 		Int("schema_version", SchemaVersion).
+		Str("synchronous", cfg.synchronousMode()).
+		Str("journal_mode", journalMode).
 		Msg("Metrics repository initialized")
 
 	return &repository{
-		db:         db,
-		insertStmt: stmt,
+		db:                   db,
+		insertStmt:           stmt,
+		insertReturnCodeStmt: returnCodeStmt,
+		journalMode:          journalMode,
+		maxSize:              cfg.MaxSize,
+		retention:            cfg.Retention,
 	}, nil
 }
 
@@ -93,11 +152,18 @@ func (r *repository) Record(snapshot *MetricsSnapshot) error {
 		int64(snapshot.FanSpeed.Target),
 		int64(snapshot.Temperature.Current),
 		int64(snapshot.Temperature.Average),
+		int64(snapshot.Temperature.Raw),
 		int64(snapshot.PowerLimit.Current),
 		int64(snapshot.PowerLimit.Target),
 		int64(snapshot.PowerLimit.Average),
+		nullableInt(snapshot.PowerLimit.Board),
 		int64(boolToInt(snapshot.SystemState.AutoFanControl)),
 		int64(boolToInt(snapshot.SystemState.PerformanceMode)),
+		nullableInt(snapshot.SystemState.PerformanceState),
+		nullableInt(snapshot.Load.Utilization),
+		nullableInt(snapshot.Load.MemoryUsed),
+		nullableInt(snapshot.Load.MemoryTotal),
+		nullableInt(snapshot.PowerLimit.Draw),
 	}
 
 	if _, err := r.insertStmt.Exec(values...); err != nil {
@@ -112,27 +178,157 @@ func (r *repository) Record(snapshot *MetricsSnapshot) error {
 		})
 	}
 
-	return nil
+	if err := r.recordReturnCodes(snapshot.ReturnCodes); err != nil {
+		return err
+	}
+
+	if err := r.checkRetention(snapshot.Timestamp); err != nil {
+		return err
+	}
+
+	return r.checkSize()
 }
 
-func (r *repository) Close() error {
+// Query reads the snapshots recorded between from and to. Record writes
+// every snapshot right away, so there is nothing pending to flush first.
+// Readings that weren't available are -1, a missing raw temperature is the
+// current one.
+func (r *repository) Query(ctx context.Context, from, to time.Time) ([]*MetricsSnapshot, error) {
 	errFactory := errors.New()
 
-	// Close prepared statement
-	if err := r.insertStmt.Close(); err != nil {
-		logger.Debug().Err(err).Msg("Failed to close prepared statement")
+	start, end := int64(0), int64(math.MaxInt64)
+	if !from.IsZero() {
+		start = from.Unix()
+	}
+	if !to.IsZero() {
+		end = to.Unix()
 	}
 
-	// Checkpoint WAL and cleanup on close
-	if _, err := r.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
-		return errFactory.WithData(ErrStorageClose, struct {
+	rows, err := r.db.QueryContext(ctx, selectMetricsSQL, start, end)
+	if err != nil {
+		return nil, errFactory.WithData(ErrStorageAccess, struct {
 			Phase string
 			Error string
 		}{
-			Phase: "checkpoint_wal",
+			Phase: "query_metrics",
 			Error: err.Error(),
 		})
 	}
+	defer rows.Close()
+
+	var snapshots []*MetricsSnapshot
+	for rows.Next() {
+		var (
+			timestamp        int64
+			snapshot         MetricsSnapshot
+			rawTemperature   sql.NullInt64
+			boardPower       sql.NullInt64
+			autoFanControl   int
+			performanceMode  int
+			performanceState sql.NullInt64
+			utilization      sql.NullInt64
+			memoryUsed       sql.NullInt64
+			memoryTotal      sql.NullInt64
+			powerDraw        sql.NullInt64
+		)
+		if err := rows.Scan(
+			&timestamp,
+			&snapshot.FanSpeed.Current, &snapshot.FanSpeed.Target,
+			&snapshot.Temperature.Current, &snapshot.Temperature.Average, &rawTemperature,
+			&snapshot.PowerLimit.Current, &snapshot.PowerLimit.Target, &snapshot.PowerLimit.Average, &boardPower,
+			&autoFanControl, &performanceMode,
+			&performanceState,
+			&utilization, &memoryUsed, &memoryTotal,
+			&powerDraw,
+		); err != nil {
+			return nil, errFactory.Wrap(ErrStorageAccess, err)
+		}
+
+		snapshot.Timestamp = time.Unix(timestamp, 0)
+		snapshot.Temperature.Raw = snapshot.Temperature.Current
+		if rawTemperature.Valid {
+			snapshot.Temperature.Raw = int(rawTemperature.Int64)
+		}
+		snapshot.PowerLimit.Board = nullIntValue(boardPower)
+		snapshot.PowerLimit.Enforced = -1
+		snapshot.PowerLimit.Draw = nullIntValue(powerDraw)
+		snapshot.SystemState.AutoFanControl = autoFanControl == 1
+		snapshot.SystemState.PerformanceMode = performanceMode == 1
+		snapshot.SystemState.PerformanceState = nullIntValue(performanceState)
+		snapshot.Load.Utilization = nullIntValue(utilization)
+		snapshot.Load.MemoryUsed = nullIntValue(memoryUsed)
+		snapshot.Load.MemoryTotal = nullIntValue(memoryTotal)
+
+		snapshots = append(snapshots, &snapshot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errFactory.Wrap(ErrStorageAccess, err)
+	}
+
+	return snapshots, nil
+}
+
+// recordReturnCodes stores traced NVML return codes in a single transaction
+func (r *repository) recordReturnCodes(codes []ReturnCodeMetrics) error {
+	errFactory := errors.New()
+
+	if len(codes) == 0 {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return errFactory.Wrap(ErrTransactionFailed, err)
+	}
+
+	stmt := tx.Stmt(r.insertReturnCodeStmt)
+	for _, code := range codes {
+		if _, err := stmt.Exec(code.Timestamp.Unix(), code.Operation, int64(code.Code), code.Message); err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				logger.Debug().Err(rbErr).Msg("Failed to rollback return code insert")
+			}
+			return errFactory.WithData(ErrStorageAccess, struct {
+				Phase     string
+				Error     string
+				Operation string
+			}{
+				Phase:     "insert_return_code",
+				Error:     err.Error(),
+				Operation: code.Operation,
+			})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return errFactory.Wrap(ErrTransactionFailed, err)
+	}
+
+	return nil
+}
+
+func (r *repository) Close() error {
+	errFactory := errors.New()
+
+	// Close prepared statements
+	if err := r.insertStmt.Close(); err != nil {
+		logger.Debug().Err(err).Msg("Failed to close prepared statement")
+	}
+	if err := r.insertReturnCodeStmt.Close(); err != nil {
+		logger.Debug().Err(err).Msg("Failed to close prepared statement")
+	}
+
+	// Checkpoint WAL and cleanup on close
+	if r.journalMode == JournalModeWAL {
+		if _, err := r.db.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			return errFactory.WithData(ErrStorageClose, struct {
+				Phase string
+				Error string
+			}{
+				Phase: "checkpoint_wal",
+				Error: err.Error(),
+			})
+		}
+	}
 
 	if err := r.db.Close(); err != nil {
 		return errFactory.WithData(ErrStorageClose, struct {
@@ -145,3 +341,16 @@ func (r *repository) Close() error {
 	}
 	return nil
 }
+
+// nullableInt stores negative values, used for unknown readings, as NULL
+func nullableInt(v int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(v), Valid: v >= 0}
+}
+
+// nullIntValue is the inverse of nullableInt, NULL reads as -1
+func nullIntValue(v sql.NullInt64) int {
+	if !v.Valid {
+		return -1
+	}
+	return int(v.Int64)
+}
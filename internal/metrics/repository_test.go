@@ -0,0 +1,124 @@
+package metrics
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestRepository opens a repository on a new database in a temporary
+// directory
+func newTestRepository(t *testing.T) MetricsRepository {
+	t.Helper()
+
+	cfg := DefaultConfig()
+	cfg.DBPath = filepath.Join(t.TempDir(), "metrics.db")
+
+	repo, err := NewRepository(cfg)
+	if err != nil {
+		t.Fatalf("NewRepository() unexpected error: %v", err)
+	}
+	return repo
+}
+
+func TestRecordWithinTheSameSecond(t *testing.T) {
+	repo := newTestRepository(t)
+	defer repo.Close()
+
+	// Ticks less than a second apart keep the last sample of the second
+	base := time.Date(2026, 10, 16, 12, 0, 0, 100*int(time.Millisecond), time.UTC)
+	for i, offset := range []time.Duration{0, 400 * time.Millisecond, 800 * time.Millisecond, 1200 * time.Millisecond} {
+		snapshot := &MetricsSnapshot{Timestamp: base.Add(offset), FanSpeed: FanMetrics{Current: 30 + i}}
+		if err := repo.Record(snapshot); err != nil {
+			t.Fatalf("Record() at +%v unexpected error: %v", offset, err)
+		}
+	}
+
+	snapshots, err := repo.Query(context.Background(), time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %v", err)
+	}
+	if len(snapshots) != 2 {
+		t.Fatalf("Query() returned %d snapshots, want one for each second", len(snapshots))
+	}
+	if got := snapshots[0].FanSpeed.Current; got != 32 {
+		t.Errorf("first second kept the sample with fan speed %d, want the last one, 32", got)
+	}
+	if got, want := snapshots[1].Timestamp, base.Add(1200*time.Millisecond).Truncate(time.Second); !got.Equal(want) {
+		t.Errorf("second snapshot timestamp = %v, want %v", got, want)
+	}
+}
+
+func TestRecordReplacesTheSameTimestamp(t *testing.T) {
+	repo := newTestRepository(t)
+	defer repo.Close()
+
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	for _, snapshot := range []*MetricsSnapshot{
+		{Timestamp: now, FanSpeed: FanMetrics{Current: 60}},
+		{Timestamp: now, FanSpeed: FanMetrics{Current: 40}, SystemState: StateMetrics{AutoFanControl: true}},
+	} {
+		if err := repo.Record(snapshot); err != nil {
+			t.Fatalf("Record() unexpected error: %v", err)
+		}
+	}
+
+	snapshots, err := repo.Query(context.Background(), time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("Query() unexpected error: %v", err)
+	}
+	if len(snapshots) != 1 {
+		t.Fatalf("Query() returned %d snapshots, want the later one replacing the first", len(snapshots))
+	}
+	if snapshots[0].FanSpeed.Current != 40 || !snapshots[0].SystemState.AutoFanControl {
+		t.Errorf("recorded snapshot %+v, want the later one", snapshots[0])
+	}
+}
+
+func TestRepositoryPragmas(t *testing.T) {
+	tests := []struct {
+		name            string
+		synchronous     string
+		journalMode     string
+		wantSynchronous int
+		wantJournalMode string
+	}{
+		{name: "defaults", wantSynchronous: 1, wantJournalMode: "wal"},
+		{name: "off", synchronous: SynchronousOff, journalMode: JournalModeWAL, wantSynchronous: 0, wantJournalMode: "wal"},
+		{name: "normal", synchronous: SynchronousNormal, journalMode: JournalModeWAL, wantSynchronous: 1, wantJournalMode: "wal"},
+		{name: "full delete", synchronous: SynchronousFull, journalMode: JournalModeDelete, wantSynchronous: 2, wantJournalMode: "delete"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.DBPath = filepath.Join(t.TempDir(), "metrics.db")
+			cfg.Synchronous = tt.synchronous
+			cfg.JournalMode = tt.journalMode
+
+			repo, err := NewRepository(cfg)
+			if err != nil {
+				t.Fatalf("NewRepository() unexpected error: %v", err)
+			}
+			defer repo.Close()
+			db := repo.(*repository).db
+
+			var synchronous int
+			if err := db.QueryRow("PRAGMA synchronous").Scan(&synchronous); err != nil {
+				t.Fatalf("PRAGMA synchronous unexpected error: %v", err)
+			}
+			if synchronous != tt.wantSynchronous {
+				t.Errorf("PRAGMA synchronous = %d, want %d", synchronous, tt.wantSynchronous)
+			}
+
+			var journalMode string
+			if err := db.QueryRow("PRAGMA journal_mode").Scan(&journalMode); err != nil {
+				t.Fatalf("PRAGMA journal_mode unexpected error: %v", err)
+			}
+			if journalMode != tt.wantJournalMode {
+				t.Errorf("PRAGMA journal_mode = %q, want %q", journalMode, tt.wantJournalMode)
+			}
+		})
+	}
+}
@@ -9,7 +9,7 @@ import (
 )
 
 const (
-	SchemaVersion = 1 // Increment version for breaking change
+	SchemaVersion = 7 // Increment version for breaking change
 
 	// SQL statements derived from schema
 	createTablesSQL = `
@@ -24,23 +24,80 @@ const (
         fan_speed_target  INTEGER NOT NULL CHECK (typeof(fan_speed_target) = 'integer'),
         temp_current     INTEGER NOT NULL CHECK (typeof(temp_current) = 'integer'),
         temp_average     INTEGER NOT NULL CHECK (typeof(temp_average) = 'integer'),
+        temp_raw         INTEGER CHECK (temp_raw IS NULL OR typeof(temp_raw) = 'integer'),
         power_current    INTEGER NOT NULL CHECK (typeof(power_current) = 'integer'),
         power_target     INTEGER NOT NULL CHECK (typeof(power_target) = 'integer'),
         power_average    INTEGER NOT NULL CHECK (typeof(power_average) = 'integer'),
+        power_board      INTEGER CHECK (power_board IS NULL OR typeof(power_board) = 'integer'),
         auto_fan_control INTEGER NOT NULL CHECK (auto_fan_control IN (0, 1)),
-        performance_mode INTEGER NOT NULL CHECK (performance_mode IN (0, 1))
-    );`
+        performance_mode INTEGER NOT NULL CHECK (performance_mode IN (0, 1)),
+        pstate           INTEGER CHECK (pstate IS NULL OR pstate BETWEEN 0 AND 15),
+        utilization      INTEGER CHECK (utilization IS NULL OR utilization BETWEEN 0 AND 100),
+        memory_used      INTEGER CHECK (memory_used IS NULL OR typeof(memory_used) = 'integer'),
+        memory_total     INTEGER CHECK (memory_total IS NULL OR typeof(memory_total) = 'integer'),
+        power_draw       INTEGER CHECK (power_draw IS NULL OR typeof(power_draw) = 'integer')
+    );
+
+    CREATE TABLE IF NOT EXISTS nvml_return_codes (
+        id          INTEGER PRIMARY KEY,
+        timestamp   INTEGER NOT NULL CHECK (typeof(timestamp) = 'integer'),
+        operation   TEXT NOT NULL,
+        return_code INTEGER NOT NULL CHECK (typeof(return_code) = 'integer'),
+        message     TEXT NOT NULL
+    );
 
+    CREATE INDEX IF NOT EXISTS idx_nvml_return_codes_timestamp
+        ON nvml_return_codes (timestamp);`
+
+	// Timestamps are in seconds. A snapshot in the second of a recorded one
+	// replaces it, so ticks less than a second apart and the shutdown
+	// snapshot right after the last tick keep the latest state of the second
+	// instead of being refused.
 	insertMetricsSQL = `
-    INSERT INTO metrics (
+    INSERT OR REPLACE INTO metrics (
         timestamp,
         fan_speed_current, fan_speed_target,
-        temp_current, temp_average,
-        power_current, power_target, power_average,
-        auto_fan_control, performance_mode
-    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+        temp_current, temp_average, temp_raw,
+        power_current, power_target, power_average, power_board,
+        auto_fan_control, performance_mode,
+        pstate,
+        utilization, memory_used, memory_total,
+        power_draw
+    ) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	selectMetricsSQL = `
+    SELECT
+        timestamp,
+        fan_speed_current, fan_speed_target,
+        temp_current, temp_average, temp_raw,
+        power_current, power_target, power_average, power_board,
+        auto_fan_control, performance_mode,
+        pstate,
+        utilization, memory_used, memory_total,
+        power_draw
+    FROM metrics
+    WHERE timestamp >= ? AND timestamp <= ?
+    ORDER BY timestamp`
+
+	insertReturnCodeSQL = `
+    INSERT INTO nvml_return_codes (
+        timestamp, operation, return_code, message
+    ) VALUES (?, ?, ?, ?)`
 )
 
+// metricsColumns are the columns of the current metrics table, used to
+// recognize a current table whose schema version wasn't recorded
+var metricsColumns = []string{
+	"timestamp",
+	"fan_speed_current", "fan_speed_target",
+	"temp_current", "temp_average", "temp_raw",
+	"power_current", "power_target", "power_average", "power_board",
+	"auto_fan_control", "performance_mode",
+	"pstate",
+	"utilization", "memory_used", "memory_total",
+	"power_draw",
+}
+
 // InitSchema creates a new database schema with the current version
 func InitSchema(db *sql.DB) error {
 	errFactory := errors.New()
@@ -65,6 +122,29 @@ func InitSchema(db *sql.DB) error {
 		}
 	}()
 
+	if err := createSchema(tx); err != nil {
+		return err
+	}
+
+	logger.Debug().Msg("Committing transaction...")
+	if err := tx.Commit(); err != nil {
+		return errFactory.Wrap(ErrSchemaInitFailed, err)
+	}
+	committed = true
+
+	logger.Info().
+		Int("version", SchemaVersion).
+		Msg("Schema initialized successfully")
+
+	return nil
+}
+
+// createSchema creates the missing tables of the current schema and records
+// its version. Versions recorded before are kept, except later ones, which
+// a downgrade leaves behind.
+func createSchema(tx *sql.Tx) error {
+	errFactory := errors.New()
+
 	// Split and execute each statement separately
 	statements := strings.Split(createTablesSQL, ";")
 	for _, stmt := range statements {
@@ -86,7 +166,15 @@ func InitSchema(db *sql.DB) error {
 	}
 
 	logger.Debug().Msg("Recording schema version...")
-	// Record schema version
+	if _, err := tx.Exec("DELETE FROM schema_versions WHERE version >= ?", SchemaVersion); err != nil {
+		return errFactory.WithData(ErrSchemaInitFailed, struct {
+			Error string
+			Phase string
+		}{
+			Error: err.Error(),
+			Phase: "record_version",
+		})
+	}
 	if _, err := tx.Exec(`
         INSERT INTO schema_versions (version, applied_at)
         VALUES (?, datetime('now'))
@@ -100,16 +188,6 @@ func InitSchema(db *sql.DB) error {
 		})
 	}
 
-	logger.Debug().Msg("Committing transaction...")
-	if err := tx.Commit(); err != nil {
-		return errFactory.Wrap(ErrSchemaInitFailed, err)
-	}
-	committed = true
-
-	logger.Info().
-		Int("version", SchemaVersion).
-		Msg("Schema initialized successfully")
-
 	return nil
 }
 
@@ -173,6 +251,44 @@ func TableExists(db *sql.DB, tableName string) (bool, error) {
 	return exists, nil
 }
 
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+}
+
+// tableColumns returns the column names of a table in definition order
+func tableColumns(db querier, tableName string) ([]string, error) {
+	errFactory := errors.New()
+
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", tableName)
+	if err != nil {
+		return nil, errFactory.WithData(ErrSchemaValidationFailed, struct {
+			Phase string
+			Table string
+			Error string
+		}{
+			Phase: "get_columns",
+			Table: tableName,
+			Error: err.Error(),
+		})
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, errFactory.Wrap(ErrSchemaValidationFailed, err)
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errFactory.Wrap(ErrSchemaValidationFailed, err)
+	}
+
+	return columns, nil
+}
+
 // SQL getters for consistent schema usage
 func GetCreateTablesSQL() string {
 	return createTablesSQL
@@ -182,3 +298,8 @@ func GetCreateTablesSQL() string {
 func GetInsertMetricSQL() string {
 	return insertMetricsSQL
 }
+
+// GetInsertReturnCodeSQL returns the SQL to insert an NVML return code
+func GetInsertReturnCodeSQL() string {
+	return insertReturnCodeSQL
+}
@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+// streamCollector writes every snapshot as a JSON line, for debugging
+type streamCollector struct {
+	writeOnly
+	encoder *json.Encoder
+	mu      sync.Mutex
+}
+
+func newStreamCollector(w io.Writer) MetricsCollector {
+	return &streamCollector{encoder: json.NewEncoder(w)}
+}
+
+func (s *streamCollector) Record(_ context.Context, snapshot *MetricsSnapshot) error {
+	errFactory := errors.New()
+
+	if snapshot == nil {
+		return errFactory.New(ErrInvalidMetrics)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.encoder.Encode(snapshot); err != nil {
+		return errFactory.Wrap(ErrMetricsCollection, err)
+	}
+
+	return nil
+}
+
+func (*streamCollector) Close() error {
+	return nil
+}
@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+// gauge is a metric of the Prometheus exposition format
+type gauge struct {
+	name  string
+	help  string
+	value func(s *MetricsSnapshot) (float64, bool)
+}
+
+// gauges are the metrics written by the textfile backend, one sample each.
+// Values the card doesn't report are left out.
+var gauges = []gauge{
+	{"nvidiactl_temperature_celsius", "Current GPU temperature.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.Temperature.Current), true }},
+	{"nvidiactl_temperature_average_celsius", "Averaged GPU temperature used for control.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.Temperature.Average), true }},
+	{"nvidiactl_fan_speed_percent", "Current fan speed.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.FanSpeed.Current), true }},
+	{"nvidiactl_fan_speed_target_percent", "Target fan speed.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.FanSpeed.Target), true }},
+	{"nvidiactl_power_limit_watts", "Current power limit.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.PowerLimit.Current), true }},
+	{"nvidiactl_power_limit_target_watts", "Target power limit.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.PowerLimit.Target), true }},
+	{"nvidiactl_power_limit_average_watts", "Averaged power limit.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.PowerLimit.Average), true }},
+	{"nvidiactl_power_limit_enforced_watts", "Power limit enforced by the card.",
+		func(s *MetricsSnapshot) (float64, bool) {
+			return float64(s.PowerLimit.Enforced), s.PowerLimit.Enforced >= 0
+		}},
+	{"nvidiactl_power_draw_watts", "Current GPU power draw.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.PowerLimit.Draw), s.PowerLimit.Draw >= 0 }},
+	{"nvidiactl_board_power_watts", "Total board power draw.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.PowerLimit.Board), s.PowerLimit.Board >= 0 }},
+	{"nvidiactl_auto_fan_control", "Whether the fans are under driver control.",
+		func(s *MetricsSnapshot) (float64, bool) {
+			return float64(boolToInt(s.SystemState.AutoFanControl)), true
+		}},
+	{"nvidiactl_performance_mode", "Whether performance mode is active.",
+		func(s *MetricsSnapshot) (float64, bool) {
+			return float64(boolToInt(s.SystemState.PerformanceMode)), true
+		}},
+	{"nvidiactl_performance_state", "Current P-state.",
+		func(s *MetricsSnapshot) (float64, bool) {
+			return float64(s.SystemState.PerformanceState), s.SystemState.PerformanceState >= 0
+		}},
+	{"nvidiactl_utilization_percent", "GPU utilization.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.Load.Utilization), s.Load.Utilization >= 0 }},
+	{"nvidiactl_memory_used_mebibytes", "Frame buffer memory in use.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.Load.MemoryUsed), s.Load.MemoryUsed >= 0 }},
+	{"nvidiactl_memory_total_mebibytes", "Total frame buffer memory.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.Load.MemoryTotal), s.Load.MemoryTotal >= 0 }},
+	{"nvidiactl_last_update_timestamp_seconds", "Time of the last update.",
+		func(s *MetricsSnapshot) (float64, bool) { return float64(s.Timestamp.UnixMilli()) / 1000, true }},
+}
+
+// textfileCollector writes the latest snapshot in the Prometheus text
+// exposition format for node_exporter's textfile collector. The file is
+// replaced atomically, so the collector never reads a partial file.
+type textfileCollector struct {
+	writeOnly
+	path string
+	mu   sync.Mutex
+}
+
+func newTextfileCollector(path string) (MetricsCollector, error) {
+	errFactory := errors.New()
+
+	if path == "" {
+		return nil, errFactory.WithData(ErrInvalidConfig, struct {
+			Field string
+			Value string
+		}{
+			Field: "textfile_path",
+			Value: path,
+		})
+	}
+
+	return &textfileCollector{path: path}, nil
+}
+
+func (t *textfileCollector) Record(_ context.Context, snapshot *MetricsSnapshot) error {
+	errFactory := errors.New()
+
+	if snapshot == nil {
+		return errFactory.New(ErrInvalidMetrics)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := writeFileAtomic(t.path, formatExposition(snapshot)); err != nil {
+		return errFactory.WithData(ErrMetricsCollection, struct {
+			Path  string
+			Error string
+		}{
+			Path:  t.path,
+			Error: err.Error(),
+		})
+	}
+
+	return nil
+}
+
+func (*textfileCollector) Close() error {
+	return nil
+}
+
+// formatExposition renders a snapshot in the Prometheus text format
+func formatExposition(snapshot *MetricsSnapshot) []byte {
+	var buf bytes.Buffer
+	for _, g := range gauges {
+		value, ok := g.value(snapshot)
+		if !ok {
+			continue
+		}
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n",
+			g.name, g.help, g.name, g.name, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+	return buf.Bytes()
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(defaultFilePerm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,10 @@
+package process
+
+import "codeberg.org/mutker/nvidiactl/internal/errors"
+
+const (
+	ErrUserLookup      = errors.ErrorCode("process_user_lookup_failed")
+	ErrDropPrivileges  = errors.ErrDropPrivileges
+	ErrSetPriority     = errors.ErrorCode("process_set_priority_failed")
+	ErrInvalidNiceness = errors.ErrorCode("process_invalid_niceness")
+)
@@ -0,0 +1,89 @@
+package process
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+const (
+	MinNiceness = -20
+	MaxNiceness = 19
+
+	// kernelPriorityBase is what the getpriority syscall returns for
+	// niceness 0; the raw value is 20 - niceness to avoid negative results
+	kernelPriorityBase = 20
+)
+
+// SetNiceness sets the scheduling niceness of the process. Linux applies
+// niceness per thread, so every existing thread is changed; threads started
+// later inherit it from the thread creating them. Lowering the niceness
+// below its current value requires CAP_SYS_NICE.
+func SetNiceness(niceness int) error {
+	errFactory := errors.New()
+
+	if niceness < MinNiceness || niceness > MaxNiceness {
+		return errFactory.WithData(ErrInvalidNiceness, niceness)
+	}
+
+	tids, err := threadIDs()
+	if err != nil {
+		return errFactory.Wrap(ErrSetPriority, err)
+	}
+
+	for _, tid := range tids {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, niceness); err != nil {
+			if err == syscall.EACCES || err == syscall.EPERM {
+				return errFactory.WithData(ErrSetPriority, struct {
+					Niceness int
+					Current  int
+					Error    string
+				}{
+					Niceness: niceness,
+					Current:  Niceness(),
+					Error:    "raising priority requires root or CAP_SYS_NICE",
+				})
+			}
+			// Threads may exit between listing and setting
+			if err == syscall.ESRCH {
+				continue
+			}
+			return errFactory.Wrap(ErrSetPriority, err)
+		}
+	}
+
+	logger.Info().Int("niceness", Niceness()).Msg("Process priority set")
+
+	return nil
+}
+
+// Niceness returns the niceness of the calling thread
+func Niceness() int {
+	prio, err := syscall.Getpriority(syscall.PRIO_PROCESS, 0)
+	if err != nil {
+		return 0
+	}
+	return kernelPriorityBase - prio
+}
+
+// threadIDs lists the threads of the current process
+func threadIDs() ([]int, error) {
+	entries, err := os.ReadDir("/proc/self/task")
+	if err != nil {
+		return nil, err
+	}
+
+	tids := make([]int, 0, len(entries))
+	for _, entry := range entries {
+		tid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		tids = append(tids, tid)
+	}
+
+	return tids, nil
+}
@@ -0,0 +1,112 @@
+package process
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+// DropPrivileges switches the process to the given user and its groups.
+// Go applies setuid and setgid to all threads, so no goroutine keeps running
+// as root. The switch is verified, a partial drop is returned as an error.
+func DropPrivileges(username string) error {
+	errFactory := errors.New()
+
+	u, err := user.Lookup(username)
+	if err != nil {
+		return errFactory.Wrap(ErrUserLookup, err)
+	}
+
+	uid, err := strconv.Atoi(u.Uid)
+	if err != nil {
+		return errFactory.Wrap(ErrUserLookup, err)
+	}
+
+	gid, err := strconv.Atoi(u.Gid)
+	if err != nil {
+		return errFactory.Wrap(ErrUserLookup, err)
+	}
+
+	if os.Geteuid() != 0 {
+		if os.Geteuid() == uid {
+			logger.Debug().Str("user", username).Msg("Already running as target user")
+			return nil
+		}
+		return errFactory.WithData(ErrDropPrivileges, struct {
+			Phase string
+			User  string
+		}{
+			Phase: "not_root",
+			User:  username,
+		})
+	}
+
+	groups, err := supplementaryGroups(u)
+	if err != nil {
+		return errFactory.Wrap(ErrUserLookup, err)
+	}
+
+	// Order matters: groups and gid can only be changed while still root
+	if err := syscall.Setgroups(groups); err != nil {
+		return dropError("setgroups", username, err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return dropError("setgid", username, err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return dropError("setuid", username, err)
+	}
+
+	if os.Getuid() != uid || os.Geteuid() != uid || os.Getgid() != gid || os.Getegid() != gid {
+		return dropError("verify", username, syscall.EPERM)
+	}
+
+	// Regaining root must fail once privileges are dropped
+	if uid != 0 && syscall.Setuid(0) == nil {
+		return dropError("verify", username, syscall.EPERM)
+	}
+
+	logger.Info().
+		Str("user", username).
+		Int("uid", uid).
+		Int("gid", gid).
+		Msg("Dropped privileges")
+
+	return nil
+}
+
+func supplementaryGroups(u *user.User) ([]int, error) {
+	ids, err := u.GroupIds()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]int, 0, len(ids))
+	for _, id := range ids {
+		gid, err := strconv.Atoi(id)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, gid)
+	}
+
+	return groups, nil
+}
+
+func dropError(phase, username string, err error) error {
+	errFactory := errors.New()
+
+	return errFactory.WithData(ErrDropPrivileges, struct {
+		Phase string
+		User  string
+		Error string
+	}{
+		Phase: phase,
+		User:  username,
+		Error: err.Error(),
+	})
+}
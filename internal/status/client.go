@@ -0,0 +1,75 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+type client struct {
+	http *http.Client
+}
+
+// NewClient creates a client for the status server on the given Unix socket
+func NewClient(socketPath string) Client {
+	if socketPath == "" {
+		socketPath = DefaultSocketPath
+	}
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		},
+	}
+
+	return &client{
+		http: &http.Client{Transport: transport},
+	}
+}
+
+func (c *client) Status(ctx context.Context) (Status, error) {
+	var st Status
+	err := c.get(ctx, statusPath, &st)
+	return st, err
+}
+
+func (c *client) Health(ctx context.Context) (Health, error) {
+	var health Health
+	err := c.get(ctx, healthPath, &health)
+	return health, err
+}
+
+// get requests a path from the status server and decodes the JSON response
+func (c *client) get(ctx context.Context, path string, v any) error {
+	errFactory := errors.New()
+
+	// The host is ignored by the Unix socket dialer
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://nvidiactl"+path, http.NoBody)
+	if err != nil {
+		return errFactory.Wrap(ErrRequestFailed, err)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return errFactory.Wrap(ErrRequestFailed, err)
+	}
+	defer resp.Body.Close()
+
+	// A failed health check still carries the health in its body
+	if resp.StatusCode == http.StatusServiceUnavailable && path != healthPath {
+		return errFactory.New(ErrUnavailable)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusServiceUnavailable {
+		return errFactory.WithData(ErrRequestFailed, resp.Status)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return errFactory.Wrap(ErrInvalidStatus, err)
+	}
+
+	return nil
+}
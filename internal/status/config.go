@@ -0,0 +1,20 @@
+package status
+
+import "time"
+
+const (
+	// DefaultSocketPath is where the daemon listens when no socket is configured
+	DefaultSocketPath = "/run/nvidiactl.sock"
+
+	socketPerm        = 0o666
+	readHeaderTimeout = 2 * time.Second
+	shutdownTimeout   = 2 * time.Second
+	statusPath        = "/status"
+	capabilitiesPath  = "/capabilities"
+	healthPath        = "/health"
+	decisionsPath     = "/decisions"
+)
+
+type Config struct {
+	SocketPath string
+}
@@ -0,0 +1,16 @@
+package status
+
+import "codeberg.org/mutker/nvidiactl/internal/errors"
+
+const (
+	// Server Errors
+	ErrListenFailed   = errors.ErrorCode("status_listen_failed")
+	ErrAlreadyInUse   = errors.ErrResourceBusy
+	ErrServeFailed    = errors.ErrorCode("status_serve_failed")
+	ErrShutdownFailed = errors.ErrShutdownFailed
+
+	// Client Errors
+	ErrRequestFailed = errors.ErrorCode("status_request_failed")
+	ErrUnavailable   = errors.ErrUnavailable
+	ErrInvalidStatus = errors.ErrorCode("status_invalid_response")
+)
@@ -0,0 +1,93 @@
+package status
+
+import (
+	"net/http"
+	"time"
+)
+
+// HealthState is the health of the daemon or one of its subsystems
+type HealthState string
+
+const (
+	// HealthOK means everything works as configured
+	HealthOK HealthState = "ok"
+	// HealthDegraded means the daemon runs but something doesn't work, e.g.
+	// power control was stopped or metrics fail to record
+	HealthDegraded HealthState = "degraded"
+	// HealthFailed means the daemon can't control the GPU
+	HealthFailed HealthState = "failed"
+)
+
+// healthSeverity orders the health states from best to worst
+var healthSeverity = map[HealthState]int{
+	HealthOK:       0,
+	HealthDegraded: 1,
+	HealthFailed:   2,
+}
+
+// Subsystems reported in the health
+const (
+	SubsystemNVML    = "nvml"
+	SubsystemTick    = "tick"
+	SubsystemFan     = "fan_control"
+	SubsystemPower   = "power_control"
+	SubsystemMetrics = "metrics"
+)
+
+// Health is the overall health of the daemon with the health of each
+// subsystem
+type Health struct {
+	Status     HealthState                `json:"status"`
+	Subsystems map[string]SubsystemHealth `json:"subsystems"`
+	// LastTick is when the GPU state was last read successfully
+	LastTick time.Time `json:"last_tick"`
+	// MaxTickAge is how old LastTick may get before the tick subsystem
+	// fails, set by the daemon
+	MaxTickAge time.Duration `json:"-"`
+}
+
+// SubsystemHealth is the health of one subsystem
+type SubsystemHealth struct {
+	Status HealthState `json:"status"`
+	Detail string      `json:"detail,omitempty"`
+}
+
+// Evaluate adds the tick subsystem, based on how long ago the last tick
+// succeeded, and sets the overall status to the worst of all subsystems
+func (h Health) Evaluate(now time.Time) Health {
+	subsystems := make(map[string]SubsystemHealth, len(h.Subsystems)+1)
+	for name, subsystem := range h.Subsystems {
+		subsystems[name] = subsystem
+	}
+
+	switch {
+	case h.LastTick.IsZero():
+		subsystems[SubsystemTick] = SubsystemHealth{Status: HealthFailed, Detail: "no successful tick yet"}
+	case h.MaxTickAge > 0 && now.Sub(h.LastTick) > h.MaxTickAge:
+		subsystems[SubsystemTick] = SubsystemHealth{
+			Status: HealthFailed,
+			Detail: "last successful tick " + now.Sub(h.LastTick).Round(time.Second).String() + " ago",
+		}
+	default:
+		subsystems[SubsystemTick] = SubsystemHealth{Status: HealthOK}
+	}
+
+	h.Status = HealthOK
+	for _, subsystem := range subsystems {
+		if healthSeverity[subsystem.Status] > healthSeverity[h.Status] {
+			h.Status = subsystem.Status
+		}
+	}
+	h.Subsystems = subsystems
+
+	return h
+}
+
+// HTTPStatus returns the HTTP status code for a health state: 503 for
+// failed, 200 otherwise, so a degraded daemon still passes health probes
+func (s HealthState) HTTPStatus() int {
+	if s == HealthFailed {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
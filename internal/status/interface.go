@@ -0,0 +1,113 @@
+package status
+
+import (
+	"context"
+	"time"
+)
+
+// Server exposes the latest daemon status to local clients
+type Server interface {
+	// Start begins serving status requests until the context is canceled
+	Start(ctx context.Context) error
+	// Publish replaces the status returned to clients
+	Publish(status Status)
+	// PublishCapabilities sets the hardware capabilities returned to clients,
+	// any value encoding to a JSON object
+	PublishCapabilities(capabilities any)
+	// PublishHealth replaces the health returned to clients. The tick
+	// subsystem and overall status are evaluated per request.
+	PublishHealth(health Health)
+	// PublishDecisions replaces the recent control decisions returned to
+	// clients, any value encoding to a JSON array
+	PublishDecisions(decisions any)
+	// Close stops the server and removes its socket
+	Close() error
+}
+
+// Client reads the status of a running daemon
+type Client interface {
+	Status(ctx context.Context) (Status, error)
+	// Health returns the health of the daemon, including when it failed
+	Health(ctx context.Context) (Health, error)
+}
+
+// Status represents a point-in-time view of the daemon
+type Status struct {
+	Timestamp   time.Time         `json:"timestamp"`
+	Temperature TemperatureStatus `json:"temperature"`
+	FanSpeed    FanStatus         `json:"fan_speed"`
+	PowerLimit  PowerStatus       `json:"power_limit"`
+	State       StateStatus       `json:"state"`
+	Daemon      DaemonStatus      `json:"daemon"`
+	// GPUs summarizes the temperatures of all GPUs, omitted unless GPU
+	// coordination is enabled
+	GPUs *GPUsStatus `json:"gpus,omitempty"`
+	// Utilization is omitted when the GPU doesn't report it
+	Utilization *UtilizationStatus `json:"utilization,omitempty"`
+}
+
+// Status value objects
+type TemperatureStatus struct {
+	Current int `json:"current"`
+	Average int `json:"average"`
+	// Unit is the unit of Current and Average, C or F. Omitted by older
+	// daemons, which always report Celsius.
+	Unit string `json:"unit,omitempty"`
+}
+
+type FanStatus struct {
+	Current int `json:"current"`
+	Target  int `json:"target"`
+	// Policies is the control policy of each fan as read from the device,
+	// omitted when not reported
+	Policies []string `json:"policies,omitempty"`
+}
+
+type UtilizationStatus struct {
+	Current int `json:"current"`
+	Average int `json:"average"`
+}
+
+type PowerStatus struct {
+	Current int `json:"current"`
+	Target  int `json:"target"`
+	Average int `json:"average"`
+	// Draw is the current power draw of the GPU, omitted when not reported
+	Draw int `json:"draw,omitempty"`
+	// Board is the total board power draw, omitted when not reported
+	Board int `json:"board,omitempty"`
+	// Enforced is the power limit the card enforces, omitted when not
+	// reported
+	Enforced int `json:"enforced,omitempty"`
+	// Constrained is set while the card enforces a lower limit than the
+	// one set
+	Constrained bool `json:"constrained,omitempty"`
+}
+
+type StateStatus struct {
+	AutoFanControl  bool `json:"auto_fan_control"`
+	PerformanceMode bool `json:"performance_mode"`
+	MonitorMode     bool `json:"monitor_mode"`
+	// PowerControlUnreliable is set when the GPU ignored a power limit
+	// write and power control was stopped
+	PowerControlUnreliable bool `json:"power_control_unreliable,omitempty"`
+}
+
+type DaemonStatus struct {
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+	Ticks         uint64    `json:"ticks"`
+	// Version and Commit identify the running build, omitted by older
+	// daemons
+	Version string `json:"version,omitempty"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+type GPUsStatus struct {
+	Count   int     `json:"count"`
+	Min     int     `json:"min"`
+	Max     int     `json:"max"`
+	Mean    float64 `json:"mean"`
+	Hottest int     `json:"hottest"`
+	Spread  int     `json:"spread"`
+}
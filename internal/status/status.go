@@ -0,0 +1,225 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+	"codeberg.org/mutker/nvidiactl/internal/logger"
+)
+
+type server struct {
+	cfg      Config
+	listener net.Listener
+	http     *http.Server
+	latest   *Status
+	caps     any
+	health   *Health
+	recent   any
+	mu       sync.RWMutex
+}
+
+// NewServer creates a status server listening on the configured Unix socket
+func NewServer(cfg Config) (Server, error) {
+	errFactory := errors.New()
+
+	if cfg.SocketPath == "" {
+		cfg.SocketPath = DefaultSocketPath
+	}
+
+	if err := removeStaleSocket(cfg.SocketPath); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("unix", cfg.SocketPath)
+	if err != nil {
+		return nil, errFactory.Wrap(ErrListenFailed, err)
+	}
+
+	// Status is read-only, so any local user may query it
+	if err := os.Chmod(cfg.SocketPath, socketPerm); err != nil {
+		logger.Debug().Err(err).Str("path", cfg.SocketPath).Msg("Failed to set status socket permissions")
+	}
+
+	s := &server{
+		cfg:      cfg,
+		listener: listener,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(statusPath, s.handleStatus)
+	mux.HandleFunc(capabilitiesPath, s.handleCapabilities)
+	mux.HandleFunc(healthPath, s.handleHealth)
+	mux.HandleFunc(decisionsPath, s.handleDecisions)
+	s.http = &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	return s, nil
+}
+
+func (s *server) Start(ctx context.Context) error {
+	errFactory := errors.New()
+
+	go func() {
+		<-ctx.Done()
+		if err := s.Close(); err != nil {
+			logger.Debug().Err(err).Msg("Failed to close status server")
+		}
+	}()
+
+	logger.Debug().Str("path", s.cfg.SocketPath).Msg("Status server listening")
+
+	if err := s.http.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return errFactory.Wrap(ErrServeFailed, err)
+	}
+
+	return nil
+}
+
+func (s *server) Publish(status Status) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latest = &status
+}
+
+func (s *server) PublishCapabilities(capabilities any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.caps = capabilities
+}
+
+func (s *server) PublishHealth(health Health) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health = &health
+}
+
+func (s *server) PublishDecisions(decisions any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recent = decisions
+}
+
+func (s *server) Close() error {
+	errFactory := errors.New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := s.http.Shutdown(ctx); err != nil {
+		return errFactory.Wrap(ErrShutdownFailed, err)
+	}
+
+	if err := os.Remove(s.cfg.SocketPath); err != nil && !os.IsNotExist(err) {
+		return errFactory.Wrap(ErrShutdownFailed, err)
+	}
+
+	return nil
+}
+
+func (s *server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	latest := s.latest
+	s.mu.RUnlock()
+
+	if latest == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, latest)
+}
+
+func (s *server) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	caps := s.caps
+	s.mu.RUnlock()
+
+	if caps == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, caps)
+}
+
+func (s *server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	var health Health
+	if s.health != nil {
+		health = *s.health
+	}
+	s.mu.RUnlock()
+
+	health = health.Evaluate(time.Now())
+	writeJSON(w, health.Status.HTTPStatus(), health)
+}
+
+func (s *server) handleDecisions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	recent := s.recent
+	s.mu.RUnlock()
+
+	if recent == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, recent)
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Debug().Err(err).Msg("Failed to encode status response")
+	}
+}
+
+// removeStaleSocket removes a socket left behind by a previous instance.
+// A socket that still accepts connections belongs to a running daemon.
+func removeStaleSocket(path string) error {
+	errFactory := errors.New()
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	if conn, err := net.Dial("unix", path); err == nil {
+		conn.Close()
+		return errFactory.WithData(ErrAlreadyInUse, path)
+	}
+
+	if err := os.Remove(path); err != nil {
+		return errFactory.Wrap(ErrListenFailed, err)
+	}
+
+	return nil
+}
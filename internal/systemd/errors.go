@@ -0,0 +1,7 @@
+package systemd
+
+import "codeberg.org/mutker/nvidiactl/internal/errors"
+
+const (
+	ErrNotifyFailed = errors.ErrorCode("systemd_notify_failed")
+)
@@ -0,0 +1,62 @@
+// Package systemd implements the sd_notify protocol, telling systemd when the
+// daemon is ready and pinging the service watchdog. Without NOTIFY_SOCKET,
+// when not run by systemd, every call is a no-op.
+package systemd
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"codeberg.org/mutker/nvidiactl/internal/errors"
+)
+
+const (
+	notifySocketEnv = "NOTIFY_SOCKET"
+	watchdogUSecEnv = "WATCHDOG_USEC"
+)
+
+// Ready tells systemd the daemon finished starting, for Type=notify services
+func Ready() error {
+	return notify("READY=1")
+}
+
+// Watchdog pings the service watchdog, systemd restarts the service when
+// no ping arrives within WatchdogSec
+func Watchdog() error {
+	return notify("WATCHDOG=1")
+}
+
+// WatchdogInterval returns the WatchdogSec of the service, 0 if the watchdog
+// isn't enabled
+func WatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv(watchdogUSecEnv), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// notify sends a state to the notify socket. An abstract socket, starting
+// with @, is handled by the net package.
+func notify(state string) error {
+	errFactory := errors.New()
+
+	socket := os.Getenv(notifySocketEnv)
+	if socket == "" {
+		return nil
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return errFactory.Wrap(ErrNotifyFailed, err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return errFactory.Wrap(ErrNotifyFailed, err)
+	}
+
+	return nil
+}